@@ -0,0 +1,165 @@
+// Package broadcast fans out one periodically computed payload to many subscribers
+//
+// Each key (e.g. a question ID) gets a single poller while it has subscribers,
+// so the database work is done once per interval regardless of how many
+// clients are watching
+package broadcast
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// FetchFunc computes the current payload for a key
+type FetchFunc func(ctx context.Context, key string) ([]byte, error)
+
+// Hub runs one poller per key and pushes changed payloads to subscribers
+type Hub struct {
+	fetch    FetchFunc
+	interval time.Duration
+	log      *log.Logger
+
+	mu     sync.Mutex
+	topics map[string]*topic
+}
+
+type topic struct {
+	subs   map[*Subscription]struct{}
+	last   []byte // Last payload sent, nil until the first fetch succeeds
+	cancel context.CancelFunc
+}
+
+// Subscription receives payloads for one key until Close is called
+// C only ever holds the latest payload, slow readers skip intermediate ones
+type Subscription struct {
+	C <-chan []byte
+
+	c   chan []byte
+	hub *Hub
+	key string
+}
+
+// NewHub creates a hub that refreshes each watched key every interval
+func NewHub(fetch FetchFunc, interval time.Duration, logger *log.Logger) *Hub {
+	return &Hub{
+		fetch:    fetch,
+		interval: interval,
+		log:      logger,
+		topics:   make(map[string]*topic),
+	}
+}
+
+// Subscribe starts watching a key, starting its poller if this is the first subscriber
+// The latest known payload is delivered immediately
+func (h *Hub) Subscribe(key string) *Subscription {
+	c := make(chan []byte, 1)
+	sub := &Subscription{C: c, c: c, hub: h, key: key}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	t, ok := h.topics[key]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		t = &topic{subs: make(map[*Subscription]struct{}), cancel: cancel}
+		h.topics[key] = t
+		go h.poll(ctx, key, t)
+	}
+	t.subs[sub] = struct{}{}
+
+	if t.last != nil {
+		c <- t.last
+	}
+	return sub
+}
+
+// Close stops the subscription, stopping the key's poller if it was the last one
+func (s *Subscription) Close() {
+	h := s.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	t, ok := h.topics[s.key]
+	if !ok {
+		return
+	}
+	delete(t.subs, s)
+	if len(t.subs) == 0 {
+		t.cancel()
+		delete(h.topics, s.key)
+	}
+}
+
+// Subscribers returns the number of active subscriptions across all keys
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	n := 0
+	for _, t := range h.topics {
+		n += len(t.subs)
+	}
+	return n
+}
+
+// poll fetches the payload for a key every interval until ctx is cancelled
+func (h *Hub) poll(ctx context.Context, key string, t *topic) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		h.refresh(ctx, key, t)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Hub) refresh(ctx context.Context, key string, t *topic) {
+	fetchCtx, cancel := context.WithTimeout(ctx, h.interval*5)
+	defer cancel()
+
+	payload, err := h.fetch(fetchCtx, key)
+	if err != nil {
+		if ctx.Err() == nil && h.log != nil {
+			h.log.Printf("broadcast: error fetching %s: %v", key, err)
+		}
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if bytes.Equal(payload, t.last) {
+		return
+	}
+	t.last = payload
+
+	for sub := range t.subs {
+		sub.send(payload)
+	}
+}
+
+// send replaces any unread payload with the new one, never blocking the poller
+// Only the poller sends on c (under the hub lock), so this can't race with itself
+func (s *Subscription) send(payload []byte) {
+	select {
+	case s.c <- payload:
+		return
+	default:
+	}
+	select {
+	case <-s.c:
+	default:
+	}
+	select {
+	case s.c <- payload:
+	default:
+	}
+}
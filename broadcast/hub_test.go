@@ -0,0 +1,206 @@
+package broadcast
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fetcher serves whatever payload the test last set and counts fetches per key
+type fetcher struct {
+	mu      sync.Mutex
+	payload map[string]string
+	calls   map[string]int
+	ctxs    map[string]context.Context
+}
+
+func newFetcher() *fetcher {
+	return &fetcher{payload: map[string]string{}, calls: map[string]int{}, ctxs: map[string]context.Context{}}
+}
+
+func (f *fetcher) fetch(ctx context.Context, key string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls[key]++
+	f.ctxs[key] = ctx
+	return []byte(f.payload[key]), nil
+}
+
+func (f *fetcher) set(key, payload string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.payload[key] = payload
+}
+
+func (f *fetcher) count(key string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[key]
+}
+
+// settle waits until a fetch that started after now has been delivered
+// The poller is sequential, so once two more fetches have begun the first of
+// them has finished sending
+func (f *fetcher) settle(t *testing.T, key string) {
+	t.Helper()
+	target := f.count(key) + 3
+	deadline := time.Now().Add(2 * time.Second)
+	for f.count(key) < target {
+		if time.Now().After(deadline) {
+			t.Fatalf("poller for %s stalled at %d fetches", key, f.count(key))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func receive(t *testing.T, sub *Subscription) string {
+	t.Helper()
+	select {
+	case payload := <-sub.C:
+		return string(payload)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no payload delivered")
+		return ""
+	}
+}
+
+func pending(sub *Subscription) (string, bool) {
+	select {
+	case payload := <-sub.C:
+		return string(payload), true
+	default:
+		return "", false
+	}
+}
+
+func TestHubLatestOnly(t *testing.T) {
+	f := newFetcher()
+	f.set("q1", "v1")
+	hub := NewHub(f.fetch, time.Millisecond, nil)
+
+	sub := hub.Subscribe("q1")
+	defer sub.Close()
+	if got := receive(t, sub); got != "v1" {
+		t.Fatalf("first payload %q, want v1", got)
+	}
+
+	// A reader that falls behind only sees the newest payload
+	for _, payload := range []string{"v2", "v3", "v4"} {
+		f.set("q1", payload)
+		f.settle(t, "q1")
+	}
+	if got := receive(t, sub); got != "v4" {
+		t.Fatalf("payload %q, want only the latest v4", got)
+	}
+
+	// Unchanged payloads aren't sent again
+	f.settle(t, "q1")
+	if got, ok := pending(sub); ok {
+		t.Fatalf("unchanged payload %q re-sent", got)
+	}
+}
+
+func TestHubLateSubscriber(t *testing.T) {
+	f := newFetcher()
+	f.set("q1", "v1")
+	hub := NewHub(f.fetch, time.Millisecond, nil)
+
+	first := hub.Subscribe("q1")
+	defer first.Close()
+	receive(t, first)
+
+	// The latest payload is waiting as soon as Subscribe returns
+	late := hub.Subscribe("q1")
+	defer late.Close()
+	if got, ok := pending(late); !ok || got != "v1" {
+		t.Fatalf("late subscriber got %q %v, want v1 immediately", got, ok)
+	}
+
+	f.set("q1", "v2")
+	if got := receive(t, first); got != "v2" {
+		t.Fatalf("first subscriber got %q, want v2", got)
+	}
+	if got := receive(t, late); got != "v2" {
+		t.Fatalf("late subscriber got %q, want v2", got)
+	}
+}
+
+func TestHubUnsubscribe(t *testing.T) {
+	tests := []struct {
+		name    string
+		subs    int
+		close   int
+		running bool
+	}{
+		{"last subscriber stops the poller", 1, 1, false},
+		{"all subscribers closed", 3, 3, false},
+		{"remaining subscriber keeps it running", 3, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFetcher()
+			f.set("q1", "v1")
+			hub := NewHub(f.fetch, time.Millisecond, nil)
+
+			var subs []*Subscription
+			for i := 0; i < tt.subs; i++ {
+				subs = append(subs, hub.Subscribe("q1"))
+			}
+			f.settle(t, "q1")
+			for _, sub := range subs[:tt.close] {
+				sub.Close()
+			}
+			for _, sub := range subs[tt.close:] {
+				defer sub.Close()
+			}
+
+			if got := hub.Subscribers(); got != tt.subs-tt.close {
+				t.Fatalf("Subscribers = %d, want %d", got, tt.subs-tt.close)
+			}
+
+			f.mu.Lock()
+			ctx := f.ctxs["q1"]
+			f.mu.Unlock()
+			if !tt.running {
+				select {
+				case <-ctx.Done():
+				case <-time.After(2 * time.Second):
+					t.Fatal("poller context not cancelled")
+				}
+				// At most the fetch already in flight finishes after Close
+				before := f.count("q1")
+				time.Sleep(20 * time.Millisecond)
+				if after := f.count("q1"); after > before+1 {
+					t.Fatalf("poller kept fetching: %d -> %d", before, after)
+				}
+				return
+			}
+			f.settle(t, "q1")
+		})
+	}
+}
+
+func TestHubKeysAreIndependent(t *testing.T) {
+	f := newFetcher()
+	f.set("q1", "one")
+	f.set("q2", "two")
+	hub := NewHub(f.fetch, time.Millisecond, nil)
+
+	a := hub.Subscribe("q1")
+	b := hub.Subscribe("q2")
+	defer b.Close()
+	if got := receive(t, a); got != "one" {
+		t.Fatalf("q1 got %q", got)
+	}
+	if got := receive(t, b); got != "two" {
+		t.Fatalf("q2 got %q", got)
+	}
+
+	a.Close()
+	a.Close() // Closing twice is harmless
+	if got := hub.Subscribers(); got != 1 {
+		t.Fatalf("Subscribers = %d, want 1", got)
+	}
+	f.settle(t, "q2")
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mrbennbenn/pick6/database"
+	"github.com/mrbennbenn/pick6/database/pii"
+	"github.com/mrbennbenn/pick6/gdpr"
+	"github.com/mrbennbenn/pick6/retention"
+)
+
+const usage = `usage:
+  pick6                                      start the server
+  pick6 gdpr export --email E | --mobile M   print everything held about a data subject as JSON
+  pick6 gdpr erase  --email E | --mobile M [--mode erase|anonymise] [--yes]
+                                             erase a data subject (dry run without --yes)
+  pick6 pii reencrypt [--batch N]            seal plaintext and old-key contact details with the
+                                             primary PII key and fill the blind indexes
+  pick6 retention run [--dry-run] [--default-days N]
+                                             purge PII from events past their retention`
+
+// runCommand runs a subcommand against the database instead of starting the server
+func runCommand(ctx context.Context, cfg Config, db *sql.DB, queries *database.Queries, args []string) error {
+	if len(args) < 2 {
+		return errors.New(usage)
+	}
+	switch args[0] {
+	case "gdpr":
+		return runGDPR(ctx, db, queries, args)
+	case "pii":
+		return runPII(ctx, queries, args)
+	case "retention":
+		return runRetention(ctx, cfg, db, args)
+	}
+	return errors.New(usage)
+}
+
+func runGDPR(ctx context.Context, db *sql.DB, queries *database.Queries, args []string) error {
+	fs := flag.NewFlagSet("gdpr "+args[1], flag.ContinueOnError)
+	email := fs.String("email", "", "subject email")
+	mobile := fs.String("mobile", "", "subject mobile (normalized to E.164)")
+	mode := fs.String("mode", string(gdpr.ModeErase), "erase deletes sessions and votes, anonymise keeps votes")
+	yes := fs.Bool("yes", false, "actually erase, otherwise only show what would be erased")
+	if err := fs.Parse(args[2:]); err != nil {
+		return err
+	}
+
+	subject, err := gdpr.NewSubject(*email, *mobile)
+	if err != nil {
+		return err
+	}
+
+	switch args[1] {
+	case "export":
+		export, err := gdpr.Find(ctx, queries, subject)
+		if err != nil {
+			return err
+		}
+		return printJSON(export)
+
+	case "erase":
+		if !*yes {
+			export, err := gdpr.Find(ctx, queries, subject)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "dry run: would %s %d sessions and %d entrants, re-run with --yes\n",
+				*mode, len(export.Sessions), len(export.Entrants))
+			return printJSON(export)
+		}
+		result, err := gdpr.Erase(ctx, db, subject, gdpr.Mode(*mode))
+		if err != nil {
+			return err
+		}
+		return printJSON(result)
+	}
+
+	return errors.New(usage)
+}
+
+func runPII(ctx context.Context, queries *database.Queries, args []string) error {
+	if args[1] != "reencrypt" {
+		return errors.New(usage)
+	}
+
+	fs := flag.NewFlagSet("pii reencrypt", flag.ContinueOnError)
+	batch := fs.Int("batch", 500, "rows per query")
+	if err := fs.Parse(args[2:]); err != nil {
+		return err
+	}
+	if !pii.Enabled() {
+		return errors.New("PII_KEYS is not set, nothing to encrypt with")
+	}
+
+	// Sessions: re-upserting the same values seals them with the primary key
+	var scanned, resealed int
+	after := ""
+	for {
+		sessions, err := queries.ListSessionsWithContact(ctx, database.ListSessionsWithContactParams{
+			After:   after,
+			MaxRows: int32(*batch),
+		})
+		if err != nil {
+			return fmt.Errorf("list sessions: %w", err)
+		}
+		if len(sessions) == 0 {
+			break
+		}
+		for _, s := range sessions {
+			after = s.SessionID
+			scanned++
+
+			emailIndex := contactIndex(s.Email, pii.EmailIndex)
+			mobileIndex := contactIndex(s.Mobile, pii.MobileIndex)
+			if !s.Name.Stale && !s.Email.Stale && !s.Mobile.Stale &&
+				emailIndex == s.EmailIndex && mobileIndex == s.MobileIndex {
+				continue
+			}
+
+			if _, err := queries.UpsertSession(ctx, database.UpsertSessionParams{
+				SessionID:   s.SessionID,
+				Name:        s.Name,
+				Email:       s.Email,
+				Mobile:      s.Mobile,
+				EmailIndex:  emailIndex,
+				MobileIndex: mobileIndex,
+			}); err != nil {
+				return fmt.Errorf("reseal session %s: %w", s.SessionID, err)
+			}
+			resealed++
+		}
+	}
+
+	// Entrants: swap the plaintext left from before encryption for blind indexes
+	var indexed int
+	for {
+		entrants, err := queries.ListUnindexedEntrants(ctx, int32(*batch))
+		if err != nil {
+			return fmt.Errorf("list entrants: %w", err)
+		}
+		progress := 0
+		for _, e := range entrants {
+			if !e.EmailNormalized.Valid {
+				continue
+			}
+			if _, err := queries.UpdateEntrantContact(ctx, database.UpdateEntrantContactParams{
+				EmailIndex:  sql.NullString{String: pii.EmailIndex(e.EmailNormalized.String), Valid: true},
+				MobileIndex: contactIndex(pii.NewString(e.Mobile.String), pii.MobileIndex),
+				EntrantID:   e.EntrantID,
+			}); err != nil {
+				return fmt.Errorf("index entrant %s: %w", e.EntrantID, err)
+			}
+			progress++
+		}
+		indexed += progress
+		if progress == 0 {
+			break
+		}
+	}
+
+	return printJSON(map[string]int{
+		"sessions_scanned":  scanned,
+		"sessions_resealed": resealed,
+		"entrants_indexed":  indexed,
+	})
+}
+
+func runRetention(ctx context.Context, cfg Config, db *sql.DB, args []string) error {
+	if args[1] != "run" {
+		return errors.New(usage)
+	}
+
+	fs := flag.NewFlagSet("retention run", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "show what would be purged without purging it")
+	defaultDays := fs.Int("default-days", cfg.PIIRetentionDays, "retention for events without their own policy, 0 keeps them")
+	if err := fs.Parse(args[2:]); err != nil {
+		return err
+	}
+
+	result, err := retention.Run(ctx, db, retention.Options{
+		DefaultDays: *defaultDays,
+		DryRun:      *dryRun,
+		Trigger:     retention.TriggerCLI,
+	})
+	if err != nil {
+		return err
+	}
+	if result.Skipped {
+		return errors.New("another retention run is in progress")
+	}
+	return printJSON(result)
+}
+
+// contactIndex is the blind index of a contact detail, NULL when there is none
+func contactIndex(value pii.String, index func(string) string) sql.NullString {
+	if !value.Valid || value.String == "" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: index(value.String), Valid: true}
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
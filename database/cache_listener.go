@@ -0,0 +1,151 @@
+package database
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/lib/pq"
+	cache "github.com/patrickmn/go-cache"
+)
+
+// CacheChannel is the NOTIFY channel the cache invalidation triggers publish on
+const CacheChannel = "pick6_cache"
+
+// CacheListener keeps this instance's caches in step with writes made anywhere else
+// Triggers NOTIFY CacheChannel on event, question, slug and session changes; while
+// the LISTEN connection is down, cache_generation is polled instead and any change
+// clears the caches outright
+type CacheListener struct {
+	URL          string       // Direct connection string, LISTEN doesn't survive transaction poolers
+	Queries      *Queries     // For the fallback poll
+	Events       *EventCache  // Event and question cache (by slug)
+	Sessions     *cache.Cache // Session validity cache, nil if there isn't one
+	PollInterval time.Duration
+	Log          *log.Logger
+
+	connected  atomic.Bool
+	generation int64 // Last cache_generation seen by the poll
+}
+
+// NewCacheListener creates a listener, call Start to connect
+func NewCacheListener(url string, queries *Queries, events *EventCache, sessions *cache.Cache, pollInterval time.Duration, logger *log.Logger) *CacheListener {
+	return &CacheListener{
+		URL:          url,
+		Queries:      queries,
+		Events:       events,
+		Sessions:     sessions,
+		PollInterval: pollInterval,
+		Log:          logger,
+	}
+}
+
+// Start listens until ctx is done, reconnecting with backoff when the connection drops
+func (l *CacheListener) Start(ctx context.Context) {
+	if l.PollInterval <= 0 {
+		l.PollInterval = 30 * time.Second
+	}
+	listener := pq.NewListener(l.URL, time.Second, time.Minute, l.onEvent)
+
+	if generation, err := l.Queries.GetCacheGeneration(ctx); err == nil {
+		l.generation = generation
+	} else {
+		l.Log.Printf("Cache listener: reading cache generation: %v", err)
+	}
+
+	// Listen blocks until the first connection, the poll covers the wait
+	go func() {
+		if err := listener.Listen(CacheChannel); err != nil {
+			l.Log.Printf("Cache listener: LISTEN %s: %v", CacheChannel, err)
+		}
+	}()
+
+	go func() {
+		defer listener.Close()
+		ticker := time.NewTicker(l.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case n := <-listener.NotificationChannel():
+				if n == nil {
+					// Sent after a reconnect, anything in between was missed
+					l.invalidateAll("listener reconnected")
+					continue
+				}
+				l.handle(n.Extra)
+			case <-ticker.C:
+				l.poll(ctx, listener)
+			}
+		}
+	}()
+}
+
+func (l *CacheListener) onEvent(event pq.ListenerEventType, err error) {
+	switch event {
+	case pq.ListenerEventConnected:
+		l.connected.Store(true)
+		l.Log.Printf("Cache listener connected")
+	case pq.ListenerEventReconnected:
+		l.connected.Store(true)
+		l.Log.Printf("Cache listener reconnected")
+	case pq.ListenerEventDisconnected:
+		l.connected.Store(false)
+		l.Log.Printf("Cache listener disconnected, polling every %s: %v", l.PollInterval, err)
+	case pq.ListenerEventConnectionAttemptFailed:
+		l.Log.Printf("Cache listener connection attempt failed: %v", err)
+	}
+}
+
+// handle applies one notification payload
+func (l *CacheListener) handle(payload string) {
+	kind, id, _ := strings.Cut(payload, ":")
+	switch kind {
+	case "event":
+		l.Events.InvalidateEvent(id)
+	case "slug":
+		l.Events.InvalidateSlug(id)
+	case "session":
+		if l.Sessions != nil {
+			l.Sessions.Delete(id)
+		}
+	default:
+		l.invalidateAll("unknown notification " + payload)
+	}
+}
+
+// poll checks the connection while connected, and stands in for it while not
+func (l *CacheListener) poll(ctx context.Context, listener *pq.Listener) {
+	if l.connected.Load() {
+		// A dead connection can go unnoticed without traffic, a failed ping
+		// makes the listener reconnect
+		if err := listener.Ping(); err != nil {
+			l.Log.Printf("Cache listener ping failed: %v", err)
+		}
+	}
+
+	generation, err := l.Queries.GetCacheGeneration(ctx)
+	if err != nil {
+		l.Log.Printf("Cache listener: reading cache generation: %v", err)
+		return
+	}
+	if generation != l.generation && !l.connected.Load() {
+		l.invalidateAll("cache generation changed while disconnected")
+	}
+	l.generation = generation
+}
+
+func (l *CacheListener) invalidateAll(reason string) {
+	l.Events.InvalidateAll()
+	if l.Sessions != nil {
+		// Delete rather than Flush, so eviction counters see every entry go
+		for id := range l.Sessions.Items() {
+			l.Sessions.Delete(id)
+		}
+	}
+	l.Log.Printf("Cache listener cleared caches: %s", reason)
+}
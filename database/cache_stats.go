@@ -0,0 +1,117 @@
+package database
+
+import (
+	"sync/atomic"
+	"time"
+
+	cache "github.com/patrickmn/go-cache"
+)
+
+// CacheCounters counts cache hits, misses, loads and evictions
+// Safe for concurrent use, and on a nil receiver (which counts nothing)
+type CacheCounters struct {
+	hits       atomic.Uint64
+	misses     atomic.Uint64
+	notFound   atomic.Uint64
+	loads      atomic.Uint64
+	loadErrors atomic.Uint64
+	loadNanos  atomic.Uint64 // Total time spent loading on misses, errors included
+	refreshes  atomic.Uint64
+	evictions  atomic.Uint64
+}
+
+// CacheStats is a snapshot of a cache's counters
+type CacheStats struct {
+	Items            int     `json:"items"`
+	Hits             uint64  `json:"hits"`
+	Misses           uint64  `json:"misses"`
+	NotFound         uint64  `json:"not_found"` // Lookups answered from the not-found cache, not counted as hits
+	HitRatio         float64 `json:"hit_ratio"` // 0 until the first lookup
+	Loads            uint64  `json:"loads"`     // Database loads, errors included (fewer than misses when loads are shared)
+	Refreshes        uint64  `json:"refreshes"` // Background reloads of entries close to expiry
+	LoadErrors       uint64  `json:"load_errors"`
+	LoadSecondsTotal float64 `json:"load_seconds_total"`
+	AvgLoadMs        float64 `json:"avg_load_ms"`
+	Evictions        uint64  `json:"evictions"` // Expired, invalidated or flushed entries
+}
+
+// Hit records a lookup served from the cache
+func (c *CacheCounters) Hit() {
+	if c != nil {
+		c.hits.Add(1)
+	}
+}
+
+// Miss records a lookup that had to go to the database
+func (c *CacheCounters) Miss() {
+	if c != nil {
+		c.misses.Add(1)
+	}
+}
+
+// NotFound records a lookup for a key remembered as missing from the database
+func (c *CacheCounters) NotFound() {
+	if c != nil {
+		c.notFound.Add(1)
+	}
+}
+
+// Load records how long the database took to answer a miss or refresh
+func (c *CacheCounters) Load(d time.Duration, err error) {
+	if c == nil {
+		return
+	}
+	c.loads.Add(1)
+	c.loadNanos.Add(uint64(d))
+	if err != nil {
+		c.loadErrors.Add(1)
+	}
+}
+
+// Refresh records a background reload of an entry that is still being served
+func (c *CacheCounters) Refresh() {
+	if c != nil {
+		c.refreshes.Add(1)
+	}
+}
+
+// Evicted records entries leaving the cache
+func (c *CacheCounters) Evicted(n int) {
+	if c != nil && n > 0 {
+		c.evictions.Add(uint64(n))
+	}
+}
+
+// Watch counts a go-cache's expirations and deletes as evictions
+// go-cache doesn't report Flush, so callers flushing it should call Evicted themselves
+func (c *CacheCounters) Watch(gc *cache.Cache) {
+	gc.OnEvicted(func(string, interface{}) {
+		c.Evicted(1)
+	})
+}
+
+// Snapshot reads the counters, items is the cache's current size
+func (c *CacheCounters) Snapshot(items int) CacheStats {
+	stats := CacheStats{Items: items}
+	if c == nil {
+		return stats
+	}
+
+	stats.Hits = c.hits.Load()
+	stats.Misses = c.misses.Load()
+	stats.NotFound = c.notFound.Load()
+	stats.Loads = c.loads.Load()
+	stats.LoadErrors = c.loadErrors.Load()
+	stats.Refreshes = c.refreshes.Load()
+	stats.Evictions = c.evictions.Load()
+
+	loadTime := time.Duration(c.loadNanos.Load())
+	stats.LoadSecondsTotal = loadTime.Seconds()
+	if stats.Loads > 0 {
+		stats.AvgLoadMs = float64(loadTime.Microseconds()) / 1000 / float64(stats.Loads)
+	}
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(lookups)
+	}
+	return stats
+}
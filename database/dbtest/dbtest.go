@@ -0,0 +1,104 @@
+// Package dbtest gives tests a migrated Postgres schema of their own
+// Tests using it skip unless PICK6_TEST_DATABASE_URL points at a database the
+// test user can create schemas in
+package dbtest
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	_ "github.com/lib/pq"
+)
+
+// EnvURL names the variable holding the test database connection string
+const EnvURL = "PICK6_TEST_DATABASE_URL"
+
+// Open creates a fresh schema, applies every up migration to it and returns a
+// connection scoped to it; the schema is dropped when the test finishes
+func Open(t testing.TB) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv(EnvURL)
+	if dsn == "" {
+		t.Skipf("%s not set", EnvURL)
+	}
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	defer admin.Close()
+
+	suffix := make([]byte, 6)
+	rand.Read(suffix)
+	schema := "test_" + hex.EncodeToString(suffix)
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		cleanup, err := sql.Open("postgres", dsn)
+		if err != nil {
+			t.Errorf("drop schema %s: %v", schema, err)
+			return
+		}
+		defer cleanup.Close()
+		if _, err := cleanup.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Errorf("drop schema %s: %v", schema, err)
+		}
+	})
+
+	db, err := sql.Open("postgres", withSearchPath(dsn, schema))
+	if err != nil {
+		t.Fatalf("open test schema: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	for _, path := range migrations(t) {
+		body, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read migration: %v", err)
+		}
+		if _, err := db.Exec(string(body)); err != nil {
+			t.Fatalf("apply %s: %v", filepath.Base(path), err)
+		}
+	}
+	return db
+}
+
+// Helper: Add search_path to a URL or key=value connection string, lib/pq
+// passes unknown parameters through to the server as runtime settings
+func withSearchPath(dsn, schema string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err == nil {
+			q := u.Query()
+			q.Set("search_path", schema)
+			u.RawQuery = q.Encode()
+			return u.String()
+		}
+	}
+	return fmt.Sprintf("%s search_path=%s", dsn, schema)
+}
+
+// Helper: The up migrations in the order golang-migrate applies them
+func migrations(t testing.TB) []string {
+	t.Helper()
+	_, file, _, ok := runtime.Caller(0)
+	if !ok {
+		t.Fatal("locate migrations: no caller information")
+	}
+	paths, err := filepath.Glob(filepath.Join(filepath.Dir(file), "..", "migrations", "*.up.sql"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("locate migrations: %v (%d found)", err, len(paths))
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -10,22 +10,23 @@ import (
 )
 
 const getEventEngagementBySlug = `-- name: GetEventEngagementBySlug :many
-SELECT 
+SELECT
     s.slug,
-    COUNT(DISTINCT r.session_id) as sessions,
-    COALESCE(COUNT(r.session_id), 0) as total_votes
+    COALESCE(p.sessions, 0)::bigint as sessions,
+    (SELECT COALESCE(SUM(t.votes_a + t.votes_b), 0)::bigint
+     FROM vote_tallies t
+     JOIN questions q ON q.question_id = t.question_id
+     WHERE t.slug = s.slug AND q.event_id = $1) as total_votes
 FROM slugs s
-LEFT JOIN responses r ON r.slug = s.slug
-    AND r.question_id IN (SELECT question_id FROM questions WHERE questions.event_id = $1)
+LEFT JOIN participant_tallies p ON p.event_id = s.event_id AND p.slug = s.slug
 WHERE s.event_id = $1
-GROUP BY s.slug
 ORDER BY s.slug
 `
 
 type GetEventEngagementBySlugRow struct {
-	Slug       string      `json:"slug"`
-	Sessions   int64       `json:"sessions"`
-	TotalVotes interface{} `json:"total_votes"`
+	Slug       string `json:"slug"`
+	Sessions   int64  `json:"sessions"`
+	TotalVotes int64  `json:"total_votes"`
 }
 
 func (q *Queries) GetEventEngagementBySlug(ctx context.Context, eventID string) ([]GetEventEngagementBySlugRow, error) {
@@ -53,12 +54,14 @@ func (q *Queries) GetEventEngagementBySlug(ctx context.Context, eventID string)
 
 const getEventEngagementTotal = `-- name: GetEventEngagementTotal :one
 
-SELECT 
-    COUNT(DISTINCT r.session_id) as sessions,
-    COUNT(*) as total_votes
-FROM responses r
-JOIN questions q ON q.question_id = r.question_id
-WHERE q.event_id = $1
+SELECT
+    COALESCE((SELECT p.sessions
+     FROM participant_tallies p
+     WHERE p.event_id = $1 AND p.slug = ''), 0)::bigint as sessions,
+    (SELECT COALESCE(SUM(t.votes_a + t.votes_b), 0)::bigint
+     FROM vote_tallies t
+     JOIN questions q ON q.question_id = t.question_id
+     WHERE q.event_id = $1) as total_votes
 `
 
 type GetEventEngagementTotalRow struct {
@@ -67,6 +70,7 @@ type GetEventEngagementTotalRow struct {
 }
 
 // Event-Level Engagement Queries
+// Sessions come from participant_tallies, vote totals from vote_tallies
 func (q *Queries) GetEventEngagementTotal(ctx context.Context, eventID string) (GetEventEngagementTotalRow, error) {
 	row := q.db.QueryRowContext(ctx, getEventEngagementTotal, eventID)
 	var i GetEventEngagementTotalRow
@@ -123,26 +127,56 @@ func (q *Queries) GetEventRetentionBySlug(ctx context.Context, eventID string) (
 	return items, nil
 }
 
+const getEventVersion = `-- name: GetEventVersion :one
+SELECT
+    (SELECT generation FROM cache_generation WHERE id = 1) as generation,
+    (SELECT COALESCE(SUM(t.version), 0)::bigint
+     FROM vote_tallies t
+     JOIN questions q ON q.question_id = t.question_id
+     WHERE q.event_id = $1) as tally_version,
+    (SELECT COALESCE(string_agg(q.question_id, ',' ORDER BY q.question_id), '')::text
+     FROM questions q
+     WHERE q.event_id = $1
+         AND q.result IS NULL
+         AND (q.opens_at IS NULL OR q.opens_at <= NOW())
+         AND (q.closes_at IS NULL OR q.closes_at > NOW())) as open_questions
+`
+
+type GetEventVersionRow struct {
+	Generation    int64  `json:"generation"`
+	TallyVersion  int64  `json:"tally_version"`
+	OpenQuestions string `json:"open_questions"`
+}
+
+// Changes whenever a public API response for the event could: edits to events,
+// questions or slugs (cache_generation), votes (vote_tallies.version), and
+// questions opening or closing as their voting windows pass
+func (q *Queries) GetEventVersion(ctx context.Context, eventID string) (GetEventVersionRow, error) {
+	row := q.db.QueryRowContext(ctx, getEventVersion, eventID)
+	var i GetEventVersionRow
+	err := row.Scan(&i.Generation, &i.TallyVersion, &i.OpenQuestions)
+	return i, err
+}
+
 const getQuestionEngagementBySlug = `-- name: GetQuestionEngagementBySlug :many
-SELECT 
+SELECT
     s.slug,
-    COUNT(DISTINCT r.session_id) as sessions,
-    COALESCE(COUNT(r.session_id), 0) as total_votes,
-    COALESCE(SUM(CASE WHEN r.choice = 'a' THEN 1 ELSE 0 END), 0) as votes_a,
-    COALESCE(SUM(CASE WHEN r.choice = 'b' THEN 1 ELSE 0 END), 0) as votes_b
+    COALESCE(t.votes_a + t.votes_b, 0)::bigint as sessions,
+    COALESCE(t.votes_a + t.votes_b, 0)::bigint as total_votes,
+    COALESCE(t.votes_a, 0)::bigint as votes_a,
+    COALESCE(t.votes_b, 0)::bigint as votes_b
 FROM slugs s
-LEFT JOIN responses r ON r.slug = s.slug AND r.question_id = $1
+LEFT JOIN vote_tallies t ON t.slug = s.slug AND t.question_id = $1
 WHERE s.event_id = (SELECT event_id FROM questions WHERE question_id = $1)
-GROUP BY s.slug
 ORDER BY s.slug
 `
 
 type GetQuestionEngagementBySlugRow struct {
-	Slug       string      `json:"slug"`
-	Sessions   int64       `json:"sessions"`
-	TotalVotes interface{} `json:"total_votes"`
-	VotesA     interface{} `json:"votes_a"`
-	VotesB     interface{} `json:"votes_b"`
+	Slug       string `json:"slug"`
+	Sessions   int64  `json:"sessions"`
+	TotalVotes int64  `json:"total_votes"`
+	VotesA     int64  `json:"votes_a"`
+	VotesB     int64  `json:"votes_b"`
 }
 
 func (q *Queries) GetQuestionEngagementBySlug(ctx context.Context, questionID string) ([]GetQuestionEngagementBySlugRow, error) {
@@ -176,23 +210,24 @@ func (q *Queries) GetQuestionEngagementBySlug(ctx context.Context, questionID st
 
 const getQuestionEngagementTotal = `-- name: GetQuestionEngagementTotal :one
 
-SELECT 
-    COUNT(DISTINCT session_id) as sessions,
-    COUNT(*) as total_votes,
-    COALESCE(SUM(CASE WHEN choice = 'a' THEN 1 ELSE 0 END), 0) as votes_a,
-    COALESCE(SUM(CASE WHEN choice = 'b' THEN 1 ELSE 0 END), 0) as votes_b
-FROM responses
+SELECT
+    COALESCE(SUM(votes_a + votes_b), 0)::bigint as sessions,
+    COALESCE(SUM(votes_a + votes_b), 0)::bigint as total_votes,
+    COALESCE(SUM(votes_a), 0)::bigint as votes_a,
+    COALESCE(SUM(votes_b), 0)::bigint as votes_b
+FROM vote_tallies
 WHERE question_id = $1
 `
 
 type GetQuestionEngagementTotalRow struct {
-	Sessions   int64       `json:"sessions"`
-	TotalVotes int64       `json:"total_votes"`
-	VotesA     interface{} `json:"votes_a"`
-	VotesB     interface{} `json:"votes_b"`
+	Sessions   int64 `json:"sessions"`
+	TotalVotes int64 `json:"total_votes"`
+	VotesA     int64 `json:"votes_a"`
+	VotesB     int64 `json:"votes_b"`
 }
 
 // Question-Level Engagement Queries
+// Read from vote_tallies, one response per session means votes = sessions
 func (q *Queries) GetQuestionEngagementTotal(ctx context.Context, questionID string) (GetQuestionEngagementTotalRow, error) {
 	row := q.db.QueryRowContext(ctx, getQuestionEngagementTotal, questionID)
 	var i GetQuestionEngagementTotalRow
@@ -204,3 +239,47 @@ func (q *Queries) GetQuestionEngagementTotal(ctx context.Context, questionID str
 	)
 	return i, err
 }
+
+const listQuestionTalliesByEvent = `-- name: ListQuestionTalliesByEvent :many
+SELECT t.question_id, t.slug, t.votes_a, t.votes_b
+FROM vote_tallies t
+JOIN questions q ON q.question_id = t.question_id
+WHERE q.event_id = $1
+ORDER BY t.question_id, t.slug
+`
+
+type ListQuestionTalliesByEventRow struct {
+	QuestionID string `json:"question_id"`
+	Slug       string `json:"slug"`
+	VotesA     int64  `json:"votes_a"`
+	VotesB     int64  `json:"votes_b"`
+}
+
+// Every question's per-slug tallies in one query, for event summaries
+func (q *Queries) ListQuestionTalliesByEvent(ctx context.Context, eventID string) ([]ListQuestionTalliesByEventRow, error) {
+	rows, err := q.db.QueryContext(ctx, listQuestionTalliesByEvent, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListQuestionTalliesByEventRow{}
+	for rows.Next() {
+		var i ListQuestionTalliesByEventRow
+		if err := rows.Scan(
+			&i.QuestionID,
+			&i.Slug,
+			&i.VotesA,
+			&i.VotesB,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,81 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mrbennbenn/pick6/database"
+	"github.com/mrbennbenn/pick6/database/dbtest"
+)
+
+// Seeded by the initial migration alongside seedQuestion
+const (
+	seedEvent     = "event_39aJ1km3pr9v1yQYX5gS88e3CUM"
+	otherQuestion = "question_39aJ1lU5dd430R5uChL3QSIg9r8"
+)
+
+func TestParticipantTallies(t *testing.T) {
+	db := dbtest.Open(t)
+	q := database.New(db)
+	ctx := context.Background()
+
+	if _, err := db.Exec(`INSERT INTO sessions (session_id) VALUES ('session_one'), ('session_two')`); err != nil {
+		t.Fatalf("create sessions: %v", err)
+	}
+	vote := func(question, session, slug, choice string) {
+		t.Helper()
+		if _, err := q.UpsertResponse(ctx, database.UpsertResponseParams{
+			QuestionID: question,
+			SessionID:  session,
+			Slug:       slug,
+			Choice:     choice,
+		}); err != nil {
+			t.Fatalf("vote: %v", err)
+		}
+	}
+	check := func(step string, total int64, bySlug map[string]int64) {
+		t.Helper()
+		got, err := q.GetEventEngagementTotal(ctx, seedEvent)
+		if err != nil {
+			t.Fatalf("%s: total: %v", step, err)
+		}
+		if got.Sessions != total {
+			t.Errorf("%s: event sessions = %d, want %d", step, got.Sessions, total)
+		}
+		rows, err := q.GetEventEngagementBySlug(ctx, seedEvent)
+		if err != nil {
+			t.Fatalf("%s: by slug: %v", step, err)
+		}
+		for _, row := range rows {
+			if row.Sessions != bySlug[row.Slug] {
+				t.Errorf("%s: %s sessions = %d, want %d", step, row.Slug, row.Sessions, bySlug[row.Slug])
+			}
+		}
+	}
+
+	vote(seedQuestion, "session_one", "tk03", "a")
+	vote(otherQuestion, "session_one", "tk03", "b")
+	check("one session, two votes", 1, map[string]int64{"tk03": 1})
+
+	vote(seedQuestion, "session_one", "tk03", "b")
+	check("changed pick", 1, map[string]int64{"tk03": 1})
+
+	vote(seedQuestion, "session_two", "tk03-web", "a")
+	check("second session", 2, map[string]int64{"tk03": 1, "tk03-web": 1})
+
+	// Re-voting from another slug moves the response, the session is counted on both while it has votes on both
+	vote(seedQuestion, "session_one", "tk03-web", "a")
+	check("split across slugs", 2, map[string]int64{"tk03": 1, "tk03-web": 2})
+	vote(otherQuestion, "session_one", "tk03-web", "a")
+	check("moved slug", 2, map[string]int64{"tk03-web": 2})
+
+	if _, err := db.Exec(`DELETE FROM sessions WHERE session_id = 'session_one'`); err != nil {
+		t.Fatalf("delete session: %v", err)
+	}
+	check("deleted session", 1, map[string]int64{"tk03-web": 1})
+
+	if _, err := db.Exec(`DELETE FROM questions WHERE question_id = $1`, seedQuestion); err != nil {
+		t.Fatalf("delete question: %v", err)
+	}
+	check("deleted question", 0, map[string]int64{})
+}
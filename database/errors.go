@@ -0,0 +1,43 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// Postgres error codes we care about when mapping errors to HTTP responses
+const (
+	pqUniqueViolation     = "23505"
+	pqForeignKeyViolation = "23503"
+	pqCheckViolation      = "23514"
+)
+
+// IsNotFound reports whether err means no rows matched, including errors wrapped by EventCache
+func IsNotFound(err error) bool {
+	return errors.Is(err, sql.ErrNoRows)
+}
+
+// IsUniqueViolation reports whether err is a Postgres unique constraint violation
+func IsUniqueViolation(err error) bool {
+	return hasPQCode(err, pqUniqueViolation)
+}
+
+// IsForeignKeyViolation reports whether err is a Postgres foreign key violation
+func IsForeignKeyViolation(err error) bool {
+	return hasPQCode(err, pqForeignKeyViolation)
+}
+
+// IsCheckViolation reports whether err is a Postgres CHECK constraint violation
+func IsCheckViolation(err error) bool {
+	return hasPQCode(err, pqCheckViolation)
+}
+
+func hasPQCode(err error, code string) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return string(pqErr.Code) == code
+	}
+	return false
+}
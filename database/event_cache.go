@@ -2,17 +2,46 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	cache "github.com/patrickmn/go-cache"
+	"golang.org/x/sync/singleflight"
 )
 
+// loadTimeout bounds a database load shared by several requests
+// It is detached from any one caller's context, so a client hanging up doesn't fail the others
+const loadTimeout = 10 * time.Second
+
+// notFoundTTL is how long an unknown slug is remembered, so scanners probing
+// /wp-admin/ and friends don't each cost a query. Creating the slug invalidates it sooner
+const notFoundTTL = 30 * time.Second
+
+// maxNotFound caps the not-found cache, a scanner cycling through random slugs
+// shouldn't be able to grow it without bound
+const maxNotFound = 10000
+
 // EventCache caches event and questions data to reduce database load
 // Events and questions are essentially static during an event's lifecycle
+//
+// Concurrent misses for a slug share one database load, and entries close to
+// expiry are served as-is while a background load refreshes them
 type EventCache struct {
-	cache   *cache.Cache
-	queries *Queries
+	cache    *cache.Cache
+	notFound *cache.Cache // Slugs that had no event, kept for notFoundTTL
+	queries  *Queries
+	counters *CacheCounters
+
+	loads         singleflight.Group
+	refreshWindow time.Duration // Hits within this long of expiry trigger a background refresh
+
+	// generation is bumped by every invalidation, loads started before one
+	// neither store their result nor get joined by later misses
+	generation atomic.Uint64
 }
 
 // CachedEventData contains event and its questions
@@ -24,40 +53,106 @@ type CachedEventData struct {
 // NewEventCache creates a new event cache
 // defaultTTL: how long to cache (recommend 1 hour for static event data)
 // cleanupInterval: how often to cleanup expired entries
+// Entries are refreshed in the background during the last quarter of their TTL
 func NewEventCache(queries *Queries, defaultTTL, cleanupInterval time.Duration) *EventCache {
-	return &EventCache{
-		cache:   cache.New(defaultTTL, cleanupInterval),
-		queries: queries,
+	ec := &EventCache{
+		cache:         cache.New(defaultTTL, cleanupInterval),
+		notFound:      cache.New(notFoundTTL, time.Minute),
+		queries:       queries,
+		counters:      &CacheCounters{},
+		refreshWindow: defaultTTL / 4,
 	}
+	ec.counters.Watch(ec.cache)
+	return ec
 }
 
 // GetEventWithQuestionsBySlug retrieves event and questions from cache or database
 // Cache key is the slug, value contains both event and questions
 func (ec *EventCache) GetEventWithQuestionsBySlug(ctx context.Context, slug string) (*CachedEventData, error) {
 	// Check cache first
-	if cached, found := ec.cache.Get(slug); found {
+	if cached, expires, found := ec.cache.GetWithExpiration(slug); found {
 		if data, ok := cached.(*CachedEventData); ok {
+			ec.counters.Hit()
+			if time.Until(expires) < ec.refreshWindow {
+				ec.refresh(slug)
+			}
 			return data, nil
 		}
 	}
 
-	// Cache miss - fetch from database
+	if _, found := ec.notFound.Get(slug); found {
+		ec.counters.NotFound()
+		return nil, fmt.Errorf("failed to get event by slug: %w", sql.ErrNoRows)
+	}
+
+	// Cache miss - fetch from database, sharing the load with concurrent misses
+	ec.counters.Miss()
+	result := ec.loads.DoChan(ec.loadKey(slug), func() (interface{}, error) {
+		return ec.load(slug)
+	})
+
+	select {
+	case res := <-result:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*CachedEventData), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// refresh reloads a slug in the background, at most once at a time
+func (ec *EventCache) refresh(slug string) {
+	ec.loads.DoChan(ec.loadKey(slug), func() (interface{}, error) {
+		ec.counters.Refresh()
+		data, err := ec.load(slug)
+		if IsNotFound(err) {
+			// The slug is gone, stop serving it rather than waiting for the TTL
+			ec.cache.Delete(slug)
+		}
+		return data, err
+	})
+}
+
+// loadKey keys in-flight loads by slug and generation, so a miss after an
+// invalidation starts a fresh load instead of joining one that may be stale
+func (ec *EventCache) loadKey(slug string) string {
+	return strconv.FormatUint(ec.generation.Load(), 10) + ":" + slug
+}
+
+// load reads a slug's event and questions and caches them
+func (ec *EventCache) load(slug string) (*CachedEventData, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), loadTimeout)
+	defer cancel()
+
+	generation := ec.generation.Load()
+	start := time.Now()
+
 	event, err := ec.queries.GetEventBySlug(ctx, slug)
+	if errors.Is(err, sql.ErrNoRows) && ec.generation.Load() == generation && ec.notFound.ItemCount() < maxNotFound {
+		ec.notFound.SetDefault(slug, struct{}{})
+	}
 	if err != nil {
+		ec.counters.Load(time.Since(start), err)
 		return nil, fmt.Errorf("failed to get event by slug: %w", err)
 	}
 
 	questions, err := ec.queries.ListQuestionsByEventID(ctx, event.EventID)
+	ec.counters.Load(time.Since(start), err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list questions: %w", err)
 	}
 
-	// Store in cache
 	data := &CachedEventData{
 		Event:     event,
 		Questions: questions,
 	}
-	ec.cache.Set(slug, data, cache.DefaultExpiration)
+
+	// Store in cache, unless it was invalidated while we were reading
+	if ec.generation.Load() == generation {
+		ec.cache.Set(slug, data, cache.DefaultExpiration)
+	}
 
 	return data, nil
 }
@@ -65,15 +160,34 @@ func (ec *EventCache) GetEventWithQuestionsBySlug(ctx context.Context, slug stri
 // InvalidateSlug removes a slug from the cache
 // Useful if event data changes (rare, but possible)
 func (ec *EventCache) InvalidateSlug(slug string) {
+	ec.generation.Add(1)
 	ec.cache.Delete(slug)
+	ec.notFound.Delete(slug)
+}
+
+// InvalidateEvent removes every cached slug that points at the given event
+// Used after admin writes to an event or its questions
+// Not-found slugs are forgotten too, a write that creates the event may attach
+// its slugs in the same transaction
+func (ec *EventCache) InvalidateEvent(eventID string) {
+	ec.generation.Add(1)
+	for slug, item := range ec.cache.Items() {
+		if data, ok := item.Object.(*CachedEventData); ok && data.Event.EventID == eventID {
+			ec.cache.Delete(slug)
+		}
+	}
+	ec.notFound.Flush()
 }
 
 // InvalidateAll clears the entire cache
 func (ec *EventCache) InvalidateAll() {
+	ec.generation.Add(1)
+	ec.counters.Evicted(ec.cache.ItemCount())
 	ec.cache.Flush()
+	ec.notFound.Flush()
 }
 
 // Stats returns cache statistics for monitoring
-func (ec *EventCache) Stats() (items int, hits, misses uint64) {
-	return ec.cache.ItemCount(), 0, 0 // go-cache doesn't track hits/misses
+func (ec *EventCache) Stats() CacheStats {
+	return ec.counters.Snapshot(ec.cache.ItemCount())
 }
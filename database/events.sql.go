@@ -7,10 +7,148 @@ package database
 
 import (
 	"context"
+	"database/sql"
 )
 
+const createEvent = `-- name: CreateEvent :one
+INSERT INTO events (event_id, description)
+VALUES ($1, $2)
+RETURNING event_id, description, created_at, on_air_question_id
+`
+
+type CreateEventParams struct {
+	EventID     string `json:"event_id"`
+	Description string `json:"description"`
+}
+
+func (q *Queries) CreateEvent(ctx context.Context, arg CreateEventParams) (Event, error) {
+	row := q.db.QueryRowContext(ctx, createEvent, arg.EventID, arg.Description)
+	var i Event
+	err := row.Scan(
+		&i.EventID,
+		&i.Description,
+		&i.CreatedAt,
+		&i.OnAirQuestionID,
+	)
+	return i, err
+}
+
+const createQuestion = `-- name: CreateQuestion :one
+
+INSERT INTO questions (question_id, event_id, big_text, small_text, image_filename, choice_a, choice_b)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING question_id, event_id, big_text, small_text, image_filename, choice_a, choice_b, result, result_set_at, opens_at, closes_at
+`
+
+type CreateQuestionParams struct {
+	QuestionID    string `json:"question_id"`
+	EventID       string `json:"event_id"`
+	BigText       string `json:"big_text"`
+	SmallText     string `json:"small_text"`
+	ImageFilename string `json:"image_filename"`
+	ChoiceA       string `json:"choice_a"`
+	ChoiceB       string `json:"choice_b"`
+}
+
+// Admin: Questions
+func (q *Queries) CreateQuestion(ctx context.Context, arg CreateQuestionParams) (Question, error) {
+	row := q.db.QueryRowContext(ctx, createQuestion,
+		arg.QuestionID,
+		arg.EventID,
+		arg.BigText,
+		arg.SmallText,
+		arg.ImageFilename,
+		arg.ChoiceA,
+		arg.ChoiceB,
+	)
+	var i Question
+	err := row.Scan(
+		&i.QuestionID,
+		&i.EventID,
+		&i.BigText,
+		&i.SmallText,
+		&i.ImageFilename,
+		&i.ChoiceA,
+		&i.ChoiceB,
+		&i.Result,
+		&i.ResultSetAt,
+		&i.OpensAt,
+		&i.ClosesAt,
+	)
+	return i, err
+}
+
+const createSlug = `-- name: CreateSlug :one
+INSERT INTO slugs (slug, event_id)
+VALUES ($1, $2)
+RETURNING slug, event_id, created_at
+`
+
+type CreateSlugParams struct {
+	Slug    string `json:"slug"`
+	EventID string `json:"event_id"`
+}
+
+func (q *Queries) CreateSlug(ctx context.Context, arg CreateSlugParams) (Slug, error) {
+	row := q.db.QueryRowContext(ctx, createSlug, arg.Slug, arg.EventID)
+	var i Slug
+	err := row.Scan(&i.Slug, &i.EventID, &i.CreatedAt)
+	return i, err
+}
+
+const deleteEvent = `-- name: DeleteEvent :execrows
+DELETE FROM events
+WHERE event_id = $1
+`
+
+func (q *Queries) DeleteEvent(ctx context.Context, eventID string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteEvent, eventID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteQuestion = `-- name: DeleteQuestion :one
+DELETE FROM questions
+WHERE question_id = $1
+RETURNING question_id, event_id, big_text, small_text, image_filename, choice_a, choice_b, result, result_set_at, opens_at, closes_at
+`
+
+func (q *Queries) DeleteQuestion(ctx context.Context, questionID string) (Question, error) {
+	row := q.db.QueryRowContext(ctx, deleteQuestion, questionID)
+	var i Question
+	err := row.Scan(
+		&i.QuestionID,
+		&i.EventID,
+		&i.BigText,
+		&i.SmallText,
+		&i.ImageFilename,
+		&i.ChoiceA,
+		&i.ChoiceB,
+		&i.Result,
+		&i.ResultSetAt,
+		&i.OpensAt,
+		&i.ClosesAt,
+	)
+	return i, err
+}
+
+const deleteSlug = `-- name: DeleteSlug :one
+DELETE FROM slugs
+WHERE slug = $1
+RETURNING slug, event_id, created_at
+`
+
+func (q *Queries) DeleteSlug(ctx context.Context, slug string) (Slug, error) {
+	row := q.db.QueryRowContext(ctx, deleteSlug, slug)
+	var i Slug
+	err := row.Scan(&i.Slug, &i.EventID, &i.CreatedAt)
+	return i, err
+}
+
 const getEventByID = `-- name: GetEventByID :one
-SELECT event_id, description, created_at
+SELECT event_id, description, created_at, on_air_question_id
 FROM events
 WHERE event_id = $1
 `
@@ -18,12 +156,17 @@ WHERE event_id = $1
 func (q *Queries) GetEventByID(ctx context.Context, eventID string) (Event, error) {
 	row := q.db.QueryRowContext(ctx, getEventByID, eventID)
 	var i Event
-	err := row.Scan(&i.EventID, &i.Description, &i.CreatedAt)
+	err := row.Scan(
+		&i.EventID,
+		&i.Description,
+		&i.CreatedAt,
+		&i.OnAirQuestionID,
+	)
 	return i, err
 }
 
 const getEventBySlug = `-- name: GetEventBySlug :one
-SELECT e.event_id, e.description, e.created_at
+SELECT e.event_id, e.description, e.created_at, e.on_air_question_id
 FROM events e
 JOIN slugs s ON s.event_id = e.event_id
 WHERE s.slug = $1
@@ -32,12 +175,17 @@ WHERE s.slug = $1
 func (q *Queries) GetEventBySlug(ctx context.Context, slug string) (Event, error) {
 	row := q.db.QueryRowContext(ctx, getEventBySlug, slug)
 	var i Event
-	err := row.Scan(&i.EventID, &i.Description, &i.CreatedAt)
+	err := row.Scan(
+		&i.EventID,
+		&i.Description,
+		&i.CreatedAt,
+		&i.OnAirQuestionID,
+	)
 	return i, err
 }
 
 const getQuestionByEventAndIndex = `-- name: GetQuestionByEventAndIndex :one
-SELECT question_id, event_id, big_text, small_text, image_filename, choice_a, choice_b
+SELECT question_id, event_id, big_text, small_text, image_filename, choice_a, choice_b, result, result_set_at, opens_at, closes_at
 FROM questions
 WHERE event_id = $1
 ORDER BY question_id ASC
@@ -60,12 +208,16 @@ func (q *Queries) GetQuestionByEventAndIndex(ctx context.Context, arg GetQuestio
 		&i.ImageFilename,
 		&i.ChoiceA,
 		&i.ChoiceB,
+		&i.Result,
+		&i.ResultSetAt,
+		&i.OpensAt,
+		&i.ClosesAt,
 	)
 	return i, err
 }
 
 const getQuestionByID = `-- name: GetQuestionByID :one
-SELECT question_id, event_id, big_text, small_text, image_filename, choice_a, choice_b
+SELECT question_id, event_id, big_text, small_text, image_filename, choice_a, choice_b, result, result_set_at, opens_at, closes_at
 FROM questions
 WHERE question_id = $1
 `
@@ -81,12 +233,52 @@ func (q *Queries) GetQuestionByID(ctx context.Context, questionID string) (Quest
 		&i.ImageFilename,
 		&i.ChoiceA,
 		&i.ChoiceB,
+		&i.Result,
+		&i.ResultSetAt,
+		&i.OpensAt,
+		&i.ClosesAt,
 	)
 	return i, err
 }
 
+const listEvents = `-- name: ListEvents :many
+
+SELECT event_id, description, created_at, on_air_question_id
+FROM events
+ORDER BY created_at DESC
+`
+
+// Admin: Events
+func (q *Queries) ListEvents(ctx context.Context) ([]Event, error) {
+	rows, err := q.db.QueryContext(ctx, listEvents)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Event{}
+	for rows.Next() {
+		var i Event
+		if err := rows.Scan(
+			&i.EventID,
+			&i.Description,
+			&i.CreatedAt,
+			&i.OnAirQuestionID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listQuestionsByEventID = `-- name: ListQuestionsByEventID :many
-SELECT question_id, event_id, big_text, small_text, image_filename, choice_a, choice_b
+SELECT question_id, event_id, big_text, small_text, image_filename, choice_a, choice_b, result, result_set_at, opens_at, closes_at
 FROM questions
 WHERE event_id = $1
 ORDER BY question_id ASC
@@ -109,6 +301,10 @@ func (q *Queries) ListQuestionsByEventID(ctx context.Context, eventID string) ([
 			&i.ImageFilename,
 			&i.ChoiceA,
 			&i.ChoiceB,
+			&i.Result,
+			&i.ResultSetAt,
+			&i.OpensAt,
+			&i.ClosesAt,
 		); err != nil {
 			return nil, err
 		}
@@ -122,3 +318,230 @@ func (q *Queries) ListQuestionsByEventID(ctx context.Context, eventID string) ([
 	}
 	return items, nil
 }
+
+const listSlugsByEventID = `-- name: ListSlugsByEventID :many
+
+SELECT slug, event_id, created_at
+FROM slugs
+WHERE event_id = $1
+ORDER BY slug ASC
+`
+
+// Admin: Slugs
+func (q *Queries) ListSlugsByEventID(ctx context.Context, eventID string) ([]Slug, error) {
+	rows, err := q.db.QueryContext(ctx, listSlugsByEventID, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Slug{}
+	for rows.Next() {
+		var i Slug
+		if err := rows.Scan(&i.Slug, &i.EventID, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockQuestion = `-- name: LockQuestion :one
+UPDATE questions
+SET closes_at = NOW(),
+    opens_at = LEAST(opens_at, NOW())
+WHERE question_id = $1
+RETURNING question_id, event_id, big_text, small_text, image_filename, choice_a, choice_b, result, result_set_at, opens_at, closes_at
+`
+
+// Closes voting immediately, pulling opens_at back if it was scheduled later
+func (q *Queries) LockQuestion(ctx context.Context, questionID string) (Question, error) {
+	row := q.db.QueryRowContext(ctx, lockQuestion, questionID)
+	var i Question
+	err := row.Scan(
+		&i.QuestionID,
+		&i.EventID,
+		&i.BigText,
+		&i.SmallText,
+		&i.ImageFilename,
+		&i.ChoiceA,
+		&i.ChoiceB,
+		&i.Result,
+		&i.ResultSetAt,
+		&i.OpensAt,
+		&i.ClosesAt,
+	)
+	return i, err
+}
+
+const setEventOnAirQuestion = `-- name: SetEventOnAirQuestion :one
+UPDATE events
+SET on_air_question_id = $1
+WHERE event_id = $2
+    AND (
+        $1::text IS NULL
+        OR EXISTS (
+            SELECT 1 FROM questions
+            WHERE question_id = $1 AND event_id = $2
+        )
+    )
+RETURNING event_id, description, created_at, on_air_question_id
+`
+
+type SetEventOnAirQuestionParams struct {
+	QuestionID sql.NullString `json:"question_id"`
+	EventID    string         `json:"event_id"`
+}
+
+// Sets (or clears with NULL) the on-air question, which must belong to the event
+func (q *Queries) SetEventOnAirQuestion(ctx context.Context, arg SetEventOnAirQuestionParams) (Event, error) {
+	row := q.db.QueryRowContext(ctx, setEventOnAirQuestion, arg.QuestionID, arg.EventID)
+	var i Event
+	err := row.Scan(
+		&i.EventID,
+		&i.Description,
+		&i.CreatedAt,
+		&i.OnAirQuestionID,
+	)
+	return i, err
+}
+
+const setQuestionResult = `-- name: SetQuestionResult :one
+UPDATE questions
+SET result = $2,
+    result_set_at = CASE WHEN $2::text IS NULL THEN NULL ELSE NOW() END
+WHERE question_id = $1
+RETURNING question_id, event_id, big_text, small_text, image_filename, choice_a, choice_b, result, result_set_at, opens_at, closes_at
+`
+
+type SetQuestionResultParams struct {
+	QuestionID string         `json:"question_id"`
+	Result     sql.NullString `json:"result"`
+}
+
+func (q *Queries) SetQuestionResult(ctx context.Context, arg SetQuestionResultParams) (Question, error) {
+	row := q.db.QueryRowContext(ctx, setQuestionResult, arg.QuestionID, arg.Result)
+	var i Question
+	err := row.Scan(
+		&i.QuestionID,
+		&i.EventID,
+		&i.BigText,
+		&i.SmallText,
+		&i.ImageFilename,
+		&i.ChoiceA,
+		&i.ChoiceB,
+		&i.Result,
+		&i.ResultSetAt,
+		&i.OpensAt,
+		&i.ClosesAt,
+	)
+	return i, err
+}
+
+const setQuestionWindow = `-- name: SetQuestionWindow :one
+UPDATE questions
+SET opens_at = $2,
+    closes_at = $3
+WHERE question_id = $1
+RETURNING question_id, event_id, big_text, small_text, image_filename, choice_a, choice_b, result, result_set_at, opens_at, closes_at
+`
+
+type SetQuestionWindowParams struct {
+	QuestionID string       `json:"question_id"`
+	OpensAt    sql.NullTime `json:"opens_at"`
+	ClosesAt   sql.NullTime `json:"closes_at"`
+}
+
+func (q *Queries) SetQuestionWindow(ctx context.Context, arg SetQuestionWindowParams) (Question, error) {
+	row := q.db.QueryRowContext(ctx, setQuestionWindow, arg.QuestionID, arg.OpensAt, arg.ClosesAt)
+	var i Question
+	err := row.Scan(
+		&i.QuestionID,
+		&i.EventID,
+		&i.BigText,
+		&i.SmallText,
+		&i.ImageFilename,
+		&i.ChoiceA,
+		&i.ChoiceB,
+		&i.Result,
+		&i.ResultSetAt,
+		&i.OpensAt,
+		&i.ClosesAt,
+	)
+	return i, err
+}
+
+const updateEvent = `-- name: UpdateEvent :one
+UPDATE events
+SET description = $2
+WHERE event_id = $1
+RETURNING event_id, description, created_at, on_air_question_id
+`
+
+type UpdateEventParams struct {
+	EventID     string `json:"event_id"`
+	Description string `json:"description"`
+}
+
+func (q *Queries) UpdateEvent(ctx context.Context, arg UpdateEventParams) (Event, error) {
+	row := q.db.QueryRowContext(ctx, updateEvent, arg.EventID, arg.Description)
+	var i Event
+	err := row.Scan(
+		&i.EventID,
+		&i.Description,
+		&i.CreatedAt,
+		&i.OnAirQuestionID,
+	)
+	return i, err
+}
+
+const updateQuestion = `-- name: UpdateQuestion :one
+UPDATE questions
+SET big_text = $2,
+    small_text = $3,
+    image_filename = $4,
+    choice_a = $5,
+    choice_b = $6
+WHERE question_id = $1
+RETURNING question_id, event_id, big_text, small_text, image_filename, choice_a, choice_b, result, result_set_at, opens_at, closes_at
+`
+
+type UpdateQuestionParams struct {
+	QuestionID    string `json:"question_id"`
+	BigText       string `json:"big_text"`
+	SmallText     string `json:"small_text"`
+	ImageFilename string `json:"image_filename"`
+	ChoiceA       string `json:"choice_a"`
+	ChoiceB       string `json:"choice_b"`
+}
+
+func (q *Queries) UpdateQuestion(ctx context.Context, arg UpdateQuestionParams) (Question, error) {
+	row := q.db.QueryRowContext(ctx, updateQuestion,
+		arg.QuestionID,
+		arg.BigText,
+		arg.SmallText,
+		arg.ImageFilename,
+		arg.ChoiceA,
+		arg.ChoiceB,
+	)
+	var i Question
+	err := row.Scan(
+		&i.QuestionID,
+		&i.EventID,
+		&i.BigText,
+		&i.SmallText,
+		&i.ImageFilename,
+		&i.ChoiceA,
+		&i.ChoiceB,
+		&i.Result,
+		&i.ResultSetAt,
+		&i.OpensAt,
+		&i.ClosesAt,
+	)
+	return i, err
+}
@@ -0,0 +1,5 @@
+-- Rollback question results
+
+ALTER TABLE questions
+    DROP COLUMN IF EXISTS result_set_at,
+    DROP COLUMN IF EXISTS result;
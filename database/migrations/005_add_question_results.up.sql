@@ -0,0 +1,6 @@
+-- Record the official result of each fight so predictions can be scored
+-- NULL means no result yet; draw and no_contest are not scored for anyone
+
+ALTER TABLE questions
+    ADD COLUMN result TEXT CHECK (result IN ('a', 'b', 'draw', 'no_contest')),
+    ADD COLUMN result_set_at TIMESTAMP;
@@ -0,0 +1,3 @@
+-- Rollback prize draws
+
+DROP TABLE IF EXISTS draws;
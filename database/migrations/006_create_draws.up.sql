@@ -0,0 +1,24 @@
+-- Prize draws
+-- Each row records everything needed to re-run and audit a draw:
+-- the seed, the algorithm, the eligibility rules, a digest of the eligible
+-- session IDs and any sessions excluded by earlier draws
+
+CREATE TABLE draws (
+    draw_id TEXT PRIMARY KEY,
+    event_id TEXT NOT NULL REFERENCES events(event_id) ON DELETE CASCADE,
+    algorithm TEXT NOT NULL,
+    seed BIGINT NOT NULL,
+    perfect_only BOOLEAN NOT NULL DEFAULT FALSE,
+    excluded TEXT[] NOT NULL DEFAULT '{}',
+    eligible_count INTEGER NOT NULL,
+    eligible_digest TEXT NOT NULL,
+    winner_session_id TEXT,
+    alternates TEXT[] NOT NULL DEFAULT '{}',
+    redraw_of TEXT REFERENCES draws(draw_id),
+    redraw_reason TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CHECK ((redraw_of IS NULL) = (redraw_reason IS NULL))
+);
+
+CREATE INDEX idx_draws_event_id ON draws(event_id);
@@ -0,0 +1,6 @@
+-- Rollback question voting window
+
+ALTER TABLE questions
+    DROP CONSTRAINT IF EXISTS questions_voting_window_check,
+    DROP COLUMN IF EXISTS closes_at,
+    DROP COLUMN IF EXISTS opens_at;
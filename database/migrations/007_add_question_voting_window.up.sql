@@ -0,0 +1,11 @@
+-- Per-question voting window so picks can't be changed once a fight starts
+-- NULL opens_at means open immediately; NULL closes_at means open until locked
+-- A question with a result recorded is always closed
+-- TIMESTAMPTZ, so Question.IsOpen (Go) and the NOW() checks in SQL compare the
+-- same instant whatever the server and session time zones are
+
+ALTER TABLE questions
+    ADD COLUMN opens_at TIMESTAMPTZ,
+    ADD COLUMN closes_at TIMESTAMPTZ,
+    ADD CONSTRAINT questions_voting_window_check
+        CHECK (opens_at IS NULL OR closes_at IS NULL OR opens_at <= closes_at);
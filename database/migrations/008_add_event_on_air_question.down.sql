@@ -0,0 +1,4 @@
+-- Rollback on-air question
+
+ALTER TABLE events
+    DROP COLUMN IF EXISTS on_air_question_id;
@@ -0,0 +1,5 @@
+-- Track which question is currently on air for broadcast graphics
+-- Cleared automatically if the question is deleted
+
+ALTER TABLE events
+    ADD COLUMN on_air_question_id TEXT REFERENCES questions(question_id) ON DELETE SET NULL;
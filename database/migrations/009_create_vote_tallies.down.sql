@@ -0,0 +1,9 @@
+-- Rollback vote tallies
+
+DROP TRIGGER IF EXISTS responses_participant_tally ON responses;
+DROP TRIGGER IF EXISTS responses_vote_tally ON responses;
+DROP FUNCTION IF EXISTS apply_participant_tally();
+DROP FUNCTION IF EXISTS apply_vote_tally();
+DROP TABLE IF EXISTS vote_tallies;
+DROP TABLE IF EXISTS participant_tallies;
+DROP TABLE IF EXISTS participant_sessions;
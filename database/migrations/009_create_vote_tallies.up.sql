@@ -0,0 +1,178 @@
+-- Incrementally maintained vote counters per question and slug
+-- Kept in step with responses by a trigger, so every write path (UpsertResponse,
+-- cascaded deletes) updates the tallies in the same transaction
+-- Each session has at most one response per question, so votes also count sessions
+-- Sessions across a slug or a whole event are counted the same way in participant_tallies
+
+CREATE TABLE vote_tallies (
+    question_id TEXT NOT NULL REFERENCES questions(question_id) ON DELETE CASCADE,
+    slug TEXT NOT NULL REFERENCES slugs(slug) ON DELETE CASCADE,
+    votes_a BIGINT NOT NULL DEFAULT 0 CHECK (votes_a >= 0),
+    votes_b BIGINT NOT NULL DEFAULT 0 CHECK (votes_b >= 0),
+
+    PRIMARY KEY (question_id, slug)
+);
+
+CREATE FUNCTION apply_vote_tally() RETURNS trigger AS $$
+BEGIN
+    -- Re-voting for the same fighter from the same slug changes nothing
+    IF TG_OP = 'UPDATE' AND OLD.choice = NEW.choice AND OLD.slug = NEW.slug THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE vote_tallies
+        SET votes_a = votes_a - CASE WHEN OLD.choice = 'a' THEN 1 ELSE 0 END,
+            votes_b = votes_b - CASE WHEN OLD.choice = 'b' THEN 1 ELSE 0 END
+        WHERE question_id = OLD.question_id AND slug = OLD.slug;
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO vote_tallies (question_id, slug, votes_a, votes_b)
+        VALUES (
+            NEW.question_id,
+            NEW.slug,
+            CASE WHEN NEW.choice = 'a' THEN 1 ELSE 0 END,
+            CASE WHEN NEW.choice = 'b' THEN 1 ELSE 0 END
+        )
+        ON CONFLICT (question_id, slug) DO UPDATE
+        SET votes_a = vote_tallies.votes_a + EXCLUDED.votes_a,
+            votes_b = vote_tallies.votes_b + EXCLUDED.votes_b;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- How many responses each session has per slug, so participant_tallies only moves
+-- when a session's first response lands or its last one goes (session erasure)
+-- event_id is stored because the question may already be gone when a cascade fires
+CREATE TABLE participant_sessions (
+    slug TEXT NOT NULL,
+    session_id TEXT NOT NULL,
+    event_id TEXT NOT NULL REFERENCES events(event_id) ON DELETE CASCADE,
+    responses INT NOT NULL CHECK (responses >= 0),
+
+    PRIMARY KEY (slug, session_id)
+);
+
+CREATE INDEX idx_participant_sessions_event_session ON participant_sessions(event_id, session_id);
+
+-- Distinct sessions per event and slug, slug '' counts the whole event
+-- (the slugs check constraint never allows an empty slug)
+CREATE TABLE participant_tallies (
+    event_id TEXT NOT NULL REFERENCES events(event_id) ON DELETE CASCADE,
+    slug TEXT NOT NULL,
+    sessions BIGINT NOT NULL DEFAULT 0 CHECK (sessions >= 0),
+
+    PRIMARY KEY (event_id, slug)
+);
+
+CREATE FUNCTION apply_participant_tally() RETURNS trigger AS $$
+DECLARE
+    tally_event_id TEXT;
+    remaining INT;
+BEGIN
+    -- Changing the pick from the same slug changes nothing
+    IF TG_OP = 'UPDATE' AND OLD.slug = NEW.slug THEN
+        RETURN NULL;
+    END IF;
+
+    -- One writer per session at a time, so two first votes from different slugs
+    -- can't both miss each other and count the session twice for the event
+    IF TG_OP = 'DELETE' THEN
+        PERFORM pg_advisory_xact_lock(hashtext('participant:' || OLD.session_id));
+    ELSE
+        PERFORM pg_advisory_xact_lock(hashtext('participant:' || NEW.session_id));
+    END IF;
+
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE participant_sessions
+        SET responses = responses - 1
+        WHERE slug = OLD.slug AND session_id = OLD.session_id
+        RETURNING event_id, responses INTO tally_event_id, remaining;
+
+        IF FOUND AND remaining = 0 THEN
+            DELETE FROM participant_sessions
+            WHERE slug = OLD.slug AND session_id = OLD.session_id;
+
+            UPDATE participant_tallies
+            SET sessions = sessions - 1
+            WHERE event_id = tally_event_id AND slug = OLD.slug;
+
+            IF NOT EXISTS (
+                SELECT 1 FROM participant_sessions
+                WHERE event_id = tally_event_id AND session_id = OLD.session_id
+            ) THEN
+                UPDATE participant_tallies
+                SET sessions = sessions - 1
+                WHERE event_id = tally_event_id AND slug = '';
+            END IF;
+        END IF;
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        SELECT event_id INTO tally_event_id FROM questions WHERE question_id = NEW.question_id;
+
+        INSERT INTO participant_sessions (slug, session_id, event_id, responses)
+        VALUES (NEW.slug, NEW.session_id, tally_event_id, 1)
+        ON CONFLICT (slug, session_id) DO UPDATE
+        SET responses = participant_sessions.responses + 1
+        RETURNING responses INTO remaining;
+
+        IF remaining = 1 THEN
+            INSERT INTO participant_tallies (event_id, slug, sessions)
+            VALUES (tally_event_id, NEW.slug, 1)
+            ON CONFLICT (event_id, slug) DO UPDATE
+            SET sessions = participant_tallies.sessions + 1;
+
+            IF NOT EXISTS (
+                SELECT 1 FROM participant_sessions
+                WHERE event_id = tally_event_id AND session_id = NEW.session_id AND slug <> NEW.slug
+            ) THEN
+                INSERT INTO participant_tallies (event_id, slug, sessions)
+                VALUES (tally_event_id, '', 1)
+                ON CONFLICT (event_id, slug) DO UPDATE
+                SET sessions = participant_tallies.sessions + 1;
+            END IF;
+        END IF;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Block writes while backfilling so no vote is counted twice or missed
+LOCK TABLE responses IN SHARE ROW EXCLUSIVE MODE;
+
+INSERT INTO vote_tallies (question_id, slug, votes_a, votes_b)
+SELECT
+    question_id,
+    slug,
+    COUNT(*) FILTER (WHERE choice = 'a'),
+    COUNT(*) FILTER (WHERE choice = 'b')
+FROM responses
+GROUP BY question_id, slug;
+
+INSERT INTO participant_sessions (slug, session_id, event_id, responses)
+SELECT r.slug, r.session_id, MIN(q.event_id), COUNT(*)
+FROM responses r
+JOIN questions q ON q.question_id = r.question_id
+GROUP BY r.slug, r.session_id;
+
+INSERT INTO participant_tallies (event_id, slug, sessions)
+SELECT event_id, slug, COUNT(*)
+FROM participant_sessions
+GROUP BY event_id, slug
+UNION ALL
+SELECT event_id, '', COUNT(DISTINCT session_id)
+FROM participant_sessions
+GROUP BY event_id;
+
+CREATE TRIGGER responses_vote_tally
+AFTER INSERT OR UPDATE OF choice, slug OR DELETE ON responses
+FOR EACH ROW EXECUTE FUNCTION apply_vote_tally();
+
+CREATE TRIGGER responses_participant_tally
+AFTER INSERT OR UPDATE OF slug OR DELETE ON responses
+FOR EACH ROW EXECUTE FUNCTION apply_participant_tally();
@@ -0,0 +1,5 @@
+-- Rollback prize entrants
+
+DROP TABLE IF EXISTS entrant_sessions;
+DROP TABLE IF EXISTS entrants;
+DROP FUNCTION IF EXISTS normalize_email(TEXT);
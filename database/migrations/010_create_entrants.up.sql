@@ -0,0 +1,71 @@
+-- Prize entrants, one per person per event
+-- A fan who clears cookies gets a new session but the same normalized email
+-- or mobile, so they resolve to the same entrant and the draw counts them once
+
+-- Lowercase, drop +tags, and drop dots for Gmail (which ignores them)
+CREATE FUNCTION normalize_email(email TEXT) RETURNS TEXT AS $$
+    SELECT CASE
+        WHEN domain IN ('gmail.com', 'googlemail.com')
+            THEN replace(split_part(local, '+', 1), '.', '') || '@gmail.com'
+        ELSE split_part(local, '+', 1) || '@' || domain
+    END
+    FROM (
+        SELECT split_part(lower(btrim(email)), '@', 1) AS local,
+               split_part(lower(btrim(email)), '@', 2) AS domain
+    ) parts
+$$ LANGUAGE sql IMMUTABLE STRICT;
+
+CREATE TABLE entrants (
+    entrant_id TEXT PRIMARY KEY,
+    event_id TEXT NOT NULL REFERENCES events(event_id) ON DELETE CASCADE,
+    email_normalized TEXT NOT NULL,
+    mobile TEXT NOT NULL, -- E.164
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_entrants_event_email ON entrants(event_id, email_normalized);
+CREATE UNIQUE INDEX idx_entrants_event_mobile ON entrants(event_id, mobile);
+
+-- Sessions that belong to an entrant, at most one entrant per session per event
+CREATE TABLE entrant_sessions (
+    event_id TEXT NOT NULL REFERENCES events(event_id) ON DELETE CASCADE,
+    session_id TEXT NOT NULL REFERENCES sessions(session_id) ON DELETE CASCADE,
+    entrant_id TEXT NOT NULL REFERENCES entrants(entrant_id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (event_id, session_id)
+);
+
+CREATE INDEX idx_entrant_sessions_entrant_id ON entrant_sessions(entrant_id);
+
+-- Backfill from sessions that already entered, earliest voter first
+-- The entrant reuses its first session's KSUID
+WITH entered AS (
+    SELECT q.event_id, s.session_id, normalize_email(s.email) AS email_normalized,
+           s.mobile, MIN(r.created_at) AS first_vote
+    FROM sessions s
+    JOIN responses r ON r.session_id = s.session_id
+    JOIN questions q ON q.question_id = r.question_id
+    WHERE COALESCE(s.name, '') <> ''
+        AND COALESCE(s.email, '') <> ''
+        AND COALESCE(s.mobile, '') <> ''
+    GROUP BY q.event_id, s.session_id
+)
+INSERT INTO entrants (entrant_id, event_id, email_normalized, mobile, created_at)
+SELECT 'entrant_' || substr(session_id, length('voter_') + 1), event_id, email_normalized, mobile, first_vote
+FROM entered
+ORDER BY first_vote ASC, session_id ASC
+ON CONFLICT DO NOTHING;
+
+-- Link every entered session to the entrant it matches, preferring an email match
+INSERT INTO entrant_sessions (event_id, session_id, entrant_id)
+SELECT DISTINCT ON (q.event_id, s.session_id) q.event_id, s.session_id, e.entrant_id
+FROM sessions s
+JOIN responses r ON r.session_id = s.session_id
+JOIN questions q ON q.question_id = r.question_id
+JOIN entrants e ON e.event_id = q.event_id
+    AND (e.email_normalized = normalize_email(s.email) OR e.mobile = s.mobile)
+WHERE COALESCE(s.name, '') <> ''
+    AND COALESCE(s.email, '') <> ''
+    AND COALESCE(s.mobile, '') <> ''
+ORDER BY q.event_id, s.session_id, (e.email_normalized = normalize_email(s.email)) DESC, e.created_at ASC;
@@ -0,0 +1,4 @@
+-- Rollback fraud signals
+
+DROP TABLE IF EXISTS session_risk;
+DROP TABLE IF EXISTS response_signals;
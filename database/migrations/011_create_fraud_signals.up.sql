@@ -0,0 +1,30 @@
+-- Fraud signals
+-- response_signals keeps request metadata for the latest write of each response
+-- (raw IPs are never stored, only a keyed hash) and session_risk holds the
+-- suspicion score computed from them, so tallies can leave flagged sessions out
+
+CREATE TABLE response_signals (
+    question_id TEXT NOT NULL,
+    session_id TEXT NOT NULL,
+    ip_hash TEXT NOT NULL,
+    user_agent TEXT NOT NULL,
+    time_on_page_ms INTEGER,    -- NULL when the vote arrived without the question page being rendered
+    since_previous_ms INTEGER,  -- Gap since the session's previous vote, NULL for the first
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (question_id, session_id),
+    FOREIGN KEY (question_id, session_id) REFERENCES responses(question_id, session_id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_response_signals_session_id ON response_signals(session_id);
+CREATE INDEX idx_response_signals_fingerprint ON response_signals(ip_hash, user_agent);
+
+CREATE TABLE session_risk (
+    session_id TEXT PRIMARY KEY REFERENCES sessions(session_id) ON DELETE CASCADE,
+    score INTEGER NOT NULL CHECK (score BETWEEN 0 AND 100),
+    reasons TEXT[] NOT NULL DEFAULT '{}',
+    flagged BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_session_risk_flagged ON session_risk(session_id) WHERE flagged;
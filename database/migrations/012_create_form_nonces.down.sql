@@ -0,0 +1,3 @@
+-- Rollback form token nonces
+
+DROP TABLE IF EXISTS form_nonces;
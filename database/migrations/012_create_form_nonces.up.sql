@@ -0,0 +1,11 @@
+-- Consumed form token nonces, shared by every instance so a token is single-use
+-- across the fleet rather than once per machine
+-- Rows are only needed until the token would have expired anyway, fraud.DBNonces
+-- deletes them after that
+
+CREATE TABLE form_nonces (
+    nonce TEXT PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_form_nonces_expires_at ON form_nonces(expires_at);
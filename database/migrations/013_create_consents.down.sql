@@ -0,0 +1,3 @@
+-- Rollback consent records
+
+DROP TABLE IF EXISTS consents;
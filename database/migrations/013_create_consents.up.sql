@@ -0,0 +1,17 @@
+-- Consent records, append-only so the history of each decision is kept
+-- Every info form submission writes one row per purpose, granted or not;
+-- a session's current status is its latest row for that purpose
+
+CREATE TABLE consents (
+    consent_id TEXT PRIMARY KEY,
+    session_id TEXT NOT NULL REFERENCES sessions(session_id) ON DELETE CASCADE,
+    event_id TEXT NOT NULL REFERENCES events(event_id) ON DELETE CASCADE,
+    purpose TEXT NOT NULL CHECK (purpose IN ('prize_draw_terms', 'marketing')),
+    granted BOOLEAN NOT NULL,
+    policy_version TEXT NOT NULL,
+    ip_address TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_consents_session_purpose ON consents(session_id, purpose, created_at DESC);
+CREATE INDEX idx_consents_event_purpose ON consents(event_id, purpose);
@@ -0,0 +1,35 @@
+-- Rollback blind indexes
+-- Sealed values can only be read by the application, and entrants indexed by the
+-- backfill have lost their plaintext, so refuse rather than leave either behind
+-- unreadable; this is only safe before `pick6 pii reencrypt` or PII_KEYS were used
+
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM sessions
+        WHERE name LIKE 'enc:%' OR email LIKE 'enc:%' OR mobile LIKE 'enc:%'
+    ) THEN
+        RAISE EXCEPTION 'sessions hold sealed contact details, rolling back would leave them unreadable'
+            USING HINT = 'Restore a backup taken before PII encryption was enabled, or erase the sealed sessions first';
+    END IF;
+    IF EXISTS (SELECT 1 FROM entrants WHERE email_normalized IS NULL OR mobile IS NULL) THEN
+        RAISE EXCEPTION 'entrants only hold blind indexes, their contact details can''t be restored'
+            USING HINT = 'Restore a backup taken before PII encryption was enabled, or delete those entrants first';
+    END IF;
+END $$;
+
+DROP INDEX IF EXISTS idx_entrants_event_mobile_index;
+DROP INDEX IF EXISTS idx_entrants_event_email_index;
+
+ALTER TABLE entrants
+    DROP COLUMN IF EXISTS mobile_index,
+    DROP COLUMN IF EXISTS email_index,
+    ALTER COLUMN email_normalized SET NOT NULL,
+    ALTER COLUMN mobile SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_sessions_mobile_index;
+DROP INDEX IF EXISTS idx_sessions_email_index;
+
+ALTER TABLE sessions
+    DROP COLUMN IF EXISTS mobile_index,
+    DROP COLUMN IF EXISTS email_index;
@@ -0,0 +1,22 @@
+-- Field-level encryption of contact details
+-- The application seals sessions.name, email and mobile with AES-GCM, so equality
+-- lookups move to blind indexes (keyed HMACs of the normalized values)
+-- Existing rows stay readable as plaintext until `pick6 pii reencrypt` seals
+-- them and fills the indexes
+
+ALTER TABLE sessions
+    ADD COLUMN email_index TEXT,
+    ADD COLUMN mobile_index TEXT;
+
+CREATE INDEX idx_sessions_email_index ON sessions(email_index);
+CREATE INDEX idx_sessions_mobile_index ON sessions(mobile_index);
+
+-- Entrants keep only the indexes, the plaintext columns are emptied by the backfill
+ALTER TABLE entrants
+    ADD COLUMN email_index TEXT,
+    ADD COLUMN mobile_index TEXT,
+    ALTER COLUMN email_normalized DROP NOT NULL,
+    ALTER COLUMN mobile DROP NOT NULL;
+
+CREATE UNIQUE INDEX idx_entrants_event_email_index ON entrants(event_id, email_index);
+CREATE UNIQUE INDEX idx_entrants_event_mobile_index ON entrants(event_id, mobile_index);
@@ -0,0 +1,4 @@
+-- Rollback PII retention
+
+DROP TABLE IF EXISTS retention_runs;
+DROP TABLE IF EXISTS event_retention;
@@ -0,0 +1,28 @@
+-- PII retention: contact details are purged a number of days after an event closes
+-- Votes are kept for analytics, only the details that identify a fan go
+
+-- Per-event policy, events without a row use PII_RETENTION_DAYS
+CREATE TABLE event_retention (
+    event_id TEXT PRIMARY KEY REFERENCES events(event_id) ON DELETE CASCADE,
+    retention_days INTEGER CHECK (retention_days > 0), -- NULL uses the default
+    purged_at TIMESTAMP,                               -- Set once the event's PII is purged
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Audit log, one row per purge run (dry runs and failures included)
+CREATE TABLE retention_runs (
+    run_id TEXT PRIMARY KEY,
+    trigger TEXT NOT NULL CHECK (trigger IN ('worker', 'cli', 'admin')),
+    dry_run BOOLEAN NOT NULL,
+    default_days INTEGER NOT NULL,
+    event_ids TEXT[] NOT NULL,
+    sessions_purged BIGINT NOT NULL DEFAULT 0,
+    entrants_deleted BIGINT NOT NULL DEFAULT 0,
+    consents_deleted BIGINT NOT NULL DEFAULT 0,
+    signals_deleted BIGINT NOT NULL DEFAULT 0,
+    error TEXT,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_retention_runs_started_at ON retention_runs(started_at DESC);
@@ -0,0 +1,14 @@
+-- Rollback cross-instance cache invalidation
+
+DROP TRIGGER IF EXISTS sessions_bump_cache_generation ON sessions;
+DROP TRIGGER IF EXISTS slugs_bump_cache_generation ON slugs;
+DROP TRIGGER IF EXISTS questions_bump_cache_generation ON questions;
+DROP TRIGGER IF EXISTS events_bump_cache_generation ON events;
+DROP FUNCTION IF EXISTS bump_cache_generation();
+DROP TABLE IF EXISTS cache_generation;
+
+DROP TRIGGER IF EXISTS sessions_notify_cache ON sessions;
+DROP TRIGGER IF EXISTS slugs_notify_cache ON slugs;
+DROP TRIGGER IF EXISTS questions_notify_cache ON questions;
+DROP TRIGGER IF EXISTS events_notify_cache ON events;
+DROP FUNCTION IF EXISTS notify_cache_invalidation();
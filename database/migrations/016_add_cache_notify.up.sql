@@ -0,0 +1,87 @@
+-- Cross-instance cache invalidation
+-- Every instance caches events/questions by slug and session validity in memory
+-- Triggers NOTIFY 'pick6_cache' with what changed, so each instance's listener can
+-- drop its copy, whichever instance (or psql session) made the write
+-- Payloads: 'event:<event_id>', 'slug:<slug>', 'session:<session_id>'
+-- NOTIFY is delivered on commit and identical payloads in a transaction are sent once
+
+CREATE FUNCTION notify_cache_invalidation() RETURNS trigger AS $$
+DECLARE
+    changed RECORD;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed := OLD;
+    ELSE
+        changed := NEW;
+    END IF;
+
+    CASE TG_TABLE_NAME
+        WHEN 'events' THEN
+            PERFORM pg_notify('pick6_cache', 'event:' || changed.event_id);
+        WHEN 'questions' THEN
+            PERFORM pg_notify('pick6_cache', 'event:' || changed.event_id);
+            IF TG_OP = 'UPDATE' AND OLD.event_id <> NEW.event_id THEN
+                PERFORM pg_notify('pick6_cache', 'event:' || OLD.event_id);
+            END IF;
+        WHEN 'slugs' THEN
+            PERFORM pg_notify('pick6_cache', 'slug:' || changed.slug);
+            IF TG_OP = 'UPDATE' AND OLD.slug <> NEW.slug THEN
+                PERFORM pg_notify('pick6_cache', 'slug:' || OLD.slug);
+            END IF;
+        WHEN 'sessions' THEN
+            PERFORM pg_notify('pick6_cache', 'session:' || changed.session_id);
+    END CASE;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER events_notify_cache
+AFTER INSERT OR UPDATE OR DELETE ON events
+FOR EACH ROW EXECUTE FUNCTION notify_cache_invalidation();
+
+CREATE TRIGGER questions_notify_cache
+AFTER INSERT OR UPDATE OR DELETE ON questions
+FOR EACH ROW EXECUTE FUNCTION notify_cache_invalidation();
+
+CREATE TRIGGER slugs_notify_cache
+AFTER INSERT OR UPDATE OR DELETE ON slugs
+FOR EACH ROW EXECUTE FUNCTION notify_cache_invalidation();
+
+-- New sessions are never cached as invalid, so only deletes matter
+CREATE TRIGGER sessions_notify_cache
+AFTER DELETE ON sessions
+FOR EACH ROW EXECUTE FUNCTION notify_cache_invalidation();
+
+-- Bumped by the same writes, polled while an instance's listener is disconnected
+CREATE TABLE cache_generation (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    generation BIGINT NOT NULL,
+    changed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO cache_generation (id, generation) VALUES (1, 0);
+
+CREATE FUNCTION bump_cache_generation() RETURNS trigger AS $$
+BEGIN
+    UPDATE cache_generation SET generation = generation + 1, changed_at = NOW() WHERE id = 1;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Once per statement, so bulk deletes don't queue on the single row
+CREATE TRIGGER events_bump_cache_generation
+AFTER INSERT OR UPDATE OR DELETE ON events
+FOR EACH STATEMENT EXECUTE FUNCTION bump_cache_generation();
+
+CREATE TRIGGER questions_bump_cache_generation
+AFTER INSERT OR UPDATE OR DELETE ON questions
+FOR EACH STATEMENT EXECUTE FUNCTION bump_cache_generation();
+
+CREATE TRIGGER slugs_bump_cache_generation
+AFTER INSERT OR UPDATE OR DELETE ON slugs
+FOR EACH STATEMENT EXECUTE FUNCTION bump_cache_generation();
+
+CREATE TRIGGER sessions_bump_cache_generation
+AFTER DELETE ON sessions
+FOR EACH STATEMENT EXECUTE FUNCTION bump_cache_generation();
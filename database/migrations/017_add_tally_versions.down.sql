@@ -0,0 +1,34 @@
+-- Rollback tally versions
+
+CREATE OR REPLACE FUNCTION apply_vote_tally() RETURNS trigger AS $$
+BEGIN
+    -- Re-voting for the same fighter from the same slug changes nothing
+    IF TG_OP = 'UPDATE' AND OLD.choice = NEW.choice AND OLD.slug = NEW.slug THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE vote_tallies
+        SET votes_a = votes_a - CASE WHEN OLD.choice = 'a' THEN 1 ELSE 0 END,
+            votes_b = votes_b - CASE WHEN OLD.choice = 'b' THEN 1 ELSE 0 END
+        WHERE question_id = OLD.question_id AND slug = OLD.slug;
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO vote_tallies (question_id, slug, votes_a, votes_b)
+        VALUES (
+            NEW.question_id,
+            NEW.slug,
+            CASE WHEN NEW.choice = 'a' THEN 1 ELSE 0 END,
+            CASE WHEN NEW.choice = 'b' THEN 1 ELSE 0 END
+        )
+        ON CONFLICT (question_id, slug) DO UPDATE
+        SET votes_a = vote_tallies.votes_a + EXCLUDED.votes_a,
+            votes_b = vote_tallies.votes_b + EXCLUDED.votes_b;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE vote_tallies DROP COLUMN IF EXISTS version;
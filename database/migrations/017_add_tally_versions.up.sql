@@ -0,0 +1,39 @@
+-- Count every change to a tally row, so the public API can tell whether an event's
+-- numbers moved from SUM(version) alone and answer If-None-Match without recounting
+-- A vote switching fighters leaves the totals unchanged but still bumps the version
+
+ALTER TABLE vote_tallies ADD COLUMN version BIGINT NOT NULL DEFAULT 0;
+
+CREATE OR REPLACE FUNCTION apply_vote_tally() RETURNS trigger AS $$
+BEGIN
+    -- Re-voting for the same fighter from the same slug changes nothing
+    IF TG_OP = 'UPDATE' AND OLD.choice = NEW.choice AND OLD.slug = NEW.slug THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE vote_tallies
+        SET votes_a = votes_a - CASE WHEN OLD.choice = 'a' THEN 1 ELSE 0 END,
+            votes_b = votes_b - CASE WHEN OLD.choice = 'b' THEN 1 ELSE 0 END,
+            version = version + 1
+        WHERE question_id = OLD.question_id AND slug = OLD.slug;
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO vote_tallies (question_id, slug, votes_a, votes_b, version)
+        VALUES (
+            NEW.question_id,
+            NEW.slug,
+            CASE WHEN NEW.choice = 'a' THEN 1 ELSE 0 END,
+            CASE WHEN NEW.choice = 'b' THEN 1 ELSE 0 END,
+            1
+        )
+        ON CONFLICT (question_id, slug) DO UPDATE
+        SET votes_a = vote_tallies.votes_a + EXCLUDED.votes_a,
+            votes_b = vote_tallies.votes_b + EXCLUDED.votes_b,
+            version = vote_tallies.version + 1;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
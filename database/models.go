@@ -7,22 +7,104 @@ package database
 import (
 	"database/sql"
 	"time"
+
+	"github.com/mrbennbenn/pick6/database/pii"
 )
 
+type CacheGeneration struct {
+	ID         int32     `json:"id"`
+	Generation int64     `json:"generation"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
+
+type Consent struct {
+	ConsentID     string    `json:"consent_id"`
+	SessionID     string    `json:"session_id"`
+	EventID       string    `json:"event_id"`
+	Purpose       string    `json:"purpose"`
+	Granted       bool      `json:"granted"`
+	PolicyVersion string    `json:"policy_version"`
+	IpAddress     string    `json:"ip_address"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type Draw struct {
+	DrawID          string         `json:"draw_id"`
+	EventID         string         `json:"event_id"`
+	Algorithm       string         `json:"algorithm"`
+	Seed            int64          `json:"seed"`
+	PerfectOnly     bool           `json:"perfect_only"`
+	Excluded        []string       `json:"excluded"`
+	EligibleCount   int32          `json:"eligible_count"`
+	EligibleDigest  string         `json:"eligible_digest"`
+	WinnerSessionID sql.NullString `json:"winner_session_id"`
+	Alternates      []string       `json:"alternates"`
+	RedrawOf        sql.NullString `json:"redraw_of"`
+	RedrawReason    sql.NullString `json:"redraw_reason"`
+	CreatedAt       time.Time      `json:"created_at"`
+}
+
+type Entrant struct {
+	EntrantID       string         `json:"entrant_id"`
+	EventID         string         `json:"event_id"`
+	EmailNormalized sql.NullString `json:"email_normalized"`
+	Mobile          sql.NullString `json:"mobile"`
+	CreatedAt       time.Time      `json:"created_at"`
+	EmailIndex      sql.NullString `json:"email_index"`
+	MobileIndex     sql.NullString `json:"mobile_index"`
+}
+
+type EntrantSession struct {
+	EventID   string    `json:"event_id"`
+	SessionID string    `json:"session_id"`
+	EntrantID string    `json:"entrant_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type Event struct {
-	EventID     string    `json:"event_id"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
+	EventID         string         `json:"event_id"`
+	Description     string         `json:"description"`
+	CreatedAt       time.Time      `json:"created_at"`
+	OnAirQuestionID sql.NullString `json:"on_air_question_id"`
+}
+
+type EventRetention struct {
+	EventID       string        `json:"event_id"`
+	RetentionDays sql.NullInt32 `json:"retention_days"`
+	PurgedAt      sql.NullTime  `json:"purged_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+}
+
+type FormNonce struct {
+	Nonce     string    `json:"nonce"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type ParticipantSession struct {
+	Slug      string `json:"slug"`
+	SessionID string `json:"session_id"`
+	EventID   string `json:"event_id"`
+	Responses int32  `json:"responses"`
+}
+
+type ParticipantTally struct {
+	EventID  string `json:"event_id"`
+	Slug     string `json:"slug"`
+	Sessions int64  `json:"sessions"`
 }
 
 type Question struct {
-	QuestionID    string `json:"question_id"`
-	EventID       string `json:"event_id"`
-	BigText       string `json:"big_text"`
-	SmallText     string `json:"small_text"`
-	ImageFilename string `json:"image_filename"`
-	ChoiceA       string `json:"choice_a"`
-	ChoiceB       string `json:"choice_b"`
+	QuestionID    string         `json:"question_id"`
+	EventID       string         `json:"event_id"`
+	BigText       string         `json:"big_text"`
+	SmallText     string         `json:"small_text"`
+	ImageFilename string         `json:"image_filename"`
+	ChoiceA       string         `json:"choice_a"`
+	ChoiceB       string         `json:"choice_b"`
+	Result        sql.NullString `json:"result"`
+	ResultSetAt   sql.NullTime   `json:"result_set_at"`
+	OpensAt       sql.NullTime   `json:"opens_at"`
+	ClosesAt      sql.NullTime   `json:"closes_at"`
 }
 
 type Response struct {
@@ -34,11 +116,46 @@ type Response struct {
 	UpdatedAt  time.Time `json:"updated_at"`
 }
 
+type ResponseSignal struct {
+	QuestionID      string        `json:"question_id"`
+	SessionID       string        `json:"session_id"`
+	IpHash          string        `json:"ip_hash"`
+	UserAgent       string        `json:"user_agent"`
+	TimeOnPageMs    sql.NullInt32 `json:"time_on_page_ms"`
+	SincePreviousMs sql.NullInt32 `json:"since_previous_ms"`
+	CreatedAt       time.Time     `json:"created_at"`
+}
+
+type RetentionRun struct {
+	RunID           string         `json:"run_id"`
+	Trigger         string         `json:"trigger"`
+	DryRun          bool           `json:"dry_run"`
+	DefaultDays     int32          `json:"default_days"`
+	EventIds        []string       `json:"event_ids"`
+	SessionsPurged  int64          `json:"sessions_purged"`
+	EntrantsDeleted int64          `json:"entrants_deleted"`
+	ConsentsDeleted int64          `json:"consents_deleted"`
+	SignalsDeleted  int64          `json:"signals_deleted"`
+	Error           sql.NullString `json:"error"`
+	StartedAt       time.Time      `json:"started_at"`
+	FinishedAt      time.Time      `json:"finished_at"`
+}
+
 type Session struct {
-	SessionID string         `json:"session_id"`
-	Name      sql.NullString `json:"name"`
-	Email     sql.NullString `json:"email"`
-	Mobile    sql.NullString `json:"mobile"`
+	SessionID   string         `json:"session_id"`
+	Name        pii.String     `json:"name"`
+	Email       pii.String     `json:"email"`
+	Mobile      pii.String     `json:"mobile"`
+	EmailIndex  sql.NullString `json:"email_index"`
+	MobileIndex sql.NullString `json:"mobile_index"`
+}
+
+type SessionRisk struct {
+	SessionID string    `json:"session_id"`
+	Score     int32     `json:"score"`
+	Reasons   []string  `json:"reasons"`
+	Flagged   bool      `json:"flagged"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type Slug struct {
@@ -46,3 +163,11 @@ type Slug struct {
 	EventID   string    `json:"event_id"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+type VoteTally struct {
+	QuestionID string `json:"question_id"`
+	Slug       string `json:"slug"`
+	VotesA     int64  `json:"votes_a"`
+	VotesB     int64  `json:"votes_b"`
+	Version    int64  `json:"version"`
+}
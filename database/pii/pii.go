@@ -0,0 +1,215 @@
+// Package pii encrypts personal data at rest and derives blind indexes for lookups
+//
+// Contact details are sealed with AES-256-GCM before they are written and opened
+// when they are scanned, via the String type that sqlc uses for the sessions
+// name, email and mobile columns. Sealed values carry the ID of the key that
+// sealed them, so old keys keep decrypting after a new primary key is added.
+// Values written before encryption was enabled are read back as they are.
+//
+// Ciphertext is randomised, so equality lookups use a blind index instead: an
+// HMAC of the normalized value under a separate key that never rotates.
+package pii
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// prefix marks a sealed value, followed by "<key id>:<base64 nonce+ciphertext>"
+const prefix = "enc:v1:"
+
+// escapePrefix marks plaintext stored without keys that itself starts with "enc:",
+// so a fan typing "enc:v1:..." into a form is never mistaken for a sealed value
+const escapePrefix = "enc:raw:"
+
+// minSealed is the shortest sealed payload, a GCM nonce plus tag
+const minSealed = 12 + 16
+
+var (
+	// ErrNoKeys is returned when a sealed value is read without keys configured
+	ErrNoKeys = errors.New("pii: value is encrypted but no keys are configured")
+	// ErrUnknownKey is returned when a sealed value names a key that isn't configured
+	ErrUnknownKey = errors.New("pii: value is encrypted with an unknown key")
+)
+
+// Keys seals and opens values and computes blind indexes
+type Keys struct {
+	primary  string                 // Seals new values
+	aeads    map[string]cipher.AEAD // Every configured key opens
+	indexKey []byte
+}
+
+// active is used by String, which database/sql drives without a way to pass it in
+var active atomic.Pointer[Keys]
+
+// Use installs keys for String and the index helpers, nil stores plaintext
+func Use(k *Keys) {
+	active.Store(k)
+}
+
+// Enabled reports whether keys are installed, so new values are sealed
+func Enabled() bool {
+	return active.Load() != nil
+}
+
+// NewKeys parses "id:base64key" entries (first seals, all open) and a base64 index key
+// Encryption keys must be 32 bytes (AES-256), the index key at least 32 bytes
+// Returns nil when neither is given, leaving PII in plaintext
+// An index key without encryption keys is an error rather than being ignored:
+// the indexes would silently fall back to plain, guessable SHA-256
+func NewKeys(keys []string, indexKey string) (*Keys, error) {
+	if len(keys) == 0 {
+		if strings.TrimSpace(indexKey) != "" {
+			return nil, errors.New("pii: index key is set without encryption keys, set both or neither")
+		}
+		return nil, nil
+	}
+
+	k := &Keys{aeads: make(map[string]cipher.AEAD, len(keys))}
+	for _, entry := range keys {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("pii: key %q must be id:base64key", entry)
+		}
+		if _, dup := k.aeads[id]; dup {
+			return nil, fmt.Errorf("pii: duplicate key id %q", id)
+		}
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("pii: key %q: %w", id, err)
+		}
+		if len(raw) != 32 {
+			return nil, fmt.Errorf("pii: key %q is %d bytes, want 32", id, len(raw))
+		}
+		block, err := aes.NewCipher(raw)
+		if err != nil {
+			return nil, fmt.Errorf("pii: key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("pii: key %q: %w", id, err)
+		}
+		k.aeads[id] = aead
+		if k.primary == "" {
+			k.primary = id
+		}
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(indexKey)
+	if err != nil {
+		return nil, fmt.Errorf("pii: index key: %w", err)
+	}
+	if len(raw) < 32 {
+		return nil, errors.New("pii: index key must be at least 32 bytes")
+	}
+	k.indexKey = raw
+
+	return k, nil
+}
+
+// Seal encrypts a value with the primary key
+// A nil receiver returns it unchanged, escaped if it starts with "enc:"
+func (k *Keys) Seal(plaintext string) (string, error) {
+	if k == nil {
+		if strings.HasPrefix(plaintext, "enc:") {
+			return escapePrefix + plaintext, nil
+		}
+		return plaintext, nil
+	}
+	aead := k.aeads[k.primary]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("pii: nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return prefix + k.primary + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a sealed value, anything without the prefix is legacy plaintext
+// The second result reports whether the value should be re-sealed with the primary key
+func (k *Keys) Open(value string) (string, bool, error) {
+	if plaintext, escaped := strings.CutPrefix(value, escapePrefix); escaped {
+		return plaintext, k != nil, nil
+	}
+	rest, sealed := strings.CutPrefix(value, prefix)
+	if !sealed {
+		return value, k != nil, nil
+	}
+	if k == nil {
+		// Plaintext stored before escaping can start with the prefix too, only
+		// something shaped like a sealed value needs keys to read
+		if !wellFormed(rest) {
+			return value, false, nil
+		}
+		return "", false, ErrNoKeys
+	}
+
+	id, encoded, _ := strings.Cut(rest, ":")
+	aead, ok := k.aeads[id]
+	if !ok {
+		return "", false, fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(raw) < aead.NonceSize() {
+		return "", false, fmt.Errorf("pii: malformed value for key %q", id)
+	}
+	plaintext, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], nil)
+	if err != nil {
+		return "", false, fmt.Errorf("pii: decrypt with key %q: %w", id, err)
+	}
+	return string(plaintext), id != k.primary, nil
+}
+
+// wellFormed reports whether the part after the prefix looks like "<key id>:<base64>"
+// with room for a nonce and tag
+func wellFormed(rest string) bool {
+	id, encoded, ok := strings.Cut(rest, ":")
+	if !ok || id == "" {
+		return false
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	return err == nil && len(raw) >= minSealed
+}
+
+// index is the hex HMAC-SHA256 of a namespaced value
+// Without keys it is a plain SHA-256, still usable for equality but guessable
+func (k *Keys) index(namespace, value string) string {
+	var key []byte
+	if k != nil {
+		key = k.indexKey
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(namespace + ":" + value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// EmailIndex is the blind index of an email, equal for addresses NormalizeEmail treats as one
+func EmailIndex(email string) string {
+	return active.Load().index("email", NormalizeEmail(email))
+}
+
+// MobileIndex is the blind index of a mobile number, which should already be E.164
+func MobileIndex(mobile string) string {
+	return active.Load().index("mobile", strings.TrimSpace(mobile))
+}
+
+// NormalizeEmail matches the normalize_email SQL function: lowercase, drop
+// +tags, and drop dots for Gmail (which ignores them)
+func NormalizeEmail(email string) string {
+	local, domain, _ := strings.Cut(strings.ToLower(strings.TrimSpace(email)), "@")
+	domain, _, _ = strings.Cut(domain, "@")
+	local, _, _ = strings.Cut(local, "+")
+	if domain == "gmail.com" || domain == "googlemail.com" {
+		return strings.ReplaceAll(local, ".", "") + "@gmail.com"
+	}
+	return local + "@" + domain
+}
@@ -0,0 +1,237 @@
+package pii
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte, n int) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(rune(b)), n)))
+}
+
+func mustKeys(t *testing.T, keys ...string) *Keys {
+	t.Helper()
+	k, err := NewKeys(keys, testKey('i', 32))
+	if err != nil {
+		t.Fatalf("NewKeys: %v", err)
+	}
+	return k
+}
+
+func TestNewKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     []string
+		indexKey string
+		wantNil  bool
+		wantErr  bool
+	}{
+		{"no keys leaves plaintext", nil, "", true, false},
+		{"index key without keys", nil, testKey('i', 32), false, true},
+		{"one key", []string{"k1:" + testKey('a', 32)}, testKey('i', 32), false, false},
+		{"rotation list", []string{"k2:" + testKey('b', 32), "k1:" + testKey('a', 32)}, testKey('i', 32), false, false},
+		{"missing id", []string{testKey('a', 32)}, testKey('i', 32), false, true},
+		{"empty id", []string{":" + testKey('a', 32)}, testKey('i', 32), false, true},
+		{"duplicate id", []string{"k1:" + testKey('a', 32), "k1:" + testKey('b', 32)}, testKey('i', 32), false, true},
+		{"bad base64", []string{"k1:not base64"}, testKey('i', 32), false, true},
+		{"short key", []string{"k1:" + testKey('a', 16)}, testKey('i', 32), false, true},
+		{"short index key", []string{"k1:" + testKey('a', 32)}, testKey('i', 16), false, true},
+		{"missing index key", []string{"k1:" + testKey('a', 32)}, "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := NewKeys(tt.keys, tt.indexKey)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (k == nil) != tt.wantNil {
+				t.Fatalf("keys = %v, wantNil %v", k, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	k := mustKeys(t, "k1:"+testKey('a', 32))
+
+	for _, plaintext := range []string{"", "Ada Lovelace", "ada@example.com", "+447700900123", "enc:v1:k1:looks sealed", "ünïcödé"} {
+		sealed, err := k.Seal(plaintext)
+		if err != nil {
+			t.Fatalf("Seal(%q): %v", plaintext, err)
+		}
+		if !strings.HasPrefix(sealed, prefix+"k1:") {
+			t.Fatalf("Seal(%q) = %q, want the k1 prefix", plaintext, sealed)
+		}
+		if plaintext != "" && strings.Contains(sealed, plaintext) {
+			t.Fatalf("Seal(%q) leaks the plaintext", plaintext)
+		}
+		got, stale, err := k.Open(sealed)
+		if err != nil {
+			t.Fatalf("Open(%q): %v", sealed, err)
+		}
+		if got != plaintext || stale {
+			t.Fatalf("Open = %q stale=%v, want %q stale=false", got, stale, plaintext)
+		}
+	}
+
+	a, _ := k.Seal("same")
+	b, _ := k.Seal("same")
+	if a == b {
+		t.Fatal("sealing twice gave the same ciphertext")
+	}
+}
+
+func TestOpenRotation(t *testing.T) {
+	old := mustKeys(t, "k1:"+testKey('a', 32))
+	rotated := mustKeys(t, "k2:"+testKey('b', 32), "k1:"+testKey('a', 32))
+	other := mustKeys(t, "k3:"+testKey('c', 32))
+
+	sealedOld, _ := old.Seal("ada@example.com")
+	sealedNew, _ := rotated.Seal("ada@example.com")
+
+	tests := []struct {
+		name      string
+		keys      *Keys
+		value     string
+		want      string
+		wantStale bool
+		wantErr   error
+	}{
+		{"old key still opens", rotated, sealedOld, "ada@example.com", true, nil},
+		{"primary key is fresh", rotated, sealedNew, "ada@example.com", false, nil},
+		{"legacy plaintext is stale", rotated, "ada@example.com", "ada@example.com", true, nil},
+		{"escaped plaintext is stale", rotated, escapePrefix + "enc:x", "enc:x", true, nil},
+		{"unknown key", other, sealedOld, "", false, ErrUnknownKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, stale, err := tt.keys.Open(tt.value)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want || stale != tt.wantStale {
+				t.Fatalf("Open = %q stale=%v, want %q stale=%v", got, stale, tt.want, tt.wantStale)
+			}
+		})
+	}
+}
+
+func TestOpenTampered(t *testing.T) {
+	k := mustKeys(t, "k1:"+testKey('a', 32))
+	sealed, _ := k.Seal("ada@example.com")
+
+	for name, value := range map[string]string{
+		"bad base64": prefix + "k1:???",
+		"too short":  prefix + "k1:" + base64.StdEncoding.EncodeToString([]byte("short")),
+		"flipped":    sealed[:len(sealed)-4] + "AAA=",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := k.Open(value); err == nil {
+				t.Fatalf("Open(%q) succeeded", value)
+			}
+		})
+	}
+}
+
+func TestWithoutKeys(t *testing.T) {
+	var k *Keys
+	sealed, _ := mustKeys(t, "k1:"+testKey('a', 32)).Seal("ada@example.com")
+
+	tests := []struct {
+		name      string
+		plaintext string
+		stored    string
+	}{
+		{"plain", "Ada Lovelace", "Ada Lovelace"},
+		{"looks sealed", "enc:v1:k1:abc", escapePrefix + "enc:v1:k1:abc"},
+		{"looks escaped", "enc:raw:abc", escapePrefix + "enc:raw:abc"},
+		{"enc without version", "enc:", escapePrefix + "enc:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored, err := k.Seal(tt.plaintext)
+			if err != nil || stored != tt.stored {
+				t.Fatalf("Seal = %q, %v, want %q", stored, err, tt.stored)
+			}
+			got, stale, err := k.Open(stored)
+			if err != nil || got != tt.plaintext || stale {
+				t.Fatalf("Open = %q stale=%v, %v, want %q", got, stale, err, tt.plaintext)
+			}
+		})
+	}
+
+	// Rows written before escaping existed come back raw unless they really are sealed
+	for _, raw := range []string{"enc:v1:", "enc:v1:k1", "enc:v1:k1:not base64", "enc:v1::" + base64.StdEncoding.EncodeToString(make([]byte, minSealed))} {
+		got, _, err := k.Open(raw)
+		if err != nil || got != raw {
+			t.Fatalf("Open(%q) = %q, %v, want it raw", raw, got, err)
+		}
+	}
+	if _, _, err := k.Open(sealed); !errors.Is(err, ErrNoKeys) {
+		t.Fatalf("Open(sealed) err = %v, want ErrNoKeys", err)
+	}
+}
+
+// Cases follow the normalize_email SQL function in 010_create_entrants, which
+// entrant matching and the GDPR export rely on giving the same answer
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		email string
+		want  string
+	}{
+		{"ada@example.com", "ada@example.com"},
+		{"  Ada@Example.COM  ", "ada@example.com"},
+		{"ada+pick6@example.com", "ada@example.com"},
+		{"ada+a+b@example.com", "ada@example.com"},
+		{"a.d.a@example.com", "a.d.a@example.com"},
+		{"A.D.A+tag@Gmail.com", "ada@gmail.com"},
+		{"ada.l@googlemail.com", "adal@gmail.com"},
+		{"ada@gmail.com.evil", "ada@gmail.com.evil"},
+		{"ada", "ada@"},
+		{"ada@one@two", "ada@one"},
+		{"", "@"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeEmail(tt.email); got != tt.want {
+			t.Errorf("NormalizeEmail(%q) = %q, want %q", tt.email, got, tt.want)
+		}
+	}
+}
+
+func TestIndexes(t *testing.T) {
+	t.Cleanup(func() { Use(nil) })
+
+	Use(nil)
+	plain := EmailIndex("ada@example.com")
+
+	Use(mustKeys(t, "k1:"+testKey('a', 32)))
+	tests := []struct {
+		name  string
+		a, b  string
+		index func(string) string
+		equal bool
+	}{
+		{"normalized emails match", "Ada+x@Example.com", "ada@example.com", EmailIndex, true},
+		{"gmail dots match", "a.da@gmail.com", "ada@googlemail.com", EmailIndex, true},
+		{"different emails differ", "ada@example.com", "bob@example.com", EmailIndex, false},
+		{"mobiles are trimmed", " +447700900123 ", "+447700900123", MobileIndex, true},
+		{"namespaces differ", "x", "x", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := EmailIndex(tt.a), MobileIndex(tt.b)
+			if tt.index != nil {
+				a, b = tt.index(tt.a), tt.index(tt.b)
+			}
+			if (a == b) != tt.equal {
+				t.Fatalf("index(%q) == index(%q) is %v, want %v", tt.a, tt.b, a == b, tt.equal)
+			}
+		})
+	}
+
+	if EmailIndex("ada@example.com") == plain {
+		t.Fatal("keyed index equals the unkeyed one")
+	}
+}
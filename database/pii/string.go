@@ -0,0 +1,59 @@
+package pii
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// String is a nullable text column sealed on write and opened on read
+// Field names match sql.NullString, so callers use it the same way
+type String struct {
+	String string
+	Valid  bool // Valid is true if String is not NULL
+	Stale  bool // Read as plaintext or with a non-primary key, re-seal to rotate
+}
+
+// NewString is a valid String, or NULL when s is empty
+func NewString(s string) String {
+	return String{String: s, Valid: s != ""}
+}
+
+// Scan implements sql.Scanner, opening sealed values with the active keys
+func (s *String) Scan(value interface{}) error {
+	var raw string
+	switch v := value.(type) {
+	case nil:
+		*s = String{}
+		return nil
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	default:
+		return fmt.Errorf("pii: cannot scan %T into String", value)
+	}
+
+	plaintext, stale, err := active.Load().Open(raw)
+	if err != nil {
+		return err
+	}
+	*s = String{String: plaintext, Valid: true, Stale: stale}
+	return nil
+}
+
+// Value implements driver.Valuer, sealing with the active primary key
+func (s String) Value() (driver.Value, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	return active.Load().Seal(s.String)
+}
+
+// MarshalJSON renders the plaintext, or null
+func (s String) MarshalJSON() ([]byte, error) {
+	if !s.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(s.String)
+}
@@ -0,0 +1,3 @@
+-- name: GetCacheGeneration :one
+-- Bumped by triggers on every write that invalidates cached events, slugs or sessions
+SELECT generation FROM cache_generation WHERE id = 1;
@@ -0,0 +1,25 @@
+-- name: CreateConsent :exec
+INSERT INTO consents (consent_id, session_id, event_id, purpose, granted, policy_version, ip_address)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: ListConsentsBySessions :many
+SELECT * FROM consents
+WHERE session_id = ANY(sqlc.arg(session_ids)::text[])
+ORDER BY session_id, created_at;
+
+-- name: ListMarketingContacts :many
+-- Sessions whose latest marketing decision for the event is an opt-in
+SELECT s.session_id, s.name, s.email, s.mobile, c.policy_version, c.created_at as opted_in_at
+FROM (
+    SELECT DISTINCT ON (session_id) session_id, granted, policy_version, created_at
+    FROM consents
+    WHERE event_id = $1 AND purpose = 'marketing'
+    ORDER BY session_id, created_at DESC
+) c
+JOIN sessions s ON s.session_id = c.session_id
+WHERE c.granted
+    AND COALESCE(s.email, '') <> ''
+ORDER BY c.created_at ASC;
+
+-- name: DeleteConsentsBySessions :execrows
+DELETE FROM consents WHERE session_id = ANY(sqlc.arg(session_ids)::text[]);
@@ -0,0 +1,48 @@
+-- Prize draw eligibility
+-- Eligible sessions gave name, email and mobile and answered every question
+-- With perfect_only, they must also have every decisive result correct
+-- Each entrant counts once, through their earliest linked session that qualifies
+-- Excluding a session (an earlier winner) excludes every session of that entrant
+
+-- name: ListDrawEntrants :many
+SELECT session_id FROM (
+    SELECT DISTINCT ON (es.entrant_id) s.session_id
+    FROM sessions s
+    JOIN entrant_sessions es ON es.session_id = s.session_id AND es.event_id = sqlc.arg(event_id)
+    JOIN responses r ON r.session_id = s.session_id
+    JOIN questions q ON q.question_id = r.question_id
+    WHERE q.event_id = sqlc.arg(event_id)
+        AND COALESCE(s.name, '') <> ''
+        AND COALESCE(s.email, '') <> ''
+        AND COALESCE(s.mobile, '') <> ''
+        AND es.entrant_id NOT IN (
+            SELECT x.entrant_id FROM entrant_sessions x
+            WHERE x.event_id = sqlc.arg(event_id) AND x.session_id = ANY(sqlc.arg(excluded)::text[])
+        )
+    GROUP BY es.entrant_id, es.created_at, s.session_id
+    HAVING COUNT(*) = (SELECT COUNT(*) FROM questions WHERE event_id = sqlc.arg(event_id))
+        AND (
+            NOT sqlc.arg(perfect_only)::boolean
+            OR COUNT(*) FILTER (WHERE r.choice = q.result) =
+                (SELECT COUNT(*) FROM questions WHERE event_id = sqlc.arg(event_id) AND result IN ('a', 'b'))
+        )
+    ORDER BY es.entrant_id, es.created_at ASC, s.session_id ASC
+) entrants
+ORDER BY session_id ASC;
+
+-- name: CreateDraw :one
+INSERT INTO draws (
+    draw_id, event_id, algorithm, seed, perfect_only, excluded,
+    eligible_count, eligible_digest, winner_session_id, alternates,
+    redraw_of, redraw_reason
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+RETURNING *;
+
+-- name: GetDraw :one
+SELECT * FROM draws WHERE draw_id = $1;
+
+-- name: ListDrawsByEvent :many
+SELECT * FROM draws
+WHERE event_id = $1
+ORDER BY created_at ASC;
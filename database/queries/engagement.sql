@@ -1,23 +1,27 @@
 -- Event-Level Engagement Queries
 
 -- name: GetEventEngagementTotal :one
-SELECT 
-    COUNT(DISTINCT r.session_id) as sessions,
-    COUNT(*) as total_votes
-FROM responses r
-JOIN questions q ON q.question_id = r.question_id
-WHERE q.event_id = $1;
+-- Sessions come from participant_tallies, vote totals from vote_tallies
+SELECT
+    COALESCE((SELECT p.sessions
+     FROM participant_tallies p
+     WHERE p.event_id = $1 AND p.slug = ''), 0)::bigint as sessions,
+    (SELECT COALESCE(SUM(t.votes_a + t.votes_b), 0)::bigint
+     FROM vote_tallies t
+     JOIN questions q ON q.question_id = t.question_id
+     WHERE q.event_id = $1) as total_votes;
 
 -- name: GetEventEngagementBySlug :many
-SELECT 
+SELECT
     s.slug,
-    COUNT(DISTINCT r.session_id) as sessions,
-    COALESCE(COUNT(r.session_id), 0) as total_votes
+    COALESCE(p.sessions, 0)::bigint as sessions,
+    (SELECT COALESCE(SUM(t.votes_a + t.votes_b), 0)::bigint
+     FROM vote_tallies t
+     JOIN questions q ON q.question_id = t.question_id
+     WHERE t.slug = s.slug AND q.event_id = sqlc.arg(event_id)) as total_votes
 FROM slugs s
-LEFT JOIN responses r ON r.slug = s.slug
-    AND r.question_id IN (SELECT question_id FROM questions WHERE questions.event_id = sqlc.arg(event_id))
+LEFT JOIN participant_tallies p ON p.event_id = s.event_id AND p.slug = s.slug
 WHERE s.event_id = sqlc.arg(event_id)
-GROUP BY s.slug
 ORDER BY s.slug;
 
 -- name: GetEventRetentionBySlug :many
@@ -33,26 +37,51 @@ WHERE s.event_id = $1 AND q.event_id = $1
 GROUP BY s.slug, q.question_id, q.big_text
 ORDER BY s.slug, q.question_id ASC;
 
+-- name: GetEventVersion :one
+-- Changes whenever a public API response for the event could: edits to events,
+-- questions or slugs (cache_generation), votes (vote_tallies.version), and
+-- questions opening or closing as their voting windows pass
+SELECT
+    (SELECT generation FROM cache_generation WHERE id = 1) as generation,
+    (SELECT COALESCE(SUM(t.version), 0)::bigint
+     FROM vote_tallies t
+     JOIN questions q ON q.question_id = t.question_id
+     WHERE q.event_id = $1) as tally_version,
+    (SELECT COALESCE(string_agg(q.question_id, ',' ORDER BY q.question_id), '')::text
+     FROM questions q
+     WHERE q.event_id = $1
+         AND q.result IS NULL
+         AND (q.opens_at IS NULL OR q.opens_at <= NOW())
+         AND (q.closes_at IS NULL OR q.closes_at > NOW())) as open_questions;
+
 -- Question-Level Engagement Queries
 
 -- name: GetQuestionEngagementTotal :one
-SELECT 
-    COUNT(DISTINCT session_id) as sessions,
-    COUNT(*) as total_votes,
-    COALESCE(SUM(CASE WHEN choice = 'a' THEN 1 ELSE 0 END), 0) as votes_a,
-    COALESCE(SUM(CASE WHEN choice = 'b' THEN 1 ELSE 0 END), 0) as votes_b
-FROM responses
+-- Read from vote_tallies, one response per session means votes = sessions
+SELECT
+    COALESCE(SUM(votes_a + votes_b), 0)::bigint as sessions,
+    COALESCE(SUM(votes_a + votes_b), 0)::bigint as total_votes,
+    COALESCE(SUM(votes_a), 0)::bigint as votes_a,
+    COALESCE(SUM(votes_b), 0)::bigint as votes_b
+FROM vote_tallies
 WHERE question_id = $1;
 
 -- name: GetQuestionEngagementBySlug :many
-SELECT 
+SELECT
     s.slug,
-    COUNT(DISTINCT r.session_id) as sessions,
-    COALESCE(COUNT(r.session_id), 0) as total_votes,
-    COALESCE(SUM(CASE WHEN r.choice = 'a' THEN 1 ELSE 0 END), 0) as votes_a,
-    COALESCE(SUM(CASE WHEN r.choice = 'b' THEN 1 ELSE 0 END), 0) as votes_b
+    COALESCE(t.votes_a + t.votes_b, 0)::bigint as sessions,
+    COALESCE(t.votes_a + t.votes_b, 0)::bigint as total_votes,
+    COALESCE(t.votes_a, 0)::bigint as votes_a,
+    COALESCE(t.votes_b, 0)::bigint as votes_b
 FROM slugs s
-LEFT JOIN responses r ON r.slug = s.slug AND r.question_id = $1
+LEFT JOIN vote_tallies t ON t.slug = s.slug AND t.question_id = $1
 WHERE s.event_id = (SELECT event_id FROM questions WHERE question_id = $1)
-GROUP BY s.slug
 ORDER BY s.slug;
+
+-- name: ListQuestionTalliesByEvent :many
+-- Every question's per-slug tallies in one query, for event summaries
+SELECT t.question_id, t.slug, t.votes_a, t.votes_b
+FROM vote_tallies t
+JOIN questions q ON q.question_id = t.question_id
+WHERE q.event_id = $1
+ORDER BY t.question_id, t.slug;
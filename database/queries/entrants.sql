@@ -0,0 +1,43 @@
+-- name: CreateEntrant :one
+INSERT INTO entrants (entrant_id, event_id, email_index, mobile_index)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetEntrantBySession :one
+SELECT e.* FROM entrants e
+JOIN entrant_sessions es ON es.entrant_id = e.entrant_id
+WHERE es.event_id = $1 AND es.session_id = $2;
+
+-- name: LinkEntrantSession :exec
+INSERT INTO entrant_sessions (event_id, session_id, entrant_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (event_id, session_id)
+DO UPDATE SET entrant_id = EXCLUDED.entrant_id;
+
+-- Entrants in an event matching either contact detail, oldest first
+-- Two rows means the email belongs to one person and the mobile to another
+-- Entrants from before encryption match on plaintext until the backfill indexes them
+
+-- name: ListEntrantsByContact :many
+SELECT * FROM entrants
+WHERE event_id = sqlc.arg(event_id)
+    AND (email_index = sqlc.arg(email_index)
+        OR mobile_index = sqlc.arg(mobile_index)
+        OR email_normalized = normalize_email(sqlc.arg(email))
+        OR mobile = sqlc.arg(mobile))
+ORDER BY created_at ASC;
+
+-- name: ListUnindexedEntrants :many
+-- Entrants from before encryption, still holding plaintext contact details
+SELECT * FROM entrants
+WHERE email_index IS NULL
+ORDER BY entrant_id
+LIMIT $1;
+
+-- name: UpdateEntrantContact :one
+-- Sets the blind indexes and drops any plaintext left from before encryption
+UPDATE entrants
+SET email_index = sqlc.arg(email_index), mobile_index = sqlc.arg(mobile_index),
+    email_normalized = NULL, mobile = NULL
+WHERE entrant_id = sqlc.arg(entrant_id)
+RETURNING *;
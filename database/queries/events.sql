@@ -1,28 +1,126 @@
 -- name: GetEventBySlug :one
-SELECT e.event_id, e.description, e.created_at
+SELECT e.event_id, e.description, e.created_at, e.on_air_question_id
 FROM events e
 JOIN slugs s ON s.event_id = e.event_id
 WHERE s.slug = $1;
 
 -- name: GetEventByID :one
-SELECT event_id, description, created_at
+SELECT event_id, description, created_at, on_air_question_id
 FROM events
 WHERE event_id = $1;
 
 -- name: GetQuestionByID :one
-SELECT question_id, event_id, big_text, small_text, image_filename, choice_a, choice_b
+SELECT question_id, event_id, big_text, small_text, image_filename, choice_a, choice_b, result, result_set_at, opens_at, closes_at
 FROM questions
 WHERE question_id = $1;
 
 -- name: ListQuestionsByEventID :many
-SELECT question_id, event_id, big_text, small_text, image_filename, choice_a, choice_b
+SELECT question_id, event_id, big_text, small_text, image_filename, choice_a, choice_b, result, result_set_at, opens_at, closes_at
 FROM questions
 WHERE event_id = $1
 ORDER BY question_id ASC;
 
 -- name: GetQuestionByEventAndIndex :one
-SELECT question_id, event_id, big_text, small_text, image_filename, choice_a, choice_b
+SELECT question_id, event_id, big_text, small_text, image_filename, choice_a, choice_b, result, result_set_at, opens_at, closes_at
 FROM questions
 WHERE event_id = sqlc.arg(event_id)
 ORDER BY question_id ASC
 LIMIT 1 OFFSET sqlc.arg(question_index) - 1;
+
+-- Admin: Events
+
+-- name: ListEvents :many
+SELECT event_id, description, created_at, on_air_question_id
+FROM events
+ORDER BY created_at DESC;
+
+-- name: CreateEvent :one
+INSERT INTO events (event_id, description)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: UpdateEvent :one
+UPDATE events
+SET description = $2
+WHERE event_id = $1
+RETURNING *;
+
+-- name: SetEventOnAirQuestion :one
+-- Sets (or clears with NULL) the on-air question, which must belong to the event
+UPDATE events
+SET on_air_question_id = sqlc.narg(question_id)
+WHERE event_id = sqlc.arg(event_id)
+    AND (
+        sqlc.narg(question_id)::text IS NULL
+        OR EXISTS (
+            SELECT 1 FROM questions
+            WHERE question_id = sqlc.narg(question_id) AND event_id = sqlc.arg(event_id)
+        )
+    )
+RETURNING *;
+
+-- name: DeleteEvent :execrows
+DELETE FROM events
+WHERE event_id = $1;
+
+-- Admin: Slugs
+
+-- name: ListSlugsByEventID :many
+SELECT slug, event_id, created_at
+FROM slugs
+WHERE event_id = $1
+ORDER BY slug ASC;
+
+-- name: CreateSlug :one
+INSERT INTO slugs (slug, event_id)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: DeleteSlug :one
+DELETE FROM slugs
+WHERE slug = $1
+RETURNING *;
+
+-- Admin: Questions
+
+-- name: CreateQuestion :one
+INSERT INTO questions (question_id, event_id, big_text, small_text, image_filename, choice_a, choice_b)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: UpdateQuestion :one
+UPDATE questions
+SET big_text = $2,
+    small_text = $3,
+    image_filename = $4,
+    choice_a = $5,
+    choice_b = $6
+WHERE question_id = $1
+RETURNING *;
+
+-- name: DeleteQuestion :one
+DELETE FROM questions
+WHERE question_id = $1
+RETURNING *;
+
+-- name: SetQuestionResult :one
+UPDATE questions
+SET result = $2,
+    result_set_at = CASE WHEN $2::text IS NULL THEN NULL ELSE NOW() END
+WHERE question_id = $1
+RETURNING *;
+
+-- name: SetQuestionWindow :one
+UPDATE questions
+SET opens_at = $2,
+    closes_at = $3
+WHERE question_id = $1
+RETURNING *;
+
+-- name: LockQuestion :one
+-- Closes voting immediately, pulling opens_at back if it was scheduled later
+UPDATE questions
+SET closes_at = NOW(),
+    opens_at = LEAST(opens_at, NOW())
+WHERE question_id = $1
+RETURNING *;
@@ -0,0 +1,8 @@
+-- name: ConsumeFormNonce :execrows
+-- Returns 0 rows affected when the nonce was already used
+INSERT INTO form_nonces (nonce, expires_at)
+VALUES ($1, $2)
+ON CONFLICT (nonce) DO NOTHING;
+
+-- name: DeleteExpiredFormNonces :execrows
+DELETE FROM form_nonces WHERE expires_at < NOW();
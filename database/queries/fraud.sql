@@ -0,0 +1,89 @@
+-- name: UpsertResponseSignal :exec
+-- since_previous_ms is the gap to the session's latest vote on another question
+INSERT INTO response_signals (question_id, session_id, ip_hash, user_agent, time_on_page_ms, since_previous_ms)
+VALUES (
+    sqlc.arg(question_id), sqlc.arg(session_id), sqlc.arg(ip_hash), sqlc.arg(user_agent), sqlc.narg(time_on_page_ms),
+    (SELECT LEAST(EXTRACT(EPOCH FROM NOW() - MAX(created_at)) * 1000, 2147483647)::integer
+     FROM response_signals
+     WHERE session_id = sqlc.arg(session_id) AND question_id <> sqlc.arg(question_id))
+)
+ON CONFLICT (question_id, session_id)
+DO UPDATE SET
+    ip_hash = EXCLUDED.ip_hash,
+    user_agent = EXCLUDED.user_agent,
+    time_on_page_ms = EXCLUDED.time_on_page_ms,
+    since_previous_ms = EXCLUDED.since_previous_ms,
+    created_at = NOW();
+
+-- name: GetSessionSignalStats :one
+SELECT
+    COUNT(*)::bigint as responses,
+    COUNT(*) FILTER (WHERE time_on_page_ms IS NULL)::bigint as no_page_view,
+    COUNT(*) FILTER (WHERE time_on_page_ms < sqlc.arg(fast_ms)::integer)::bigint as fast_answers,
+    COUNT(*) FILTER (WHERE since_previous_ms < sqlc.arg(rapid_ms)::integer)::bigint as rapid_answers,
+    COUNT(DISTINCT user_agent)::bigint as user_agents,
+    COUNT(DISTINCT ip_hash)::bigint as ip_hashes
+FROM response_signals
+WHERE session_id = sqlc.arg(session_id);
+
+-- name: CountFingerprintSessions :one
+SELECT COUNT(DISTINCT session_id)::bigint
+FROM response_signals
+WHERE ip_hash = $1 AND user_agent = $2 AND created_at > NOW() - INTERVAL '1 hour';
+
+-- name: UpsertSessionRisk :exec
+INSERT INTO session_risk (session_id, score, reasons, flagged, updated_at)
+VALUES ($1, $2, $3, $4, NOW())
+ON CONFLICT (session_id)
+DO UPDATE SET
+    score = EXCLUDED.score,
+    reasons = EXCLUDED.reasons,
+    flagged = EXCLUDED.flagged,
+    updated_at = NOW();
+
+-- name: ListFlaggedVotesByEvent :many
+-- ListFlaggedVotesBySlug for every question in an event at once
+SELECT
+    r.question_id,
+    r.slug,
+    COUNT(*) FILTER (WHERE r.choice = 'a')::bigint as votes_a,
+    COUNT(*) FILTER (WHERE r.choice = 'b')::bigint as votes_b
+FROM responses r
+JOIN questions q ON q.question_id = r.question_id
+JOIN session_risk sr ON sr.session_id = r.session_id AND sr.flagged
+WHERE q.event_id = $1
+GROUP BY r.question_id, r.slug
+ORDER BY r.question_id, r.slug;
+
+-- name: ListFlaggedVotesBySlug :many
+-- Votes cast by flagged sessions, subtracted from vote_tallies when excluding them
+SELECT
+    r.slug,
+    COUNT(*) FILTER (WHERE r.choice = 'a')::bigint as votes_a,
+    COUNT(*) FILTER (WHERE r.choice = 'b')::bigint as votes_b
+FROM responses r
+JOIN session_risk sr ON sr.session_id = r.session_id AND sr.flagged
+WHERE r.question_id = $1
+GROUP BY r.slug
+ORDER BY r.slug;
+
+-- name: ListFlaggedClusters :many
+-- Sessions in an event grouped by IP hash and user agent, where at least one is flagged
+SELECT
+    rs.ip_hash,
+    rs.user_agent,
+    COUNT(DISTINCT rs.session_id)::bigint as sessions,
+    COUNT(DISTINCT rs.session_id) FILTER (WHERE sr.flagged)::bigint as flagged_sessions,
+    COUNT(*)::bigint as votes,
+    COALESCE(MAX(sr.score), 0)::integer as max_score,
+    MIN(rs.created_at)::timestamp as first_seen,
+    MAX(rs.created_at)::timestamp as last_seen,
+    (array_agg(DISTINCT rs.session_id ORDER BY rs.session_id) FILTER (WHERE sr.flagged))[1:20]::text[] as sample_session_ids
+FROM response_signals rs
+JOIN questions q ON q.question_id = rs.question_id
+LEFT JOIN session_risk sr ON sr.session_id = rs.session_id
+WHERE q.event_id = $1
+GROUP BY rs.ip_hash, rs.user_agent
+HAVING COUNT(DISTINCT rs.session_id) FILTER (WHERE sr.flagged) > 0
+ORDER BY flagged_sessions DESC, sessions DESC, rs.ip_hash
+LIMIT 100;
@@ -0,0 +1,69 @@
+-- Data subject access and erasure
+-- A subject is found by email or mobile on their sessions, or through an
+-- entrant record linking sessions that used other details
+
+-- name: ListSubjectSessions :many
+SELECT s.* FROM sessions s
+WHERE (sqlc.arg(email_index)::text <> '' AND s.email_index = sqlc.arg(email_index)::text)
+    OR (sqlc.arg(mobile_index)::text <> '' AND s.mobile_index = sqlc.arg(mobile_index)::text)
+    -- Rows from before encryption match on plaintext until the backfill indexes them
+    OR (sqlc.arg(email)::text <> '' AND s.email_index IS NULL AND normalize_email(s.email) = normalize_email(sqlc.arg(email)::text))
+    OR (sqlc.arg(mobile)::text <> '' AND s.mobile_index IS NULL AND s.mobile = sqlc.arg(mobile)::text)
+    OR s.session_id IN (
+        SELECT es.session_id
+        FROM entrant_sessions es
+        JOIN entrants e ON e.entrant_id = es.entrant_id
+        WHERE (sqlc.arg(email_index)::text <> '' AND e.email_index = sqlc.arg(email_index)::text)
+            OR (sqlc.arg(mobile_index)::text <> '' AND e.mobile_index = sqlc.arg(mobile_index)::text)
+            OR (sqlc.arg(email)::text <> '' AND e.email_normalized = normalize_email(sqlc.arg(email)::text))
+            OR (sqlc.arg(mobile)::text <> '' AND e.mobile = sqlc.arg(mobile)::text)
+    )
+ORDER BY s.session_id;
+
+-- name: ListSubjectResponses :many
+SELECT r.session_id, q.event_id, r.question_id, q.big_text, r.slug, r.choice, r.created_at, r.updated_at
+FROM responses r
+JOIN questions q ON q.question_id = r.question_id
+WHERE r.session_id = ANY(sqlc.arg(session_ids)::text[])
+ORDER BY r.session_id, r.created_at;
+
+-- name: ListEntrantsBySessions :many
+SELECT DISTINCT e.* FROM entrants e
+JOIN entrant_sessions es ON es.entrant_id = e.entrant_id
+WHERE es.session_id = ANY(sqlc.arg(session_ids)::text[])
+ORDER BY e.entrant_id;
+
+-- name: ListResponseSignalsBySessions :many
+SELECT * FROM response_signals
+WHERE session_id = ANY(sqlc.arg(session_ids)::text[])
+ORDER BY session_id, created_at;
+
+-- name: ListSessionRiskBySessions :many
+SELECT * FROM session_risk
+WHERE session_id = ANY(sqlc.arg(session_ids)::text[])
+ORDER BY session_id;
+
+-- name: ListDrawsBySessions :many
+-- Draws that picked one of the sessions as winner or alternate
+SELECT * FROM draws
+WHERE winner_session_id = ANY(sqlc.arg(session_ids)::text[])
+    OR alternates && sqlc.arg(session_ids)::text[]
+ORDER BY created_at;
+
+-- name: AnonymiseSessions :execrows
+UPDATE sessions SET name = NULL, email = NULL, mobile = NULL, email_index = NULL, mobile_index = NULL
+WHERE session_id = ANY(sqlc.arg(session_ids)::text[]);
+
+-- name: DeleteEntrants :execrows
+DELETE FROM entrants WHERE entrant_id = ANY(sqlc.arg(entrant_ids)::text[]);
+
+-- name: DeleteResponseSignalsBySessions :execrows
+DELETE FROM response_signals WHERE session_id = ANY(sqlc.arg(session_ids)::text[]);
+
+-- name: DeleteSessionRiskBySessions :execrows
+DELETE FROM session_risk WHERE session_id = ANY(sqlc.arg(session_ids)::text[]);
+
+-- name: DeleteSessions :execrows
+-- Cascades to responses (the tally trigger decrements vote_tallies per row),
+-- entrant links, fraud signals and risk scores
+DELETE FROM sessions WHERE session_id = ANY(sqlc.arg(session_ids)::text[]);
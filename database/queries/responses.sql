@@ -1,6 +1,13 @@
 -- name: UpsertResponse :one
+-- Only writes while the question's voting window is open
+-- Returns no rows (sql.ErrNoRows) once the question is locked
 INSERT INTO responses (question_id, session_id, slug, choice, created_at, updated_at)
-VALUES ($1, $2, $3, $4, NOW(), NOW())
+SELECT $1, $2, $3, $4, NOW(), NOW()
+FROM questions
+WHERE question_id = $1
+    AND result IS NULL
+    AND (opens_at IS NULL OR opens_at <= NOW())
+    AND (closes_at IS NULL OR closes_at > NOW())
 ON CONFLICT (question_id, session_id)
 DO UPDATE SET
     choice = EXCLUDED.choice,
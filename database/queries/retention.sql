@@ -0,0 +1,102 @@
+-- PII retention
+-- An event closes at its last question window or its last vote, whichever is later
+
+-- name: TryRetentionLock :one
+-- Held until the transaction ends, so only one instance purges at a time
+SELECT pg_try_advisory_xact_lock(hashtext('pick6_retention'));
+
+-- name: GetEventRetention :one
+SELECT er.retention_days, er.purged_at, closed.closed_at AT TIME ZONE 'UTC' AS closed_at
+FROM events e
+LEFT JOIN event_retention er ON er.event_id = e.event_id
+CROSS JOIN LATERAL (
+    SELECT GREATEST(
+        (SELECT MAX(q.closes_at) FROM questions q WHERE q.event_id = e.event_id),
+        (SELECT MAX(r.created_at) FROM responses r
+            JOIN questions q ON q.question_id = r.question_id
+            WHERE q.event_id = e.event_id)
+    ) AS closed_at
+) closed
+WHERE e.event_id = $1;
+
+-- name: UpsertEventRetention :one
+INSERT INTO event_retention (event_id, retention_days)
+VALUES (sqlc.arg(event_id), sqlc.narg(retention_days))
+ON CONFLICT (event_id)
+DO UPDATE SET retention_days = EXCLUDED.retention_days, updated_at = NOW()
+RETURNING *;
+
+-- name: ListExpiredEvents :many
+-- Events past their retention whose PII hasn't been purged, oldest close first
+-- A default of 0 leaves events without their own policy alone
+SELECT e.event_id,
+    closed.closed_at AT TIME ZONE 'UTC' AS closed_at,
+    COALESCE(er.retention_days, sqlc.arg(default_days)::int)::int AS retention_days
+FROM events e
+LEFT JOIN event_retention er ON er.event_id = e.event_id
+CROSS JOIN LATERAL (
+    SELECT GREATEST(
+        (SELECT MAX(q.closes_at) FROM questions q WHERE q.event_id = e.event_id),
+        (SELECT MAX(r.created_at) FROM responses r
+            JOIN questions q ON q.question_id = r.question_id
+            WHERE q.event_id = e.event_id)
+    ) AS closed_at
+) closed
+WHERE er.purged_at IS NULL
+    AND closed.closed_at IS NOT NULL
+    AND COALESCE(er.retention_days, NULLIF(sqlc.arg(default_days)::int, 0)) IS NOT NULL
+    AND closed.closed_at + make_interval(days => COALESCE(er.retention_days, sqlc.arg(default_days)::int)) < NOW()
+ORDER BY closed.closed_at ASC;
+
+-- name: PurgeEventSessions :execrows
+-- Clears contact details from the event's sessions, skipping any still held for
+-- another event that hasn't been purged yet (it is cleared when that one expires)
+UPDATE sessions s
+SET name = NULL, email = NULL, mobile = NULL, email_index = NULL, mobile_index = NULL
+WHERE (s.name IS NOT NULL OR s.email IS NOT NULL OR s.mobile IS NOT NULL)
+    AND s.session_id IN (
+        SELECT r.session_id FROM responses r
+        JOIN questions q ON q.question_id = r.question_id
+        WHERE q.event_id = sqlc.arg(event_id)
+        UNION
+        SELECT es.session_id FROM entrant_sessions es
+        WHERE es.event_id = sqlc.arg(event_id)
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM responses r
+        JOIN questions q ON q.question_id = r.question_id
+        LEFT JOIN event_retention er ON er.event_id = q.event_id
+        WHERE r.session_id = s.session_id
+            AND q.event_id <> sqlc.arg(event_id)
+            AND er.purged_at IS NULL
+    );
+
+-- name: DeleteEntrantsByEvent :execrows
+DELETE FROM entrants WHERE event_id = $1;
+
+-- name: DeleteConsentsByEvent :execrows
+DELETE FROM consents WHERE event_id = $1;
+
+-- name: DeleteResponseSignalsByEvent :execrows
+DELETE FROM response_signals rs
+USING questions q
+WHERE q.question_id = rs.question_id AND q.event_id = $1;
+
+-- name: MarkEventPurged :exec
+INSERT INTO event_retention (event_id, purged_at)
+VALUES ($1, NOW())
+ON CONFLICT (event_id)
+DO UPDATE SET purged_at = NOW(), updated_at = NOW();
+
+-- name: CreateRetentionRun :exec
+INSERT INTO retention_runs (
+    run_id, trigger, dry_run, default_days, event_ids,
+    sessions_purged, entrants_deleted, consents_deleted, signals_deleted,
+    error, started_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);
+
+-- name: ListRetentionRuns :many
+SELECT * FROM retention_runs
+ORDER BY started_at DESC
+LIMIT $1;
@@ -0,0 +1,56 @@
+-- Prediction scoring
+-- A pick is correct when it matches a decisive result ('a' or 'b')
+-- Draws, no contests and pending results score nothing for anyone
+
+-- name: GetEventResultSummary :one
+SELECT
+    COUNT(*) as total_questions,
+    COUNT(result) as questions_resulted,
+    COUNT(*) FILTER (WHERE result IN ('a', 'b')) as questions_scorable
+FROM questions
+WHERE event_id = $1;
+
+-- name: ListSessionScoresByEvent :many
+SELECT
+    r.session_id,
+    COUNT(*) as answered,
+    COUNT(*) FILTER (WHERE q.result IN ('a', 'b')) as scored,
+    COUNT(*) FILTER (WHERE r.choice = q.result) as correct,
+    MAX(r.updated_at)::timestamp as last_answered_at
+FROM responses r
+JOIN questions q ON q.question_id = r.question_id
+WHERE q.event_id = $1
+GROUP BY r.session_id
+ORDER BY correct DESC, last_answered_at ASC, r.session_id ASC;
+
+-- name: GetEventScoreDistribution :many
+SELECT
+    s.correct,
+    COUNT(*) as sessions
+FROM (
+    SELECT
+        r.session_id,
+        COUNT(*) FILTER (WHERE r.choice = q.result) as correct
+    FROM responses r
+    JOIN questions q ON q.question_id = r.question_id
+    WHERE q.event_id = $1
+    GROUP BY r.session_id
+) s
+GROUP BY s.correct
+ORDER BY s.correct DESC;
+
+-- name: ListLeaderboardByEvent :many
+SELECT
+    s.name,
+    COUNT(*) FILTER (WHERE r.choice = q.result) as correct,
+    COUNT(*) as answered,
+    MAX(r.updated_at)::timestamp as last_answered_at
+FROM responses r
+JOIN questions q ON q.question_id = r.question_id
+JOIN sessions s ON s.session_id = r.session_id
+WHERE q.event_id = sqlc.arg(event_id)
+    AND s.name IS NOT NULL
+    AND s.name <> ''
+GROUP BY s.session_id, s.name
+ORDER BY correct DESC, last_answered_at ASC, s.session_id ASC
+LIMIT sqlc.arg(max_entries);
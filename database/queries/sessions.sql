@@ -2,11 +2,22 @@
 SELECT * FROM sessions WHERE session_id = $1 LIMIT 1;
 
 -- name: UpsertSession :one
-INSERT INTO sessions (session_id, name, email, mobile)
-VALUES ($1, $2, $3, $4)
+-- name, email and mobile are sealed by pii.String, the indexes are computed by the caller
+INSERT INTO sessions (session_id, name, email, mobile, email_index, mobile_index)
+VALUES ($1, $2, $3, $4, $5, $6)
 ON CONFLICT (session_id) 
 DO UPDATE SET 
     name = EXCLUDED.name,
     email = EXCLUDED.email,
-    mobile = EXCLUDED.mobile
+    mobile = EXCLUDED.mobile,
+    email_index = EXCLUDED.email_index,
+    mobile_index = EXCLUDED.mobile_index
 RETURNING *;
+
+-- name: ListSessionsWithContact :many
+-- Keyset pages of sessions holding contact details, for re-sealing and indexing
+SELECT * FROM sessions
+WHERE session_id > sqlc.arg(after)
+    AND (name IS NOT NULL OR email IS NOT NULL OR mobile IS NOT NULL)
+ORDER BY session_id
+LIMIT sqlc.arg(max_rows);
@@ -0,0 +1,51 @@
+package database
+
+import "time"
+
+// Possible values of questions.result
+const (
+	ResultA         = "a"
+	ResultB         = "b"
+	ResultDraw      = "draw"
+	ResultNoContest = "no_contest"
+)
+
+// IsValidResult reports whether s is an allowed value for questions.result
+func IsValidResult(s string) bool {
+	switch s {
+	case ResultA, ResultB, ResultDraw, ResultNoContest:
+		return true
+	}
+	return false
+}
+
+// HasResult reports whether an official result has been recorded
+func (q Question) HasResult() bool {
+	return q.Result.Valid
+}
+
+// IsDecisive reports whether the result has a winner that picks can be scored against
+func (q Question) IsDecisive() bool {
+	return q.Result.Valid && (q.Result.String == ResultA || q.Result.String == ResultB)
+}
+
+// IsCorrect reports whether a pick matches the question's decisive result
+func (q Question) IsCorrect(choice string) bool {
+	return q.IsDecisive() && q.Result.String == choice
+}
+
+// IsOpen reports whether votes are accepted for the question at the given time
+// Mirrors the voting window check in UpsertResponse; the window columns are
+// TIMESTAMPTZ, so this and NOW() agree whatever the time zones are
+func (q Question) IsOpen(now time.Time) bool {
+	if q.Result.Valid {
+		return false
+	}
+	if q.OpensAt.Valid && now.Before(q.OpensAt.Time) {
+		return false
+	}
+	if q.ClosesAt.Valid && !now.Before(q.ClosesAt.Time) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestQuestionResult(t *testing.T) {
+	tests := []struct {
+		result   sql.NullString
+		has      bool
+		decisive bool
+		correct  string // The pick IsCorrect accepts, empty for none
+	}{
+		{sql.NullString{}, false, false, ""},
+		{sql.NullString{String: ResultA, Valid: true}, true, true, "a"},
+		{sql.NullString{String: ResultB, Valid: true}, true, true, "b"},
+		{sql.NullString{String: ResultDraw, Valid: true}, true, false, ""},
+		{sql.NullString{String: ResultNoContest, Valid: true}, true, false, ""},
+	}
+	for _, tt := range tests {
+		q := Question{Result: tt.result}
+		if got := q.HasResult(); got != tt.has {
+			t.Errorf("%v: HasResult = %v, want %v", tt.result, got, tt.has)
+		}
+		if got := q.IsDecisive(); got != tt.decisive {
+			t.Errorf("%v: IsDecisive = %v, want %v", tt.result, got, tt.decisive)
+		}
+		for _, pick := range []string{"a", "b"} {
+			if got := q.IsCorrect(pick); got != (pick == tt.correct) {
+				t.Errorf("%v: IsCorrect(%q) = %v", tt.result, pick, got)
+			}
+		}
+	}
+}
+
+func TestIsValidResult(t *testing.T) {
+	for _, s := range []string{ResultA, ResultB, ResultDraw, ResultNoContest} {
+		if !IsValidResult(s) {
+			t.Errorf("IsValidResult(%q) = false", s)
+		}
+	}
+	for _, s := range []string{"", "A", "tie", "no-contest"} {
+		if IsValidResult(s) {
+			t.Errorf("IsValidResult(%q) = true", s)
+		}
+	}
+}
@@ -78,7 +78,12 @@ func (q *Queries) GetResponsesBySessionAndEvent(ctx context.Context, arg GetResp
 
 const upsertResponse = `-- name: UpsertResponse :one
 INSERT INTO responses (question_id, session_id, slug, choice, created_at, updated_at)
-VALUES ($1, $2, $3, $4, NOW(), NOW())
+SELECT $1, $2, $3, $4, NOW(), NOW()
+FROM questions
+WHERE question_id = $1
+    AND result IS NULL
+    AND (opens_at IS NULL OR opens_at <= NOW())
+    AND (closes_at IS NULL OR closes_at > NOW())
 ON CONFLICT (question_id, session_id)
 DO UPDATE SET
     choice = EXCLUDED.choice,
@@ -94,6 +99,8 @@ type UpsertResponseParams struct {
 	Choice     string `json:"choice"`
 }
 
+// Only writes while the question's voting window is open
+// Returns no rows (sql.ErrNoRows) once the question is locked
 func (q *Queries) UpsertResponse(ctx context.Context, arg UpsertResponseParams) (Response, error) {
 	row := q.db.QueryRowContext(ctx, upsertResponse,
 		arg.QuestionID,
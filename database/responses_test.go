@@ -0,0 +1,79 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mrbennbenn/pick6/database"
+	"github.com/mrbennbenn/pick6/database/dbtest"
+)
+
+// Seeded by the initial migration
+const (
+	seedQuestion = "question_39aJ1eE9ihQ3hH9kmOfKdCSueFP"
+	seedSlug     = "tk03"
+)
+
+func TestUpsertResponseLock(t *testing.T) {
+	db := dbtest.Open(t)
+	q := database.New(db)
+	ctx := context.Background()
+
+	if _, err := db.Exec(`INSERT INTO sessions (session_id) VALUES ('session_lock')`); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	at := func(d time.Duration) sql.NullTime { return sql.NullTime{Time: time.Now().Add(d), Valid: true} }
+	result := func(r string) sql.NullString { return sql.NullString{String: r, Valid: r != ""} }
+
+	tests := []struct {
+		name     string
+		opensAt  sql.NullTime
+		closesAt sql.NullTime
+		result   sql.NullString
+		open     bool
+	}{
+		{"no window", sql.NullTime{}, sql.NullTime{}, sql.NullString{}, true},
+		{"inside window", at(-time.Hour), at(time.Hour), sql.NullString{}, true},
+		{"after closes_at", sql.NullTime{}, at(-time.Minute), sql.NullString{}, false},
+		{"before opens_at", at(time.Hour), sql.NullTime{}, sql.NullString{}, false},
+		{"result set", sql.NullTime{}, sql.NullTime{}, result(database.ResultA), false},
+		{"result cleared", sql.NullTime{}, sql.NullTime{}, sql.NullString{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			question, err := q.SetQuestionWindow(ctx, database.SetQuestionWindowParams{
+				QuestionID: seedQuestion,
+				OpensAt:    tt.opensAt,
+				ClosesAt:   tt.closesAt,
+			})
+			if err != nil {
+				t.Fatalf("set window: %v", err)
+			}
+			if question, err = q.SetQuestionResult(ctx, database.SetQuestionResultParams{
+				QuestionID: seedQuestion,
+				Result:     tt.result,
+			}); err != nil {
+				t.Fatalf("set result: %v", err)
+			}
+			if got := question.IsOpen(time.Now()); got != tt.open {
+				t.Errorf("IsOpen = %v, want %v", got, tt.open)
+			}
+
+			_, err = q.UpsertResponse(ctx, database.UpsertResponseParams{
+				QuestionID: seedQuestion,
+				SessionID:  "session_lock",
+				Slug:       seedSlug,
+				Choice:     "b",
+			})
+			switch {
+			case tt.open && err != nil:
+				t.Fatalf("upsert while open: %v", err)
+			case !tt.open && !errors.Is(err, sql.ErrNoRows):
+				t.Fatalf("upsert while locked = %v, want sql.ErrNoRows", err)
+			}
+		})
+	}
+}
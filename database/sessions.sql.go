@@ -8,10 +8,12 @@ package database
 import (
 	"context"
 	"database/sql"
+
+	"github.com/mrbennbenn/pick6/database/pii"
 )
 
 const getSession = `-- name: GetSession :one
-SELECT session_id, name, email, mobile FROM sessions WHERE session_id = $1 LIMIT 1
+SELECT session_id, name, email, mobile, email_index, mobile_index FROM sessions WHERE session_id = $1 LIMIT 1
 `
 
 func (q *Queries) GetSession(ctx context.Context, sessionID string) (Session, error) {
@@ -22,34 +24,87 @@ func (q *Queries) GetSession(ctx context.Context, sessionID string) (Session, er
 		&i.Name,
 		&i.Email,
 		&i.Mobile,
+		&i.EmailIndex,
+		&i.MobileIndex,
 	)
 	return i, err
 }
 
+const listSessionsWithContact = `-- name: ListSessionsWithContact :many
+SELECT session_id, name, email, mobile, email_index, mobile_index FROM sessions
+WHERE session_id > $1
+    AND (name IS NOT NULL OR email IS NOT NULL OR mobile IS NOT NULL)
+ORDER BY session_id
+LIMIT $2
+`
+
+type ListSessionsWithContactParams struct {
+	After   string `json:"after"`
+	MaxRows int32  `json:"max_rows"`
+}
+
+// Keyset pages of sessions holding contact details, for re-sealing and indexing
+func (q *Queries) ListSessionsWithContact(ctx context.Context, arg ListSessionsWithContactParams) ([]Session, error) {
+	rows, err := q.db.QueryContext(ctx, listSessionsWithContact, arg.After, arg.MaxRows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Session{}
+	for rows.Next() {
+		var i Session
+		if err := rows.Scan(
+			&i.SessionID,
+			&i.Name,
+			&i.Email,
+			&i.Mobile,
+			&i.EmailIndex,
+			&i.MobileIndex,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const upsertSession = `-- name: UpsertSession :one
-INSERT INTO sessions (session_id, name, email, mobile)
-VALUES ($1, $2, $3, $4)
+INSERT INTO sessions (session_id, name, email, mobile, email_index, mobile_index)
+VALUES ($1, $2, $3, $4, $5, $6)
 ON CONFLICT (session_id) 
 DO UPDATE SET 
     name = EXCLUDED.name,
     email = EXCLUDED.email,
-    mobile = EXCLUDED.mobile
-RETURNING session_id, name, email, mobile
+    mobile = EXCLUDED.mobile,
+    email_index = EXCLUDED.email_index,
+    mobile_index = EXCLUDED.mobile_index
+RETURNING session_id, name, email, mobile, email_index, mobile_index
 `
 
 type UpsertSessionParams struct {
-	SessionID string         `json:"session_id"`
-	Name      sql.NullString `json:"name"`
-	Email     sql.NullString `json:"email"`
-	Mobile    sql.NullString `json:"mobile"`
+	SessionID   string         `json:"session_id"`
+	Name        pii.String     `json:"name"`
+	Email       pii.String     `json:"email"`
+	Mobile      pii.String     `json:"mobile"`
+	EmailIndex  sql.NullString `json:"email_index"`
+	MobileIndex sql.NullString `json:"mobile_index"`
 }
 
+// name, email and mobile are sealed by pii.String, the indexes are computed by the caller
 func (q *Queries) UpsertSession(ctx context.Context, arg UpsertSessionParams) (Session, error) {
 	row := q.db.QueryRowContext(ctx, upsertSession,
 		arg.SessionID,
 		arg.Name,
 		arg.Email,
 		arg.Mobile,
+		arg.EmailIndex,
+		arg.MobileIndex,
 	)
 	var i Session
 	err := row.Scan(
@@ -57,6 +112,8 @@ func (q *Queries) UpsertSession(ctx context.Context, arg UpsertSessionParams) (S
 		&i.Name,
 		&i.Email,
 		&i.Mobile,
+		&i.EmailIndex,
+		&i.MobileIndex,
 	)
 	return i, err
 }
@@ -0,0 +1,11 @@
+package database
+
+import "regexp"
+
+// slugPattern mirrors the CHECK constraint on slugs.slug
+var slugPattern = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// ValidSlug reports whether s is allowed in slugs.slug
+func ValidSlug(s string) bool {
+	return slugPattern.MatchString(s)
+}
@@ -0,0 +1,16 @@
+package database
+
+import "testing"
+
+func TestValidSlug(t *testing.T) {
+	for _, s := range []string{"tk03", "tk03-stadium", "a", "2026-final"} {
+		if !ValidSlug(s) {
+			t.Errorf("ValidSlug(%q) = false", s)
+		}
+	}
+	for _, s := range []string{"", "TK03", "tk 03", "tk_03", "wp-admin/", "../tk03", "tk03\n"} {
+		if ValidSlug(s) {
+			t.Errorf("ValidSlug(%q) = true", s)
+		}
+	}
+}
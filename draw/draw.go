@@ -0,0 +1,103 @@
+// Package draw picks prize draw winners reproducibly
+//
+// A draw is fully determined by its seed and the sorted list of eligible
+// session IDs, so anyone holding the stored seed and digest can re-run it
+// and confirm the same winner and alternates come out
+package draw
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"math/bits"
+	mrand "math/rand/v2"
+	"slices"
+	"strings"
+)
+
+// Algorithm identifies this selection procedure and is stored with every draw
+// Bump it if Select ever changes, so old draws can still be verified against
+// the procedure they were made with
+const Algorithm = "pcg-shuffle-v1"
+
+// pcgStream is a fixed PCG increment, part of the algorithm not the seed
+const pcgStream = 0x7069636b36647261 // "pick6dra"
+
+// Result is the outcome of a draw
+type Result struct {
+	Winner     string   // Empty when there were no entrants
+	Alternates []string // Next in line, in order, if the winner is disqualified
+}
+
+// NewSeed returns a seed from crypto/rand
+// The seed is recorded with the draw, so it only needs to be unpredictable
+// until the draw is made
+func NewSeed() (int64, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(b[:]) &^ (1 << 63)), nil
+}
+
+// Digest returns a hex SHA-256 of the sorted entrant IDs
+// Stored with each draw to prove which entrants were in the pool
+func Digest(entrants []string) string {
+	sorted := slices.Clone(entrants)
+	slices.Sort(sorted)
+	sum := sha256.Sum256([]byte(strings.Join(sorted, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Select shuffles the entrants with a PCG seeded from seed and takes the
+// first as winner and the next alternates as alternates
+// Entrants are sorted first, so the input order does not affect the result
+func Select(entrants []string, seed int64, alternates int) Result {
+	pool := slices.Clone(entrants)
+	slices.Sort(pool)
+
+	if len(pool) == 0 {
+		return Result{Alternates: []string{}}
+	}
+
+	// Fisher-Yates, implemented here rather than with rand.Shuffle so the
+	// sequence can't change under us with a Go upgrade
+	rng := mrand.NewPCG(uint64(seed), pcgStream)
+	for i := len(pool) - 1; i > 0; i-- {
+		j := boundedUint64(rng, uint64(i+1))
+		pool[i], pool[j] = pool[j], pool[i]
+	}
+
+	end := min(1+alternates, len(pool))
+	return Result{
+		Winner:     pool[0],
+		Alternates: pool[1:end],
+	}
+}
+
+// boundedUint64 returns a uniform value in [0, n) using Lemire's method
+// with rejection, so there is no modulo bias
+func boundedUint64(rng *mrand.PCG, n uint64) uint64 {
+	hi, lo := bits.Mul64(rng.Uint64(), n)
+	if lo < n {
+		threshold := -n % n
+		for lo < threshold {
+			hi, lo = bits.Mul64(rng.Uint64(), n)
+		}
+	}
+	return hi
+}
+
+// Exclusions returns the sessions a redraw must skip: everything the previous
+// draw excluded plus its winner, so one hop covers every earlier winner in a chain
+func Exclusions(previous []string, previousWinner string) []string {
+	excluded := slices.Clone(previous)
+	if excluded == nil {
+		excluded = []string{}
+	}
+	if previousWinner != "" && !slices.Contains(excluded, previousWinner) {
+		excluded = append(excluded, previousWinner)
+	}
+	return excluded
+}
@@ -0,0 +1,154 @@
+package draw
+
+import (
+	"slices"
+	"testing"
+)
+
+var entrants = []string{"voter_a", "voter_b", "voter_c", "voter_d", "voter_e"}
+
+// Pinned outcomes for Algorithm: if one of these changes, old draws can no
+// longer be verified and Algorithm must be bumped instead
+func TestSelectGolden(t *testing.T) {
+	tests := []struct {
+		seed       int64
+		winner     string
+		alternates []string
+	}{
+		{0, "voter_b", []string{"voter_a", "voter_c"}},
+		{1, "voter_d", []string{"voter_b", "voter_a"}},
+		{42, "voter_c", []string{"voter_e", "voter_d"}},
+		{9223372036854775807, "voter_a", []string{"voter_c", "voter_d"}},
+	}
+	for _, tt := range tests {
+		got := Select(entrants, tt.seed, 2)
+		if got.Winner != tt.winner || !slices.Equal(got.Alternates, tt.alternates) {
+			t.Errorf("Select(seed=%d) = %s %v, want %s %v", tt.seed, got.Winner, got.Alternates, tt.winner, tt.alternates)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	reversed := slices.Clone(entrants)
+	slices.Reverse(reversed)
+
+	tests := []struct {
+		name       string
+		entrants   []string
+		alternates int
+		wantAlts   int
+	}{
+		{"no entrants", nil, 3, 0},
+		{"one entrant", []string{"voter_a"}, 3, 0},
+		{"fewer than alternates", entrants, 10, 4},
+		{"no alternates", entrants, 0, 0},
+		{"input order ignored", reversed, 2, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Select(tt.entrants, 42, tt.alternates)
+			if len(got.Alternates) != tt.wantAlts {
+				t.Fatalf("got %d alternates, want %d", len(got.Alternates), tt.wantAlts)
+			}
+			if len(tt.entrants) == 0 {
+				if got.Winner != "" {
+					t.Fatalf("winner %q from an empty pool", got.Winner)
+				}
+				return
+			}
+
+			// Same seed, same pool, same result, whatever the order
+			shuffled := slices.Clone(tt.entrants)
+			slices.Reverse(shuffled)
+			again := Select(shuffled, 42, tt.alternates)
+			if got.Winner != again.Winner || !slices.Equal(got.Alternates, again.Alternates) {
+				t.Fatalf("order changed the result: %v vs %v", got, again)
+			}
+
+			picked := append([]string{got.Winner}, got.Alternates...)
+			seen := map[string]bool{}
+			for _, id := range picked {
+				if !slices.Contains(tt.entrants, id) {
+					t.Fatalf("picked %q, not an entrant", id)
+				}
+				if seen[id] {
+					t.Fatalf("picked %q twice", id)
+				}
+				seen[id] = true
+			}
+		})
+	}
+}
+
+func TestSelectDoesNotModifyInput(t *testing.T) {
+	in := slices.Clone(entrants)
+	slices.Reverse(in)
+	want := slices.Clone(in)
+	Select(in, 7, 2)
+	if !slices.Equal(in, want) {
+		t.Fatalf("input changed to %v", in)
+	}
+}
+
+func TestDigest(t *testing.T) {
+	reversed := slices.Clone(entrants)
+	slices.Reverse(reversed)
+
+	tests := []struct {
+		name     string
+		entrants []string
+		want     string
+	}{
+		// sha256 of the sorted IDs joined by newlines
+		{"sorted", entrants, "082c9c05826c7300d02d0ab8f5519113e445dcf6236b4d8ac9c5e845c1419cae"},
+		{"order ignored", reversed, "082c9c05826c7300d02d0ab8f5519113e445dcf6236b4d8ac9c5e845c1419cae"},
+		{"empty", nil, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Digest(tt.entrants); got != tt.want {
+				t.Fatalf("Digest = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExclusions(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous []string
+		winner   string
+		want     []string
+	}{
+		{"first redraw", nil, "voter_a", []string{"voter_a"}},
+		{"chain accumulates", []string{"voter_a"}, "voter_b", []string{"voter_a", "voter_b"}},
+		{"previous draw had no winner", []string{"voter_a"}, "", []string{"voter_a"}},
+		{"winner already excluded", []string{"voter_a"}, "voter_a", []string{"voter_a"}},
+		{"nothing to exclude", nil, "", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Exclusions(tt.previous, tt.winner)
+			if !slices.Equal(got, tt.want) || got == nil {
+				t.Fatalf("Exclusions = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSeed(t *testing.T) {
+	seen := map[int64]bool{}
+	for i := 0; i < 100; i++ {
+		seed, err := NewSeed()
+		if err != nil {
+			t.Fatalf("NewSeed: %v", err)
+		}
+		if seed < 0 {
+			t.Fatalf("NewSeed = %d, want non-negative", seed)
+		}
+		if seen[seed] {
+			t.Fatalf("NewSeed repeated %d", seed)
+		}
+		seen[seed] = true
+	}
+}
@@ -0,0 +1,199 @@
+package fraud
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/mrbennbenn/pick6/database"
+	cache "github.com/patrickmn/go-cache"
+)
+
+// HoneypotField is a form input hidden from people with CSS
+// Browsers leave it empty, naive scripts fill in every field they find
+const HoneypotField = "website"
+
+// TokenField is the form input carrying the signed form token
+const TokenField = "form_token"
+
+// Form token errors, all mean the submission should not be saved
+var (
+	ErrTokenMissing  = errors.New("form token missing")
+	ErrTokenInvalid  = errors.New("form token invalid")
+	ErrTokenExpired  = errors.New("form token expired")
+	ErrTokenTooFast  = errors.New("form submitted too quickly")
+	ErrTokenReplayed = errors.New("form token already used")
+)
+
+// FormTokens issues and checks signed, time-stamped, single-use form tokens
+//
+// A token is "<issued unix ms>.<nonce>.<hmac>", the HMAC covering the session
+// ID and form name as well, so a token only works for the session and form it
+// was rendered for. That makes it a CSRF token too: another site can't read
+// the page to get one. Used nonces are remembered by a NonceStore until the
+// token would have expired anyway
+// A nil *FormTokens accepts every submission
+type FormTokens struct {
+	MaxAge  time.Duration
+	secrets [][]byte
+	used    NonceStore
+}
+
+// NonceStore remembers consumed token nonces until they expire
+type NonceStore interface {
+	// Consume marks a nonce used, returning false if it already was
+	Consume(ctx context.Context, nonce string, expires time.Time) (bool, error)
+}
+
+// NewFormTokens signs with the first secret and verifies with all of them
+// Secrets are required, a per-process key would reject every form rendered by
+// another instance or before a restart
+// used keeps consumed nonces, nil keeps them in this process only (replay
+// protection is then per instance)
+func NewFormTokens(secrets []string, maxAge time.Duration, used NonceStore) (*FormTokens, error) {
+	if len(secrets) == 0 {
+		return nil, errors.New("form tokens need at least one secret")
+	}
+	if used == nil {
+		used = &memoryNonces{cache: cache.New(maxAge, 10*time.Minute)}
+	}
+
+	f := &FormTokens{
+		MaxAge: maxAge,
+		used:   used,
+	}
+	for _, secret := range secrets {
+		f.secrets = append(f.secrets, []byte("pick6-form|"+secret))
+	}
+	return f, nil
+}
+
+// nonceCleanupInterval is how often an instance deletes expired form_nonces rows
+const nonceCleanupInterval = 10 * time.Minute
+
+// DBNonces keeps consumed nonces in Postgres (form_nonces), so a token is
+// single-use across every instance
+// Expired rows are deleted by whichever Consume first finds the cleanup due
+type DBNonces struct {
+	Queries *database.Queries
+	Log     *log.Logger
+
+	cleanedAt atomic.Int64 // Unix seconds of the last cleanup
+}
+
+func (d *DBNonces) Consume(ctx context.Context, nonce string, expires time.Time) (bool, error) {
+	rows, err := d.Queries.ConsumeFormNonce(ctx, database.ConsumeFormNonceParams{
+		Nonce:     nonce,
+		ExpiresAt: expires,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to consume form nonce: %w", err)
+	}
+
+	last := d.cleanedAt.Load()
+	if now := time.Now().Unix(); now-last >= int64(nonceCleanupInterval/time.Second) && d.cleanedAt.CompareAndSwap(last, now) {
+		// The nonce is already consumed, so a failed cleanup mustn't fail the submission
+		if _, err := d.Queries.DeleteExpiredFormNonces(ctx); err != nil && d.Log != nil {
+			d.Log.Printf("Error deleting expired form nonces: %v", err)
+		}
+	}
+	return rows == 1, nil
+}
+
+// memoryNonces keeps consumed nonces in this process only
+type memoryNonces struct {
+	cache *cache.Cache
+}
+
+func (m *memoryNonces) Consume(_ context.Context, nonce string, expires time.Time) (bool, error) {
+	// Add fails if the nonce is already present, which makes check-and-mark atomic
+	return m.cache.Add(nonce, true, time.Until(expires)) == nil, nil
+}
+
+// Issue returns a token for a form rendered now for a session
+func (f *FormTokens) Issue(sessionID, form string) string {
+	if f == nil {
+		return ""
+	}
+
+	nonce := make([]byte, 12)
+	if _, err := rand.Read(nonce); err != nil {
+		panic(err)
+	}
+	payload := strconv.FormatInt(time.Now().UnixMilli(), 10) + "." + base64.RawURLEncoding.EncodeToString(nonce)
+	return payload + "." + f.sign(f.secrets[0], sessionID, form, payload)
+}
+
+// Check verifies a submitted token and, if it's good, marks it used
+// minAge is how long a person needs at least to fill in the form
+// Returns when the form was rendered, for measuring time on page
+// Errors other than the ErrToken* ones mean the nonce store couldn't be reached
+func (f *FormTokens) Check(ctx context.Context, sessionID, form, token string, minAge time.Duration) (time.Time, error) {
+	if f == nil {
+		return time.Time{}, nil
+	}
+	if token == "" {
+		return time.Time{}, ErrTokenMissing
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[1] == "" {
+		return time.Time{}, ErrTokenInvalid
+	}
+	issuedStr, nonce, signature := parts[0], parts[1], parts[2]
+	payload := issuedStr + "." + nonce
+
+	valid := false
+	for _, secret := range f.secrets {
+		if hmac.Equal([]byte(signature), []byte(f.sign(secret, sessionID, form, payload))) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return time.Time{}, ErrTokenInvalid
+	}
+
+	issuedMs, err := strconv.ParseInt(issuedStr, 10, 64)
+	if err != nil {
+		return time.Time{}, ErrTokenInvalid
+	}
+	issuedAt := time.UnixMilli(issuedMs)
+	age := time.Since(issuedAt)
+	if age > f.MaxAge {
+		return issuedAt, ErrTokenExpired
+	}
+	if age < minAge {
+		return issuedAt, ErrTokenTooFast // Not consumed, so a genuine slow retry still works
+	}
+
+	fresh, err := f.used.Consume(ctx, nonce, issuedAt.Add(f.MaxAge))
+	if err != nil {
+		return issuedAt, err
+	}
+	if !fresh {
+		return issuedAt, ErrTokenReplayed
+	}
+	return issuedAt, nil
+}
+
+func (f *FormTokens) sign(secret []byte, sessionID, form, payload string) string {
+	m := hmac.New(sha256.New, secret)
+	m.Write([]byte(sessionID + "|" + form + "|" + payload))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
+
+// HoneypotFilled reports whether the hidden honeypot input came back non-empty
+func HoneypotFilled(r *http.Request) bool {
+	return strings.TrimSpace(r.PostFormValue(HoneypotField)) != ""
+}
@@ -0,0 +1,157 @@
+package fraud
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestTokens(t *testing.T, secrets ...string) *FormTokens {
+	t.Helper()
+	f, err := NewFormTokens(secrets, time.Hour, nil)
+	if err != nil {
+		t.Fatalf("NewFormTokens: %v", err)
+	}
+	return f
+}
+
+// tokenAt signs a token as if Issue had run at issuedAt
+func tokenAt(f *FormTokens, sessionID, form string, issuedAt time.Time, nonce string) string {
+	payload := strconv.FormatInt(issuedAt.UnixMilli(), 10) + "." + nonce
+	return payload + "." + f.sign(f.secrets[0], sessionID, form, payload)
+}
+
+func TestNewFormTokensNeedsSecret(t *testing.T) {
+	if _, err := NewFormTokens(nil, time.Hour, nil); err == nil {
+		t.Fatal("NewFormTokens without secrets succeeded")
+	}
+}
+
+func TestFormTokenCheck(t *testing.T) {
+	f := newTestTokens(t, "secret")
+	now := time.Now()
+	old := now.Add(-10 * time.Second)
+	valid := tokenAt(f, "sess_1", "info", old, "nonce")
+
+	tests := []struct {
+		name    string
+		session string
+		form    string
+		token   string
+		minAge  time.Duration
+		wantErr error
+	}{
+		{"valid", "sess_1", "info", tokenAt(f, "sess_1", "info", old, "n1"), 0, nil},
+		{"old enough", "sess_1", "info", tokenAt(f, "sess_1", "info", old, "n2"), 5 * time.Second, nil},
+		{"missing", "sess_1", "info", "", 0, ErrTokenMissing},
+		{"garbage", "sess_1", "info", "not-a-token", 0, ErrTokenInvalid},
+		{"empty nonce", "sess_1", "info", tokenAt(f, "sess_1", "info", old, ""), 0, ErrTokenInvalid},
+		{"bad signature", "sess_1", "info", valid[:strings.LastIndex(valid, ".")] + ".AAAA", 0, ErrTokenInvalid},
+		{"other session", "sess_2", "info", valid, 0, ErrTokenInvalid},
+		{"other form", "sess_1", "vote", valid, 0, ErrTokenInvalid},
+		{"bad timestamp", "sess_1", "info", "x.n3." + f.sign(f.secrets[0], "sess_1", "info", "x.n3"), 0, ErrTokenInvalid},
+		{"expired", "sess_1", "info", tokenAt(f, "sess_1", "info", now.Add(-2*time.Hour), "n4"), 0, ErrTokenExpired},
+		{"too fast", "sess_1", "info", tokenAt(f, "sess_1", "info", now, "n5"), 5 * time.Second, ErrTokenTooFast},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.Check(context.Background(), tt.session, tt.form, tt.token, tt.minAge)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Check = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFormTokenIssue(t *testing.T) {
+	f := newTestTokens(t, "secret")
+	token := f.Issue("sess_1", "info")
+
+	issuedAt, err := f.Check(context.Background(), "sess_1", "info", token, 0)
+	if err != nil {
+		t.Fatalf("Check(Issue) = %v", err)
+	}
+	if time.Since(issuedAt) > time.Minute {
+		t.Fatalf("issued at %v, want about now", issuedAt)
+	}
+	if f.Issue("sess_1", "info") == token {
+		t.Fatal("Issue repeated a token")
+	}
+}
+
+func TestFormTokenReplay(t *testing.T) {
+	f := newTestTokens(t, "secret")
+	token := tokenAt(f, "sess_1", "info", time.Now().Add(-10*time.Second), "nonce")
+	ctx := context.Background()
+
+	// Too fast doesn't use the token up, the retry is still accepted
+	if _, err := f.Check(ctx, "sess_1", "info", token, time.Minute); !errors.Is(err, ErrTokenTooFast) {
+		t.Fatalf("first check = %v, want ErrTokenTooFast", err)
+	}
+	if _, err := f.Check(ctx, "sess_1", "info", token, 0); err != nil {
+		t.Fatalf("second check = %v", err)
+	}
+	if _, err := f.Check(ctx, "sess_1", "info", token, 0); !errors.Is(err, ErrTokenReplayed) {
+		t.Fatalf("third check = %v, want ErrTokenReplayed", err)
+	}
+}
+
+func TestFormTokenRotation(t *testing.T) {
+	before := newTestTokens(t, "old")
+	after := newTestTokens(t, "new", "old")
+	retired := newTestTokens(t, "new")
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		issuer  *FormTokens
+		checker *FormTokens
+		wantErr error
+	}{
+		{"old token after rotation", before, after, nil},
+		{"new token after rotation", after, after, nil},
+		{"new token on a lagging instance", after, before, ErrTokenInvalid},
+		{"old token once the old secret is retired", before, retired, ErrTokenInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := tt.issuer.Issue("sess_1", "info")
+			if _, err := tt.checker.Check(ctx, "sess_1", "info", token, 0); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Check = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNilFormTokens(t *testing.T) {
+	var f *FormTokens
+	if token := f.Issue("sess_1", "info"); token != "" {
+		t.Fatalf("Issue = %q, want empty", token)
+	}
+	if _, err := f.Check(context.Background(), "sess_1", "info", "", time.Minute); err != nil {
+		t.Fatalf("Check = %v, want every submission accepted", err)
+	}
+}
+
+func TestHoneypotFilled(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"", false},
+		{"   ", false},
+		{"https://spam.example", true},
+	}
+	for _, tt := range tests {
+		r, _ := http.NewRequest(http.MethodPost, "/", strings.NewReader(url.Values{HoneypotField: {tt.value}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if got := HoneypotFilled(r); got != tt.want {
+			t.Errorf("HoneypotFilled(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package fraud
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"log"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/mrbennbenn/pick6/database"
+)
+
+// maxUserAgent caps stored user agents, real browsers stay well under this
+const maxUserAgent = 512
+
+// Vote is the request metadata for one accepted vote
+type Vote struct {
+	QuestionID string
+	SessionID  string
+	IP         string
+	UserAgent  string
+	TimeOnPage time.Duration // Zero when the page view is unknown
+	PageViewed bool          // False when the form didn't carry a render timestamp
+}
+
+// Recorder stores vote signals and re-scores the session in the background,
+// so a slow or failing write never holds up or fails a vote
+// A fixed pool of workers drains a bounded queue, so a traffic spike can't take
+// more than `workers` database connections away from the vote writes; when the
+// queue is full the signal is dropped and counted
+// A nil *Recorder records nothing
+type Recorder struct {
+	Queries *database.Queries
+	Log     *log.Logger
+	ipKey   []byte
+
+	queue   chan Vote
+	dropped atomic.Uint64
+}
+
+// NewRecorder keys IP hashes with secret and starts workers to drain a queue of queueSize
+// With an empty secret a random key is used, hashes then only match within
+// one process lifetime, which is enough for clustering during an event
+// Returns nil (recording nothing) when workers is 0 or less
+func NewRecorder(queries *database.Queries, secret string, workers, queueSize int, logger *log.Logger) *Recorder {
+	if workers <= 0 {
+		return nil
+	}
+
+	key := []byte(secret)
+	if secret == "" {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic(err) // crypto/rand never fails on supported platforms
+		}
+	}
+	rec := &Recorder{
+		Queries: queries,
+		Log:     logger,
+		ipKey:   key,
+		queue:   make(chan Vote, max(queueSize, 0)),
+	}
+	for range workers {
+		go rec.work()
+	}
+	return rec
+}
+
+// HashIP returns a keyed, truncated hash so raw addresses are never stored
+func (rec *Recorder) HashIP(ip string) string {
+	m := hmac.New(sha256.New, rec.ipKey)
+	m.Write([]byte(ip))
+	return hex.EncodeToString(m.Sum(nil))[:16]
+}
+
+// Record queues the vote's signals for a worker to store and re-score, never blocking
+func (rec *Recorder) Record(v Vote) {
+	if rec == nil {
+		return
+	}
+	select {
+	case rec.queue <- v:
+	default:
+		// Saturated, losing a signal is better than slowing the vote down
+		if dropped := rec.dropped.Add(1); dropped == 1 || dropped%1000 == 0 {
+			if rec.Log != nil {
+				rec.Log.Printf("Fraud signal queue full, %d signals dropped so far", dropped)
+			}
+		}
+	}
+}
+
+// Dropped returns how many signals were dropped because the queue was full
+func (rec *Recorder) Dropped() uint64 {
+	if rec == nil {
+		return 0
+	}
+	return rec.dropped.Load()
+}
+
+// work records queued votes one at a time, for the life of the process
+func (rec *Recorder) work() {
+	for v := range rec.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := rec.record(ctx, v); err != nil && rec.Log != nil {
+			rec.Log.Printf("Error recording vote signals: session=%s question=%s error=%v", v.SessionID, v.QuestionID, err)
+		}
+		cancel()
+	}
+}
+
+func (rec *Recorder) record(ctx context.Context, v Vote) error {
+	userAgent := v.UserAgent
+	if len(userAgent) > maxUserAgent {
+		userAgent = userAgent[:maxUserAgent]
+	}
+	ipHash := rec.HashIP(v.IP)
+
+	var timeOnPage sql.NullInt32
+	if v.PageViewed {
+		ms := v.TimeOnPage.Milliseconds()
+		if ms < 0 {
+			ms = 0
+		}
+		timeOnPage = sql.NullInt32{Int32: int32(min(ms, math.MaxInt32)), Valid: true}
+	}
+
+	if err := rec.Queries.UpsertResponseSignal(ctx, database.UpsertResponseSignalParams{
+		QuestionID:   v.QuestionID,
+		SessionID:    v.SessionID,
+		IpHash:       ipHash,
+		UserAgent:    userAgent,
+		TimeOnPageMs: timeOnPage,
+	}); err != nil {
+		return err
+	}
+
+	stats, err := rec.Queries.GetSessionSignalStats(ctx, database.GetSessionSignalStatsParams{
+		FastMs:    FastAnswerMs,
+		RapidMs:   RapidAnswerMs,
+		SessionID: v.SessionID,
+	})
+	if err != nil {
+		return err
+	}
+	crowd, err := rec.Queries.CountFingerprintSessions(ctx, database.CountFingerprintSessionsParams{
+		IpHash:    ipHash,
+		UserAgent: userAgent,
+	})
+	if err != nil {
+		return err
+	}
+
+	assessment := Score(Stats{
+		Responses:    stats.Responses,
+		NoPageView:   stats.NoPageView,
+		FastAnswers:  stats.FastAnswers,
+		RapidAnswers: stats.RapidAnswers,
+		UserAgents:   stats.UserAgents,
+		IPHashes:     stats.IpHashes,
+		UserAgent:    userAgent,
+		Crowd:        crowd,
+	})
+	if assessment.Flagged() && rec.Log != nil {
+		rec.Log.Printf("Session flagged: %s score=%d reasons=%v", v.SessionID, assessment.Score, assessment.Reasons)
+	}
+
+	return rec.Queries.UpsertSessionRisk(ctx, database.UpsertSessionRiskParams{
+		SessionID: v.SessionID,
+		Score:     int32(assessment.Score),
+		Reasons:   assessment.Reasons,
+		Flagged:   assessment.Flagged(),
+	})
+}
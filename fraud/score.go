@@ -0,0 +1,105 @@
+// Package fraud scores vote sessions for signs of automation or ballot stuffing
+//
+// Every vote records lightweight request metadata (a keyed IP hash, the user
+// agent, time spent on the question page and the gap since the previous vote).
+// Score turns a session's aggregate of those into a 0-100 suspicion score;
+// sessions at or above FlagThreshold can be left out of broadcast tallies
+package fraud
+
+import "strings"
+
+// FlagThreshold is the score at which a session is flagged
+const FlagThreshold = 50
+
+// Timing thresholds, a human needs a moment to read a matchup and tap
+const (
+	FastAnswerMs  = 1500 // Less time on the question page than this is suspicious
+	RapidAnswerMs = 1000 // Less time between two votes than this is suspicious
+)
+
+// fingerprintCrowd is how many sessions can share an IP hash and user agent
+// before it counts against them. Kept high because a venue shares one NAT
+// address and phones of the same model send identical user agents
+const fingerprintCrowd = 25
+
+// automationAgents are user agent fragments sent by scripts and headless browsers
+var automationAgents = []string{
+	"bot", "crawler", "spider", "curl", "wget", "python", "go-http-client",
+	"java/", "okhttp", "axios", "node-fetch", "headless", "phantomjs", "selenium", "puppeteer", "playwright",
+}
+
+// Stats summarises the recorded signals for one session
+type Stats struct {
+	Responses    int64
+	NoPageView   int64 // Votes posted without the question page having been rendered
+	FastAnswers  int64 // Votes with time on page under FastAnswerMs
+	RapidAnswers int64 // Votes under RapidAnswerMs after the previous one
+	UserAgents   int64 // Distinct user agents seen for the session
+	IPHashes     int64 // Distinct IP hashes seen for the session
+	UserAgent    string
+	Crowd        int64 // Sessions sharing the latest IP hash and user agent in the last hour
+}
+
+// Assessment is a session's score and the reasons that contributed to it
+type Assessment struct {
+	Score   int
+	Reasons []string
+}
+
+// Flagged reports whether the session should be left out of tallies
+func (a Assessment) Flagged() bool {
+	return a.Score >= FlagThreshold
+}
+
+// Score weighs a session's signals
+// Weights are additive and capped at 100, no single timing signal flags a
+// session on its own but an automation user agent does
+func Score(s Stats) Assessment {
+	a := Assessment{Reasons: []string{}}
+	add := func(points int, reason string) {
+		a.Score += points
+		a.Reasons = append(a.Reasons, reason)
+	}
+
+	if isAutomationAgent(s.UserAgent) {
+		add(60, "automation_user_agent")
+	}
+	if s.Responses > 0 {
+		if s.NoPageView*2 >= s.Responses {
+			add(35, "no_page_view")
+		}
+		if s.FastAnswers*2 >= s.Responses {
+			add(25, "fast_answers")
+		}
+	}
+	if s.RapidAnswers >= 3 {
+		add(25, "rapid_fire")
+	}
+	if s.UserAgents > 1 {
+		add(20, "user_agent_changed")
+	}
+	if s.IPHashes > 3 {
+		add(10, "ip_hopping")
+	}
+	if s.Crowd > fingerprintCrowd {
+		add(15, "crowded_fingerprint")
+	}
+
+	if a.Score > 100 {
+		a.Score = 100
+	}
+	return a
+}
+
+func isAutomationAgent(userAgent string) bool {
+	ua := strings.ToLower(strings.TrimSpace(userAgent))
+	if ua == "" {
+		return true
+	}
+	for _, fragment := range automationAgents {
+		if strings.Contains(ua, fragment) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package fraud
+
+import (
+	"slices"
+	"testing"
+)
+
+const phoneAgent = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148 Safari/604.1"
+
+// human is a plausible session, each case changes the signals it is about
+func human(change func(*Stats)) Stats {
+	s := Stats{Responses: 6, UserAgents: 1, IPHashes: 1, UserAgent: phoneAgent, Crowd: 3}
+	if change != nil {
+		change(&s)
+	}
+	return s
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name        string
+		stats       Stats
+		wantScore   int
+		wantReasons []string
+	}{
+		{"human", human(nil), 0, []string{}},
+		{"empty user agent", human(func(s *Stats) { s.UserAgent = "  " }), 60, []string{"automation_user_agent"}},
+		{"curl", human(func(s *Stats) { s.UserAgent = "curl/8.4.0" }), 60, []string{"automation_user_agent"}},
+		{"headless chrome", human(func(s *Stats) { s.UserAgent = "Mozilla/5.0 HeadlessChrome/120.0" }), 60, []string{"automation_user_agent"}},
+		{"half without page view", human(func(s *Stats) { s.NoPageView = 3 }), 35, []string{"no_page_view"}},
+		{"few without page view", human(func(s *Stats) { s.NoPageView = 2 }), 0, []string{}},
+		{"half fast", human(func(s *Stats) { s.FastAnswers = 3 }), 25, []string{"fast_answers"}},
+		{"few fast", human(func(s *Stats) { s.FastAnswers = 2 }), 0, []string{}},
+		{"no responses", human(func(s *Stats) { s.Responses, s.NoPageView, s.FastAnswers = 0, 0, 0 }), 0, []string{}},
+		{"rapid fire", human(func(s *Stats) { s.RapidAnswers = 3 }), 25, []string{"rapid_fire"}},
+		{"two rapid", human(func(s *Stats) { s.RapidAnswers = 2 }), 0, []string{}},
+		{"user agent changed", human(func(s *Stats) { s.UserAgents = 2 }), 20, []string{"user_agent_changed"}},
+		{"three addresses", human(func(s *Stats) { s.IPHashes = 3 }), 0, []string{}},
+		{"ip hopping", human(func(s *Stats) { s.IPHashes = 4 }), 10, []string{"ip_hopping"}},
+		{"venue crowd", human(func(s *Stats) { s.Crowd = fingerprintCrowd }), 0, []string{}},
+		{"crowded fingerprint", human(func(s *Stats) { s.Crowd = fingerprintCrowd + 1 }), 15, []string{"crowded_fingerprint"}},
+		{"fast and rapid", human(func(s *Stats) { s.FastAnswers, s.RapidAnswers = 6, 5 }), 50, []string{"fast_answers", "rapid_fire"}},
+		{"everything", Stats{Responses: 6, NoPageView: 6, FastAnswers: 6, RapidAnswers: 5, UserAgents: 3, IPHashes: 5, UserAgent: "python-requests/2.31", Crowd: 100}, 100,
+			[]string{"automation_user_agent", "no_page_view", "fast_answers", "rapid_fire", "user_agent_changed", "ip_hopping", "crowded_fingerprint"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Score(tt.stats)
+			if got.Score != tt.wantScore || !slices.Equal(got.Reasons, tt.wantReasons) {
+				t.Fatalf("Score = %d %v, want %d %v", got.Score, got.Reasons, tt.wantScore, tt.wantReasons)
+			}
+			if got.Reasons == nil {
+				t.Fatal("Reasons is nil, want an empty list for JSON")
+			}
+		})
+	}
+}
+
+func TestFlagged(t *testing.T) {
+	tests := []struct {
+		score int
+		want  bool
+	}{
+		{0, false},
+		{FlagThreshold - 1, false},
+		{FlagThreshold, true},
+		{100, true},
+	}
+	for _, tt := range tests {
+		if got := (Assessment{Score: tt.score}).Flagged(); got != tt.want {
+			t.Errorf("Flagged(%d) = %v, want %v", tt.score, got, tt.want)
+		}
+	}
+
+	// No single timing signal flags a session, an automation user agent does
+	if Score(human(func(s *Stats) { s.FastAnswers = 6 })).Flagged() {
+		t.Error("fast answers alone flagged the session")
+	}
+	if !Score(human(func(s *Stats) { s.UserAgent = "Go-http-client/1.1" })).Flagged() {
+		t.Error("automation user agent did not flag the session")
+	}
+}
@@ -0,0 +1,377 @@
+// Package gdpr finds, exports and erases everything stored about a data subject
+//
+// A subject is identified by email and/or mobile. Their data is every session
+// that used those details (or was linked to the same prize entrant), the votes
+// those sessions cast, their consents, their entrant records and their fraud signals.
+// Shared by the admin API and the `pick6 gdpr` command
+package gdpr
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mrbennbenn/pick6/database"
+	"github.com/mrbennbenn/pick6/database/pii"
+	"github.com/nyaruka/phonenumbers"
+)
+
+// Mode is how Erase treats a subject's data
+type Mode string
+
+const (
+	// ModeErase deletes the sessions outright, votes included
+	// vote_tallies are decremented by the responses trigger as the votes go
+	ModeErase Mode = "erase"
+	// ModeAnonymise clears name, email and mobile but keeps the votes,
+	// so broadcast tallies and leaderboards don't move
+	ModeAnonymise Mode = "anonymise"
+)
+
+// ErrNoSubject is returned when neither an email nor a mobile is given
+var ErrNoSubject = errors.New("email or mobile is required")
+
+// Subject identifies a data subject
+type Subject struct {
+	Email  string `json:"email,omitempty"`
+	Mobile string `json:"mobile,omitempty"` // Normalized to E.164 when it parses
+}
+
+// NewSubject trims the details and normalizes the mobile like the info form does
+func NewSubject(email, mobile string) (Subject, error) {
+	s := Subject{Email: strings.TrimSpace(email), Mobile: strings.TrimSpace(mobile)}
+	if s.Email == "" && s.Mobile == "" {
+		return Subject{}, ErrNoSubject
+	}
+	if s.Mobile != "" {
+		if num, err := phonenumbers.Parse(s.Mobile, "GB"); err == nil {
+			s.Mobile = phonenumbers.Format(num, phonenumbers.E164)
+		}
+	}
+	return s, nil
+}
+
+// Export is everything held about a subject
+type Export struct {
+	Subject     Subject    `json:"subject"`
+	GeneratedAt time.Time  `json:"generated_at"`
+	Sessions    []Session  `json:"sessions"`
+	Entrants    []Entrant  `json:"entrants"`
+	Draws       []DrawPick `json:"draws"`
+}
+
+// Session is a session's contact details and activity
+type Session struct {
+	SessionID string     `json:"session_id"`
+	Name      *string    `json:"name"`
+	Email     *string    `json:"email"`
+	Mobile    *string    `json:"mobile"`
+	Responses []Response `json:"responses"`
+	Signals   []Signal   `json:"signals"`
+	Risk      *Risk      `json:"risk"`
+	Consents  []Consent  `json:"consents"`
+	// Latest marketing decision per event, events without one are omitted
+	MarketingOptIn map[string]bool `json:"marketing_opt_in"`
+}
+
+// Consent is one recorded consent decision
+type Consent struct {
+	EventID       string    `json:"event_id"`
+	Purpose       string    `json:"purpose"`
+	Granted       bool      `json:"granted"`
+	PolicyVersion string    `json:"policy_version"`
+	IPAddress     string    `json:"ip_address"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Response is one vote
+type Response struct {
+	EventID    string    `json:"event_id"`
+	QuestionID string    `json:"question_id"`
+	Question   string    `json:"question"`
+	Slug       string    `json:"slug"`
+	Choice     string    `json:"choice"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Signal is the request metadata stored with a vote
+type Signal struct {
+	QuestionID      string    `json:"question_id"`
+	IPHash          string    `json:"ip_hash"`
+	UserAgent       string    `json:"user_agent"`
+	TimeOnPageMs    *int32    `json:"time_on_page_ms"`
+	SincePreviousMs *int32    `json:"since_previous_ms"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// Risk is a session's fraud score
+type Risk struct {
+	Score     int32     `json:"score"`
+	Reasons   []string  `json:"reasons"`
+	Flagged   bool      `json:"flagged"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Entrant is a prize draw entry
+// Contact details are held as blind indexes, plaintext only on entrants from
+// before encryption that the backfill hasn't reached
+type Entrant struct {
+	EntrantID       string    `json:"entrant_id"`
+	EventID         string    `json:"event_id"`
+	EmailNormalized *string   `json:"email_normalized,omitempty"`
+	Mobile          *string   `json:"mobile,omitempty"`
+	EmailIndex      *string   `json:"email_index"`
+	MobileIndex     *string   `json:"mobile_index"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// DrawPick is a draw that selected one of the subject's sessions
+type DrawPick struct {
+	DrawID    string    `json:"draw_id"`
+	EventID   string    `json:"event_id"`
+	SessionID string    `json:"session_id"`
+	Role      string    `json:"role"` // "winner" or "alternate"
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Result summarises an erasure
+type Result struct {
+	Mode             Mode     `json:"mode"`
+	SessionIDs       []string `json:"session_ids"`
+	SessionsErased   int64    `json:"sessions_erased"`
+	ResponsesRemoved int64    `json:"responses_removed"`
+	EntrantsRemoved  int64    `json:"entrants_removed"`
+	SignalsRemoved   int64    `json:"signals_removed"`
+	ConsentsRemoved  int64    `json:"consents_removed"`
+	DrawsReferencing int      `json:"draws_referencing"` // Draw records keep the now-anonymous session ID for audit
+}
+
+// Find gathers everything held about a subject
+func Find(ctx context.Context, queries *database.Queries, subject Subject) (*Export, error) {
+	params := database.ListSubjectSessionsParams{Email: subject.Email, Mobile: subject.Mobile}
+	if subject.Email != "" {
+		params.EmailIndex = pii.EmailIndex(subject.Email)
+	}
+	if subject.Mobile != "" {
+		params.MobileIndex = pii.MobileIndex(subject.Mobile)
+	}
+	sessions, err := queries.ListSubjectSessions(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+
+	export := &Export{
+		Subject:     subject,
+		GeneratedAt: time.Now().UTC(),
+		Sessions:    make([]Session, len(sessions)),
+		Entrants:    []Entrant{},
+		Draws:       []DrawPick{},
+	}
+	ids := make([]string, len(sessions))
+	byID := make(map[string]*Session, len(sessions))
+	for i, s := range sessions {
+		ids[i] = s.SessionID
+		export.Sessions[i] = Session{
+			SessionID: s.SessionID,
+			Name:      piiPtr(s.Name),
+			Email:     piiPtr(s.Email),
+			Mobile:    piiPtr(s.Mobile),
+			Responses: []Response{},
+			Signals:   []Signal{},
+			Consents:  []Consent{},
+
+			MarketingOptIn: map[string]bool{},
+		}
+		byID[s.SessionID] = &export.Sessions[i]
+	}
+	if len(ids) == 0 {
+		return export, nil
+	}
+
+	responses, err := queries.ListSubjectResponses(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("list responses: %w", err)
+	}
+	for _, r := range responses {
+		s := byID[r.SessionID]
+		s.Responses = append(s.Responses, Response{
+			EventID:    r.EventID,
+			QuestionID: r.QuestionID,
+			Question:   r.BigText,
+			Slug:       r.Slug,
+			Choice:     r.Choice,
+			CreatedAt:  r.CreatedAt,
+			UpdatedAt:  r.UpdatedAt,
+		})
+	}
+
+	signals, err := queries.ListResponseSignalsBySessions(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("list signals: %w", err)
+	}
+	for _, sig := range signals {
+		s := byID[sig.SessionID]
+		s.Signals = append(s.Signals, Signal{
+			QuestionID:      sig.QuestionID,
+			IPHash:          sig.IpHash,
+			UserAgent:       sig.UserAgent,
+			TimeOnPageMs:    int32Ptr(sig.TimeOnPageMs),
+			SincePreviousMs: int32Ptr(sig.SincePreviousMs),
+			CreatedAt:       sig.CreatedAt,
+		})
+	}
+
+	risks, err := queries.ListSessionRiskBySessions(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("list risk: %w", err)
+	}
+	for _, r := range risks {
+		byID[r.SessionID].Risk = &Risk{Score: r.Score, Reasons: r.Reasons, Flagged: r.Flagged, UpdatedAt: r.UpdatedAt}
+	}
+
+	// Ordered by created_at, so the last marketing row per event is the current decision
+	consents, err := queries.ListConsentsBySessions(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("list consents: %w", err)
+	}
+	for _, c := range consents {
+		s := byID[c.SessionID]
+		s.Consents = append(s.Consents, Consent{
+			EventID:       c.EventID,
+			Purpose:       c.Purpose,
+			Granted:       c.Granted,
+			PolicyVersion: c.PolicyVersion,
+			IPAddress:     c.IpAddress,
+			CreatedAt:     c.CreatedAt,
+		})
+		if c.Purpose == "marketing" {
+			s.MarketingOptIn[c.EventID] = c.Granted
+		}
+	}
+
+	entrants, err := queries.ListEntrantsBySessions(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("list entrants: %w", err)
+	}
+	for _, e := range entrants {
+		export.Entrants = append(export.Entrants, Entrant{
+			EntrantID:       e.EntrantID,
+			EventID:         e.EventID,
+			EmailNormalized: stringPtr(e.EmailNormalized),
+			Mobile:          stringPtr(e.Mobile),
+			EmailIndex:      stringPtr(e.EmailIndex),
+			MobileIndex:     stringPtr(e.MobileIndex),
+			CreatedAt:       e.CreatedAt,
+		})
+	}
+
+	draws, err := queries.ListDrawsBySessions(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("list draws: %w", err)
+	}
+	for _, d := range draws {
+		if d.WinnerSessionID.Valid && byID[d.WinnerSessionID.String] != nil {
+			export.Draws = append(export.Draws, DrawPick{DrawID: d.DrawID, EventID: d.EventID, SessionID: d.WinnerSessionID.String, Role: "winner", CreatedAt: d.CreatedAt})
+		}
+		for _, alt := range d.Alternates {
+			if byID[alt] != nil {
+				export.Draws = append(export.Draws, DrawPick{DrawID: d.DrawID, EventID: d.EventID, SessionID: alt, Role: "alternate", CreatedAt: d.CreatedAt})
+			}
+		}
+	}
+
+	return export, nil
+}
+
+// Erase removes a subject's personal data in one transaction
+// Either way entrant records, consents and fraud signals are deleted, since they
+// hold the contact details, IP address, IP hash and user agent
+func Erase(ctx context.Context, db *sql.DB, subject Subject, mode Mode) (*Result, error) {
+	if mode != ModeErase && mode != ModeAnonymise {
+		return nil, fmt.Errorf("unknown mode %q, want %q or %q", mode, ModeErase, ModeAnonymise)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	queries := database.New(db).WithTx(tx)
+
+	export, err := Find(ctx, queries, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Mode: mode, SessionIDs: []string{}}
+	for _, s := range export.Sessions {
+		result.SessionIDs = append(result.SessionIDs, s.SessionID)
+		result.ResponsesRemoved += int64(len(s.Responses))
+		result.SignalsRemoved += int64(len(s.Signals))
+		result.ConsentsRemoved += int64(len(s.Consents))
+	}
+	entrantIDs := make([]string, len(export.Entrants))
+	for i, e := range export.Entrants {
+		entrantIDs[i] = e.EntrantID
+	}
+	result.DrawsReferencing = len(export.Draws)
+	if len(result.SessionIDs) == 0 {
+		return result, nil
+	}
+
+	if result.EntrantsRemoved, err = queries.DeleteEntrants(ctx, entrantIDs); err != nil {
+		return nil, fmt.Errorf("delete entrants: %w", err)
+	}
+
+	switch mode {
+	case ModeErase:
+		// Responses, signals, risk and consent rows go with the sessions via ON DELETE CASCADE
+		if result.SessionsErased, err = queries.DeleteSessions(ctx, result.SessionIDs); err != nil {
+			return nil, fmt.Errorf("delete sessions: %w", err)
+		}
+	case ModeAnonymise:
+		result.ResponsesRemoved = 0
+		if result.SignalsRemoved, err = queries.DeleteResponseSignalsBySessions(ctx, result.SessionIDs); err != nil {
+			return nil, fmt.Errorf("delete signals: %w", err)
+		}
+		if _, err = queries.DeleteSessionRiskBySessions(ctx, result.SessionIDs); err != nil {
+			return nil, fmt.Errorf("delete risk: %w", err)
+		}
+		if result.ConsentsRemoved, err = queries.DeleteConsentsBySessions(ctx, result.SessionIDs); err != nil {
+			return nil, fmt.Errorf("delete consents: %w", err)
+		}
+		if result.SessionsErased, err = queries.AnonymiseSessions(ctx, result.SessionIDs); err != nil {
+			return nil, fmt.Errorf("anonymise sessions: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func stringPtr(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+func piiPtr(s pii.String) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+func int32Ptr(n sql.NullInt32) *int32 {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Int32
+}
@@ -0,0 +1,140 @@
+package gdpr_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/mrbennbenn/pick6/database"
+	"github.com/mrbennbenn/pick6/database/dbtest"
+	"github.com/mrbennbenn/pick6/gdpr"
+)
+
+// Seeded by the initial migration
+const (
+	seedEvent    = "event_39aJ1km3pr9v1yQYX5gS88e3CUM"
+	seedQuestion = "question_39aJ1eE9ihQ3hH9kmOfKdCSueFP"
+)
+
+// fixture stores a fan with two sessions (the second linked only through their
+// prize entrant, with a different email) and a bystander, each with a vote
+func fixture(t *testing.T, db *sql.DB) {
+	t.Helper()
+	statements := []string{
+		`INSERT INTO sessions (session_id, name, email, mobile) VALUES
+			('session_fan', 'Fan One', 'fan@example.com', '+447700900123'),
+			('session_fan_phone', 'Fan One', 'fan.work@example.com', '+447700900123'),
+			('session_other', 'Other Fan', 'other@example.com', '+447700900999')`,
+		`INSERT INTO responses (question_id, session_id, slug, choice) VALUES
+			('` + seedQuestion + `', 'session_fan', 'tk03', 'a'),
+			('` + seedQuestion + `', 'session_fan_phone', 'tk03', 'a'),
+			('` + seedQuestion + `', 'session_other', 'tk03', 'b')`,
+		`INSERT INTO response_signals (question_id, session_id, ip_hash, user_agent) VALUES
+			('` + seedQuestion + `', 'session_fan', 'iphash', 'ua'),
+			('` + seedQuestion + `', 'session_other', 'iphash', 'ua')`,
+		`INSERT INTO session_risk (session_id, score) VALUES ('session_fan', 10)`,
+		`INSERT INTO entrants (entrant_id, event_id, email_normalized, mobile) VALUES
+			('entrant_fan', '` + seedEvent + `', 'fan@example.com', '+447700900123')`,
+		`INSERT INTO entrant_sessions (event_id, session_id, entrant_id) VALUES
+			('` + seedEvent + `', 'session_fan', 'entrant_fan'),
+			('` + seedEvent + `', 'session_fan_phone', 'entrant_fan')`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("fixture: %v", err)
+		}
+	}
+}
+
+func count(t *testing.T, db *sql.DB, query string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return n
+}
+
+func TestErase(t *testing.T) {
+	tests := []struct {
+		mode      gdpr.Mode
+		sessions  int   // Fan sessions left afterwards
+		responses int64 // Result.ResponsesRemoved
+		votes     int64 // Tallied votes left on the question
+	}{
+		{gdpr.ModeErase, 0, 2, 1},
+		{gdpr.ModeAnonymise, 2, 0, 3},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			db := dbtest.Open(t)
+			fixture(t, db)
+			ctx := context.Background()
+
+			subject, err := gdpr.NewSubject("Fan@Example.com", "")
+			if err != nil {
+				t.Fatalf("subject: %v", err)
+			}
+			result, err := gdpr.Erase(ctx, db, subject, tt.mode)
+			if err != nil {
+				t.Fatalf("erase: %v", err)
+			}
+
+			if len(result.SessionIDs) != 2 || result.SessionsErased != 2 {
+				t.Errorf("sessions %v (%d erased), want both fan sessions", result.SessionIDs, result.SessionsErased)
+			}
+			if result.ResponsesRemoved != tt.responses || result.EntrantsRemoved != 1 || result.SignalsRemoved != 1 {
+				t.Errorf("result %+v", result)
+			}
+
+			fan := `session_id IN ('session_fan', 'session_fan_phone')`
+			if n := count(t, db, `SELECT COUNT(*) FROM sessions WHERE `+fan); n != tt.sessions {
+				t.Errorf("%d fan sessions left, want %d", n, tt.sessions)
+			}
+			if n := count(t, db, `SELECT COUNT(*) FROM sessions WHERE `+fan+` AND (name IS NOT NULL OR email IS NOT NULL OR mobile IS NOT NULL)`); n != 0 {
+				t.Errorf("%d fan sessions still hold contact details", n)
+			}
+			for _, table := range []string{"response_signals", "session_risk", "entrant_sessions"} {
+				if n := count(t, db, `SELECT COUNT(*) FROM `+table+` WHERE `+fan); n != 0 {
+					t.Errorf("%d fan rows left in %s", n, table)
+				}
+			}
+			if n := count(t, db, `SELECT COUNT(*) FROM entrants`); n != 0 {
+				t.Errorf("%d entrants left", n)
+			}
+
+			// The bystander is untouched
+			if n := count(t, db, `SELECT COUNT(*) FROM sessions WHERE session_id = 'session_other' AND email = 'other@example.com'`); n != 1 {
+				t.Error("other session changed")
+			}
+			if n := count(t, db, `SELECT COUNT(*) FROM response_signals WHERE session_id = 'session_other'`); n != 1 {
+				t.Error("other session's signals removed")
+			}
+
+			// Erasing drops the votes from the tallies, anonymising keeps them
+			total, err := database.New(db).GetQuestionEngagementTotal(ctx, seedQuestion)
+			if err != nil {
+				t.Fatalf("tally: %v", err)
+			}
+			if total.TotalVotes != tt.votes {
+				t.Errorf("%d votes tallied, want %d", total.TotalVotes, tt.votes)
+			}
+
+			// Nothing is found for the subject any more
+			export, err := gdpr.Find(ctx, database.New(db), subject)
+			if err != nil {
+				t.Fatalf("find: %v", err)
+			}
+			if len(export.Sessions) != 0 || len(export.Entrants) != 0 {
+				t.Errorf("still found %d sessions and %d entrants", len(export.Sessions), len(export.Entrants))
+			}
+		})
+	}
+}
+
+func TestEraseUnknownMode(t *testing.T) {
+	subject, _ := gdpr.NewSubject("fan@example.com", "")
+	if _, err := gdpr.Erase(context.Background(), nil, subject, "shred"); err == nil {
+		t.Fatal("unknown mode accepted")
+	}
+}
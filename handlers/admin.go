@@ -0,0 +1,633 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/go-chi/chi/v5"
+	"github.com/mrbennbenn/pick6/database"
+	"github.com/mrbennbenn/pick6/middleware"
+	"github.com/mrbennbenn/pick6/templates"
+	"github.com/segmentio/ksuid"
+)
+
+// maxImageUploadSize caps a single upload request (all files combined)
+const maxImageUploadSize = 20 << 20 // 20MB
+
+// imageFilenamePattern restricts uploaded filenames to safe, URL-friendly names
+var imageFilenamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*\.(jpg|jpeg|png|webp)$`)
+
+// allowedImageTypes maps sniffed content types to the extensions they may use
+var allowedImageTypes = map[string][]string{
+	"image/jpeg": {".jpg", ".jpeg"},
+	"image/png":  {".png"},
+	"image/webp": {".webp"},
+}
+
+// Admin serves the server-rendered admin console for running an event night
+type Admin struct {
+	Queries    *database.Queries
+	Log        *log.Logger
+	EventCache *database.EventCache
+	Auth       *middleware.AdminAuth
+	ImageDir   string // Directory matchup images are uploaded to (e.g. ./static/images)
+}
+
+// ShowLogin displays the admin login form
+// Route: GET /admin/login
+func (h *Admin) ShowLogin(w http.ResponseWriter, r *http.Request) {
+	vm := templates.AdminLoginViewModel{}
+	if !h.Auth.Enabled() {
+		vm.Error = "Admin login is disabled: set ADMIN_PASSWORD or API_KEYS"
+	} else if r.URL.Query().Get("error") != "" {
+		vm.Error = "Invalid password or API key"
+	}
+
+	h.render(w, r, templates.AdminLoginPage(vm))
+}
+
+// Login checks the submitted credential and starts an admin session
+// Route: POST /admin/login
+func (h *Admin) Login(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	if !h.Auth.Login(w, r, r.FormValue("credential")) {
+		http.Redirect(w, r, "/admin/login?error=1", http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, "/admin", http.StatusSeeOther)
+}
+
+// Logout ends the admin session
+// Route: POST /admin/logout
+func (h *Admin) Logout(w http.ResponseWriter, r *http.Request) {
+	h.Auth.Logout(w)
+	http.Redirect(w, r, "/admin/login", http.StatusSeeOther)
+}
+
+// ShowDashboard lists all events
+// Route: GET /admin
+func (h *Admin) ShowDashboard(w http.ResponseWriter, r *http.Request) {
+	events, err := h.Queries.ListEvents(r.Context())
+	if err != nil {
+		h.Log.Printf("Error listing events: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	vm := templates.AdminDashboardViewModel{
+		Events: make([]templates.AdminEvent, len(events)),
+		Flash:  r.URL.Query().Get("flash"),
+		Errors: parseErrors(r),
+	}
+	for i, e := range events {
+		vm.Events[i] = toAdminEvent(e)
+	}
+
+	h.render(w, r, templates.AdminDashboardPage(vm))
+}
+
+// CreateEvent creates an event from the dashboard form
+// Route: POST /admin/events
+func (h *Admin) CreateEvent(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	description := strings.TrimSpace(r.FormValue("description"))
+	if description == "" {
+		http.Redirect(w, r, buildErrorRedirectURL("/admin", map[string]string{"description": "is required"}, nil), http.StatusSeeOther)
+		return
+	}
+
+	event, err := h.Queries.CreateEvent(r.Context(), database.CreateEventParams{
+		EventID:     fmt.Sprintf("event_%s", ksuid.New().String()),
+		Description: description,
+	})
+	if err != nil {
+		h.Log.Printf("Error creating event: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.Log.Printf("Admin created event %s", event.EventID)
+	h.redirectToEvent(w, r, event.EventID, "Event created", nil)
+}
+
+// ShowEvent displays the console for a single event
+// Route: GET /admin/events/{eventID}
+func (h *Admin) ShowEvent(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+	ctx := r.Context()
+
+	event, err := h.Queries.GetEventByID(ctx, eventID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+			return
+		}
+		h.Log.Printf("Error getting event: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	slugs, err := h.Queries.ListSlugsByEventID(ctx, eventID)
+	if err != nil {
+		h.Log.Printf("Error listing slugs: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	questions, err := h.Queries.ListQuestionsByEventID(ctx, eventID)
+	if err != nil {
+		h.Log.Printf("Error listing questions: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	vm := templates.AdminEventViewModel{
+		Event:     toAdminEvent(event),
+		Slugs:     make([]string, len(slugs)),
+		Questions: make([]templates.AdminQuestion, len(questions)),
+		Images:    h.listImages(),
+		Counts:    h.loadCounts(r, questions),
+		Flash:     r.URL.Query().Get("flash"),
+		Errors:    parseErrors(r),
+	}
+	for i, s := range slugs {
+		vm.Slugs[i] = s.Slug
+	}
+	now := time.Now()
+	for i, q := range questions {
+		vm.Questions[i] = templates.AdminQuestion{
+			Index:         i + 1,
+			QuestionID:    q.QuestionID,
+			BigText:       q.BigText,
+			SmallText:     q.SmallText,
+			ImageFilename: q.ImageFilename,
+			ChoiceA:       q.ChoiceA,
+			ChoiceB:       q.ChoiceB,
+			Result:        q.Result.String,
+			IsOpen:        q.IsOpen(now),
+			OnAir:         event.OnAirQuestionID.Valid && event.OnAirQuestionID.String == q.QuestionID,
+		}
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	h.render(w, r, templates.AdminEventPage(vm))
+}
+
+// ShowCounts renders the live vote counts fragment polled by the event console
+// Route: GET /admin/events/{eventID}/counts
+func (h *Admin) ShowCounts(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+
+	questions, err := h.Queries.ListQuestionsByEventID(r.Context(), eventID)
+	if err != nil {
+		h.Log.Printf("Error listing questions: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	h.render(w, r, templates.AdminCountsTable(h.loadCounts(r, questions)))
+}
+
+// SetOnAir marks a question as on air for broadcast graphics, an empty value clears it
+// Route: POST /admin/events/{eventID}/on-air
+func (h *Admin) SetOnAir(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	value := r.FormValue("question_id")
+	event, err := h.Queries.SetEventOnAirQuestion(r.Context(), database.SetEventOnAirQuestionParams{
+		QuestionID: sql.NullString{String: value, Valid: value != ""},
+		EventID:    eventID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+			return
+		}
+		h.Log.Printf("Error setting on-air question: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.Log.Printf("Admin set on-air question for event %s: %q", eventID, value)
+
+	flash := "Nothing on air"
+	if event.OnAirQuestionID.Valid {
+		flash = "On-air question updated"
+	}
+	h.redirectToEvent(w, r, eventID, flash, nil)
+}
+
+// CreateSlug adds a slug to an event
+// Route: POST /admin/events/{eventID}/slugs
+func (h *Admin) CreateSlug(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	slug := strings.TrimSpace(r.FormValue("slug"))
+	switch {
+	case !database.ValidSlug(slug):
+		h.redirectToEvent(w, r, eventID, "", map[string]string{"slug": "must contain only a-z, 0-9 and -"})
+		return
+	case reservedSlugs[slug]:
+		h.redirectToEvent(w, r, eventID, "", map[string]string{"slug": "is reserved"})
+		return
+	}
+
+	_, err := h.Queries.CreateSlug(r.Context(), database.CreateSlugParams{
+		Slug:    slug,
+		EventID: eventID,
+	})
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			h.redirectToEvent(w, r, eventID, "", map[string]string{"slug": "already exists"})
+			return
+		}
+		h.Log.Printf("Error creating slug: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.EventCache.InvalidateSlug(slug)
+	h.Log.Printf("Admin created slug %s for event %s", slug, eventID)
+	h.redirectToEvent(w, r, eventID, "Slug added", nil)
+}
+
+// CreateQuestion appends a question to an event
+// Route: POST /admin/events/{eventID}/questions
+func (h *Admin) CreateQuestion(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+
+	in, ok := h.parseQuestionForm(w, r, eventID)
+	if !ok {
+		return
+	}
+
+	question, err := h.Queries.CreateQuestion(r.Context(), database.CreateQuestionParams{
+		QuestionID:    fmt.Sprintf("question_%s", ksuid.New().String()),
+		EventID:       eventID,
+		BigText:       in.BigText,
+		SmallText:     in.SmallText,
+		ImageFilename: in.ImageFilename,
+		ChoiceA:       in.ChoiceA,
+		ChoiceB:       in.ChoiceB,
+	})
+	if err != nil {
+		h.Log.Printf("Error creating question: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.EventCache.InvalidateEvent(eventID)
+	h.Log.Printf("Admin created question %s for event %s", question.QuestionID, eventID)
+	h.redirectToEvent(w, r, eventID, "Question added", nil)
+}
+
+// UpdateQuestion saves edits to a question's text, image and choices
+// Route: POST /admin/questions/{questionID}
+func (h *Admin) UpdateQuestion(w http.ResponseWriter, r *http.Request) {
+	questionID := chi.URLParam(r, "questionID")
+
+	existing, err := h.Queries.GetQuestionByID(r.Context(), questionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+			return
+		}
+		h.Log.Printf("Error getting question: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	in, ok := h.parseQuestionForm(w, r, existing.EventID)
+	if !ok {
+		return
+	}
+
+	_, err = h.Queries.UpdateQuestion(r.Context(), database.UpdateQuestionParams{
+		QuestionID:    questionID,
+		BigText:       in.BigText,
+		SmallText:     in.SmallText,
+		ImageFilename: in.ImageFilename,
+		ChoiceA:       in.ChoiceA,
+		ChoiceB:       in.ChoiceB,
+	})
+	if err != nil {
+		h.Log.Printf("Error updating question: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.EventCache.InvalidateEvent(existing.EventID)
+	h.Log.Printf("Admin updated question %s", questionID)
+	h.redirectToEvent(w, r, existing.EventID, "Question saved", nil)
+}
+
+// SetQuestionResult records the official result of a fight, an empty value clears it
+// Route: POST /admin/questions/{questionID}/result
+func (h *Admin) SetQuestionResult(w http.ResponseWriter, r *http.Request) {
+	questionID := chi.URLParam(r, "questionID")
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	value := r.FormValue("result")
+	result := sql.NullString{String: value, Valid: value != ""}
+	if result.Valid && !database.IsValidResult(value) {
+		http.Error(w, "Invalid result", http.StatusBadRequest)
+		return
+	}
+
+	question, err := h.Queries.SetQuestionResult(r.Context(), database.SetQuestionResultParams{
+		QuestionID: questionID,
+		Result:     result,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+			return
+		}
+		h.Log.Printf("Error setting question result: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.EventCache.InvalidateEvent(question.EventID)
+	h.Log.Printf("Admin set result for question %s: %q", questionID, value)
+	h.redirectToEvent(w, r, question.EventID, fmt.Sprintf("Result saved for %s", question.BigText), nil)
+}
+
+// LockQuestion closes voting for a question immediately, e.g. as the fight starts
+// Route: POST /admin/questions/{questionID}/lock
+func (h *Admin) LockQuestion(w http.ResponseWriter, r *http.Request) {
+	questionID := chi.URLParam(r, "questionID")
+
+	question, err := h.Queries.LockQuestion(r.Context(), questionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+			return
+		}
+		h.Log.Printf("Error locking question: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.EventCache.InvalidateEvent(question.EventID)
+	h.Log.Printf("Admin locked question %s", questionID)
+	h.redirectToEvent(w, r, question.EventID, fmt.Sprintf("Voting locked for %s", question.BigText), nil)
+}
+
+// ReopenQuestion clears a question's voting window so it accepts votes again
+// Route: POST /admin/questions/{questionID}/reopen
+func (h *Admin) ReopenQuestion(w http.ResponseWriter, r *http.Request) {
+	questionID := chi.URLParam(r, "questionID")
+
+	question, err := h.Queries.SetQuestionWindow(r.Context(), database.SetQuestionWindowParams{
+		QuestionID: questionID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+			return
+		}
+		h.Log.Printf("Error reopening question: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.EventCache.InvalidateEvent(question.EventID)
+	h.Log.Printf("Admin reopened question %s", questionID)
+
+	flash := fmt.Sprintf("Voting reopened for %s", question.BigText)
+	if question.HasResult() {
+		flash += " (still closed until the result is cleared)"
+	}
+	h.redirectToEvent(w, r, question.EventID, flash, nil)
+}
+
+// UploadImages saves matchup images into the image directory
+// Route: POST /admin/events/{eventID}/images
+func (h *Admin) UploadImages(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImageUploadSize)
+	if err := r.ParseMultipartForm(maxImageUploadSize); err != nil {
+		h.redirectToEvent(w, r, eventID, "", map[string]string{"images": "upload too large or malformed"})
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	files := r.MultipartForm.File["images"]
+	if len(files) == 0 {
+		h.redirectToEvent(w, r, eventID, "", map[string]string{"images": "no files selected"})
+		return
+	}
+
+	saved := make([]string, 0, len(files))
+	for _, fh := range files {
+		filename := strings.ToLower(filepath.Base(fh.Filename))
+		if !imageFilenamePattern.MatchString(filename) {
+			h.redirectToEvent(w, r, eventID, "", map[string]string{"images": fmt.Sprintf("%q must be a .jpg, .png or .webp with a-z, 0-9, ., _ or - only", fh.Filename)})
+			return
+		}
+
+		if err := h.saveImage(fh, filename); err != nil {
+			h.Log.Printf("Error saving image %s: %v", filename, err)
+			h.redirectToEvent(w, r, eventID, "", map[string]string{"images": fmt.Sprintf("%s: %v", filename, err)})
+			return
+		}
+		saved = append(saved, filename)
+	}
+
+	h.Log.Printf("Admin uploaded images: %s", strings.Join(saved, ", "))
+	h.redirectToEvent(w, r, eventID, fmt.Sprintf("Uploaded %s", strings.Join(saved, ", ")), nil)
+}
+
+// Helper: saveImage validates an upload's content type and writes it atomically
+func (h *Admin) saveImage(fh *multipart.FileHeader, filename string) error {
+	src, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	// Sniff the real content type rather than trusting the extension
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return fmt.Errorf("could not read file")
+	}
+	contentType := http.DetectContentType(head[:n])
+	if !extensionAllowed(contentType, filepath.Ext(filename)) {
+		return fmt.Errorf("content is %s, which does not match the extension", contentType)
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	// Write to a temp file first so the static server never sees a partial image
+	tmp, err := os.CreateTemp(h.ImageDir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filepath.Join(h.ImageDir, filename))
+}
+
+func extensionAllowed(contentType, ext string) bool {
+	for _, allowed := range allowedImageTypes[contentType] {
+		if ext == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// Helper: parseQuestionForm reads and validates a question form, redirecting back on error
+func (h *Admin) parseQuestionForm(w http.ResponseWriter, r *http.Request, eventID string) (*questionInput, bool) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return nil, false
+	}
+
+	in := &questionInput{
+		BigText:       r.FormValue("big_text"),
+		SmallText:     r.FormValue("small_text"),
+		ImageFilename: r.FormValue("image_filename"),
+		ChoiceA:       r.FormValue("choice_a"),
+		ChoiceB:       r.FormValue("choice_b"),
+	}
+	if errs := in.validate(); len(errs) > 0 {
+		h.redirectToEvent(w, r, eventID, "", errs)
+		return nil, false
+	}
+
+	return in, true
+}
+
+// Helper: loadCounts builds live counts for each question from the same
+// engagement data the public API serves
+func (h *Admin) loadCounts(r *http.Request, questions []database.Question) []templates.AdminQuestionCounts {
+	counts := make([]templates.AdminQuestionCounts, 0, len(questions))
+	for i, q := range questions {
+		engagement, err := loadQuestionEngagement(r.Context(), h.Queries, q.QuestionID, false)
+		if err != nil {
+			h.Log.Printf("Error getting question engagement: %v", err)
+			continue
+		}
+
+		c := templates.AdminQuestionCounts{
+			Index:   i + 1,
+			BigText: q.BigText,
+			ChoiceA: q.ChoiceA,
+			ChoiceB: q.ChoiceB,
+			Total:   toAdminVoteCounts(engagement.Total),
+			BySlug:  make([]templates.AdminVoteCounts, len(engagement.BySlug)),
+		}
+		for j, s := range engagement.BySlug {
+			c.BySlug[j] = toAdminVoteCounts(s)
+		}
+		counts = append(counts, c)
+	}
+	return counts
+}
+
+// Helper: listImages returns image filenames available for questions
+func (h *Admin) listImages() []string {
+	entries, err := os.ReadDir(h.ImageDir)
+	if err != nil {
+		h.Log.Printf("Error reading image directory: %v", err)
+		return nil
+	}
+
+	images := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && imageFilenamePattern.MatchString(entry.Name()) {
+			images = append(images, entry.Name())
+		}
+	}
+	sort.Strings(images)
+	return images
+}
+
+// Helper: redirectToEvent redirects to the event console with a flash message or errors
+func (h *Admin) redirectToEvent(w http.ResponseWriter, r *http.Request, eventID, flash string, errs map[string]string) {
+	values := map[string]string{}
+	if flash != "" {
+		values["flash"] = flash
+	}
+	http.Redirect(w, r, buildErrorRedirectURL(fmt.Sprintf("/admin/events/%s", eventID), errs, values), http.StatusSeeOther)
+}
+
+// Helper: render renders a templ component with the login's CSRF token for its forms, logging failures
+func (h *Admin) render(w http.ResponseWriter, r *http.Request, component templ.Component) {
+	ctx := templates.WithCSRFToken(r.Context(), h.Auth.CSRFToken(r))
+	if err := component.Render(ctx, w); err != nil {
+		h.Log.Printf("Error rendering template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func toAdminEvent(e database.Event) templates.AdminEvent {
+	return templates.AdminEvent{
+		EventID:     e.EventID,
+		Description: e.Description,
+		CreatedAt:   e.CreatedAt,
+	}
+}
+
+func toAdminVoteCounts(c voteCounts) templates.AdminVoteCounts {
+	return templates.AdminVoteCounts{
+		Slug:        c.Slug,
+		TotalVotes:  c.TotalVotes,
+		VotesA:      c.VotesA,
+		VotesB:      c.VotesB,
+		PercentageA: c.PercentageA,
+		PercentageB: c.PercentageB,
+	}
+}
@@ -0,0 +1,653 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mrbennbenn/pick6/database"
+	cache "github.com/patrickmn/go-cache"
+	"github.com/segmentio/ksuid"
+)
+
+// AdminAPI exposes authenticated CRUD over events, slugs and questions
+// Every write invalidates the matching EventCache entries so the voting UI
+// picks up changes without a restart
+type AdminAPI struct {
+	DB         *sql.DB // For operations that need a transaction
+	Queries    *database.Queries
+	Log        *log.Logger
+	EventCache *database.EventCache
+
+	// Session validity cache and its counters, for the cache stats endpoint (nil = not reported)
+	SessionCache      *cache.Cache
+	SessionCacheStats *database.CacheCounters
+
+	RetentionDays int // PII retention for events without their own policy, 0 keeps it
+}
+
+// reservedSlugs are top-level paths owned by the router, a slug with one of
+// these names would never be reachable by voters
+var reservedSlugs = map[string]bool{
+	"admin":   true,
+	"api":     true,
+	"overlay": true,
+	"static":  true,
+}
+
+type eventInput struct {
+	Description string `json:"description"`
+}
+
+type onAirInput struct {
+	QuestionID *string `json:"question_id"` // null takes the event off air
+}
+
+type slugInput struct {
+	Slug string `json:"slug"`
+}
+
+type resultInput struct {
+	Result *string `json:"result"` // null clears the result
+}
+
+type windowInput struct {
+	OpensAt  *time.Time `json:"opens_at"`  // null opens immediately
+	ClosesAt *time.Time `json:"closes_at"` // null stays open until locked
+}
+
+type questionInput struct {
+	BigText       string `json:"big_text"`
+	SmallText     string `json:"small_text"`
+	ImageFilename string `json:"image_filename"`
+	ChoiceA       string `json:"choice_a"`
+	ChoiceB       string `json:"choice_b"`
+}
+
+// validate trims all fields and returns a map of field errors
+func (in *questionInput) validate() map[string]string {
+	in.BigText = strings.TrimSpace(in.BigText)
+	in.SmallText = strings.TrimSpace(in.SmallText)
+	in.ImageFilename = strings.TrimSpace(in.ImageFilename)
+	in.ChoiceA = strings.TrimSpace(in.ChoiceA)
+	in.ChoiceB = strings.TrimSpace(in.ChoiceB)
+
+	errs := make(map[string]string)
+	if in.BigText == "" {
+		errs["big_text"] = "is required"
+	}
+	if in.ChoiceA == "" {
+		errs["choice_a"] = "is required"
+	}
+	if in.ChoiceB == "" {
+		errs["choice_b"] = "is required"
+	}
+	if strings.ContainsAny(in.ImageFilename, `/\`) {
+		errs["image_filename"] = "must be a bare filename inside static/images"
+	}
+	return errs
+}
+
+// ListEvents returns all events, newest first
+// Route: GET /admin/api/events
+func (h *AdminAPI) ListEvents(w http.ResponseWriter, r *http.Request) {
+	events, err := h.Queries.ListEvents(r.Context())
+	if err != nil {
+		h.writeDBError(w, err, "Event")
+		return
+	}
+
+	if err := writeJSON(w, http.StatusOK, map[string]interface{}{"events": eventsJSON(events)}); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// CreateEvent creates a new event with a generated ID
+// Route: POST /admin/api/events
+func (h *AdminAPI) CreateEvent(w http.ResponseWriter, r *http.Request) {
+	var in eventInput
+	if err := readJSON(w, r, &in); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+
+	in.Description = strings.TrimSpace(in.Description)
+	if in.Description == "" {
+		h.writeValidationErrors(w, map[string]string{"description": "is required"})
+		return
+	}
+
+	event, err := h.Queries.CreateEvent(r.Context(), database.CreateEventParams{
+		EventID:     fmt.Sprintf("event_%s", ksuid.New().String()),
+		Description: in.Description,
+	})
+	if err != nil {
+		h.writeDBError(w, err, "Event")
+		return
+	}
+
+	h.Log.Printf("Admin created event %s", event.EventID)
+
+	if err := writeJSON(w, http.StatusCreated, eventJSON(event)); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// GetEvent returns an event with its slugs and questions
+// Route: GET /admin/api/events/{eventID}
+func (h *AdminAPI) GetEvent(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+	ctx := r.Context()
+
+	event, err := h.Queries.GetEventByID(ctx, eventID)
+	if err != nil {
+		h.writeDBError(w, err, "Event")
+		return
+	}
+
+	slugs, err := h.Queries.ListSlugsByEventID(ctx, eventID)
+	if err != nil {
+		h.writeDBError(w, err, "Slug")
+		return
+	}
+
+	questions, err := h.Queries.ListQuestionsByEventID(ctx, eventID)
+	if err != nil {
+		h.writeDBError(w, err, "Question")
+		return
+	}
+
+	response := eventJSON(event)
+	response["slugs"] = slugs
+	response["questions"] = questionsJSON(questions)
+
+	if err := writeJSON(w, http.StatusOK, response); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// UpdateEvent updates an event's description
+// Route: PUT /admin/api/events/{eventID}
+func (h *AdminAPI) UpdateEvent(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+
+	var in eventInput
+	if err := readJSON(w, r, &in); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+
+	in.Description = strings.TrimSpace(in.Description)
+	if in.Description == "" {
+		h.writeValidationErrors(w, map[string]string{"description": "is required"})
+		return
+	}
+
+	event, err := h.Queries.UpdateEvent(r.Context(), database.UpdateEventParams{
+		EventID:     eventID,
+		Description: in.Description,
+	})
+	if err != nil {
+		h.writeDBError(w, err, "Event")
+		return
+	}
+
+	h.EventCache.InvalidateEvent(eventID)
+	h.Log.Printf("Admin updated event %s", eventID)
+
+	if err := writeJSON(w, http.StatusOK, eventJSON(event)); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// DeleteEvent deletes an event, cascading to its slugs, questions and responses
+// Route: DELETE /admin/api/events/{eventID}
+func (h *AdminAPI) DeleteEvent(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+
+	rows, err := h.Queries.DeleteEvent(r.Context(), eventID)
+	if err != nil {
+		h.writeDBError(w, err, "Event")
+		return
+	}
+	if rows == 0 {
+		writeError(w, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	h.EventCache.InvalidateEvent(eventID)
+	h.Log.Printf("Admin deleted event %s", eventID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetOnAir marks a question as on air for broadcast graphics, or clears it
+// WebSocket clients subscribed to the event are switched to the new question
+// Route: PUT /admin/api/events/{eventID}/on-air
+func (h *AdminAPI) SetOnAir(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+	ctx := r.Context()
+
+	var in onAirInput
+	if err := readJSON(w, r, &in); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+
+	if _, err := h.Queries.GetEventByID(ctx, eventID); err != nil {
+		h.writeDBError(w, err, "Event")
+		return
+	}
+
+	questionID := sql.NullString{}
+	if in.QuestionID != nil {
+		questionID = sql.NullString{String: *in.QuestionID, Valid: true}
+	}
+
+	event, err := h.Queries.SetEventOnAirQuestion(ctx, database.SetEventOnAirQuestionParams{
+		QuestionID: questionID,
+		EventID:    eventID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// The event exists, so the question isn't one of its questions
+		h.writeValidationErrors(w, map[string]string{"question_id": "is not a question of this event"})
+		return
+	}
+	if err != nil {
+		h.writeDBError(w, err, "Event")
+		return
+	}
+
+	h.Log.Printf("Admin set on-air question for event %s: %v", eventID, nullableString(event.OnAirQuestionID))
+
+	if err := writeJSON(w, http.StatusOK, eventJSON(event)); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// ListSlugs returns all slugs for an event
+// Route: GET /admin/api/events/{eventID}/slugs
+func (h *AdminAPI) ListSlugs(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+	ctx := r.Context()
+
+	if _, err := h.Queries.GetEventByID(ctx, eventID); err != nil {
+		h.writeDBError(w, err, "Event")
+		return
+	}
+
+	slugs, err := h.Queries.ListSlugsByEventID(ctx, eventID)
+	if err != nil {
+		h.writeDBError(w, err, "Slug")
+		return
+	}
+
+	if err := writeJSON(w, http.StatusOK, map[string]interface{}{"slugs": slugs}); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// CreateSlug adds a new traffic-source slug to an event
+// Route: POST /admin/api/events/{eventID}/slugs
+func (h *AdminAPI) CreateSlug(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+	ctx := r.Context()
+
+	var in slugInput
+	if err := readJSON(w, r, &in); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+
+	slug := strings.TrimSpace(in.Slug)
+	if !database.ValidSlug(slug) {
+		h.writeValidationErrors(w, map[string]string{"slug": "must contain only a-z, 0-9 and -"})
+		return
+	}
+	if reservedSlugs[slug] {
+		h.writeValidationErrors(w, map[string]string{"slug": "is reserved"})
+		return
+	}
+
+	if _, err := h.Queries.GetEventByID(ctx, eventID); err != nil {
+		h.writeDBError(w, err, "Event")
+		return
+	}
+
+	created, err := h.Queries.CreateSlug(ctx, database.CreateSlugParams{
+		Slug:    slug,
+		EventID: eventID,
+	})
+	if err != nil {
+		h.writeDBError(w, err, "Slug")
+		return
+	}
+
+	h.EventCache.InvalidateSlug(slug)
+	h.Log.Printf("Admin created slug %s for event %s", slug, eventID)
+
+	if err := writeJSON(w, http.StatusCreated, created); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// DeleteSlug removes a slug and the responses recorded against it
+// Route: DELETE /admin/api/slugs/{slug}
+func (h *AdminAPI) DeleteSlug(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+
+	deleted, err := h.Queries.DeleteSlug(r.Context(), slug)
+	if err != nil {
+		h.writeDBError(w, err, "Slug")
+		return
+	}
+
+	h.EventCache.InvalidateSlug(deleted.Slug)
+	h.Log.Printf("Admin deleted slug %s from event %s", deleted.Slug, deleted.EventID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListQuestions returns all questions for an event in display order
+// Route: GET /admin/api/events/{eventID}/questions
+func (h *AdminAPI) ListQuestions(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+	ctx := r.Context()
+
+	if _, err := h.Queries.GetEventByID(ctx, eventID); err != nil {
+		h.writeDBError(w, err, "Event")
+		return
+	}
+
+	questions, err := h.Queries.ListQuestionsByEventID(ctx, eventID)
+	if err != nil {
+		h.writeDBError(w, err, "Question")
+		return
+	}
+
+	if err := writeJSON(w, http.StatusOK, map[string]interface{}{"questions": questionsJSON(questions)}); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// CreateQuestion appends a question to an event
+// Questions are ordered by KSUID, so new questions always go last
+// Route: POST /admin/api/events/{eventID}/questions
+func (h *AdminAPI) CreateQuestion(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+	ctx := r.Context()
+
+	var in questionInput
+	if err := readJSON(w, r, &in); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+	if errs := in.validate(); len(errs) > 0 {
+		h.writeValidationErrors(w, errs)
+		return
+	}
+
+	if _, err := h.Queries.GetEventByID(ctx, eventID); err != nil {
+		h.writeDBError(w, err, "Event")
+		return
+	}
+
+	question, err := h.Queries.CreateQuestion(ctx, database.CreateQuestionParams{
+		QuestionID:    fmt.Sprintf("question_%s", ksuid.New().String()),
+		EventID:       eventID,
+		BigText:       in.BigText,
+		SmallText:     in.SmallText,
+		ImageFilename: in.ImageFilename,
+		ChoiceA:       in.ChoiceA,
+		ChoiceB:       in.ChoiceB,
+	})
+	if err != nil {
+		h.writeDBError(w, err, "Question")
+		return
+	}
+
+	h.EventCache.InvalidateEvent(eventID)
+	h.Log.Printf("Admin created question %s for event %s", question.QuestionID, eventID)
+
+	if err := writeJSON(w, http.StatusCreated, questionJSON(question)); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// GetQuestion returns a single question
+// Route: GET /admin/api/questions/{questionID}
+func (h *AdminAPI) GetQuestion(w http.ResponseWriter, r *http.Request) {
+	questionID := chi.URLParam(r, "questionID")
+
+	question, err := h.Queries.GetQuestionByID(r.Context(), questionID)
+	if err != nil {
+		h.writeDBError(w, err, "Question")
+		return
+	}
+
+	if err := writeJSON(w, http.StatusOK, questionJSON(question)); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// UpdateQuestion replaces a question's text, image and choices
+// Route: PUT /admin/api/questions/{questionID}
+func (h *AdminAPI) UpdateQuestion(w http.ResponseWriter, r *http.Request) {
+	questionID := chi.URLParam(r, "questionID")
+
+	var in questionInput
+	if err := readJSON(w, r, &in); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+	if errs := in.validate(); len(errs) > 0 {
+		h.writeValidationErrors(w, errs)
+		return
+	}
+
+	question, err := h.Queries.UpdateQuestion(r.Context(), database.UpdateQuestionParams{
+		QuestionID:    questionID,
+		BigText:       in.BigText,
+		SmallText:     in.SmallText,
+		ImageFilename: in.ImageFilename,
+		ChoiceA:       in.ChoiceA,
+		ChoiceB:       in.ChoiceB,
+	})
+	if err != nil {
+		h.writeDBError(w, err, "Question")
+		return
+	}
+
+	h.EventCache.InvalidateEvent(question.EventID)
+	h.Log.Printf("Admin updated question %s", questionID)
+
+	if err := writeJSON(w, http.StatusOK, questionJSON(question)); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// DeleteQuestion removes a question and all its responses
+// Route: DELETE /admin/api/questions/{questionID}
+func (h *AdminAPI) DeleteQuestion(w http.ResponseWriter, r *http.Request) {
+	questionID := chi.URLParam(r, "questionID")
+
+	question, err := h.Queries.DeleteQuestion(r.Context(), questionID)
+	if err != nil {
+		h.writeDBError(w, err, "Question")
+		return
+	}
+
+	h.EventCache.InvalidateEvent(question.EventID)
+	h.Log.Printf("Admin deleted question %s from event %s", questionID, question.EventID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetQuestionResult records (or clears) the official result of a fight
+// Route: PUT /admin/api/questions/{questionID}/result
+func (h *AdminAPI) SetQuestionResult(w http.ResponseWriter, r *http.Request) {
+	questionID := chi.URLParam(r, "questionID")
+
+	var in resultInput
+	if err := readJSON(w, r, &in); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+
+	result := sql.NullString{}
+	if in.Result != nil {
+		if !database.IsValidResult(*in.Result) {
+			h.writeValidationErrors(w, map[string]string{"result": "must be one of a, b, draw, no_contest or null"})
+			return
+		}
+		result = sql.NullString{String: *in.Result, Valid: true}
+	}
+
+	question, err := h.Queries.SetQuestionResult(r.Context(), database.SetQuestionResultParams{
+		QuestionID: questionID,
+		Result:     result,
+	})
+	if err != nil {
+		h.writeDBError(w, err, "Question")
+		return
+	}
+
+	h.EventCache.InvalidateEvent(question.EventID)
+	h.Log.Printf("Admin set result for question %s: %v", questionID, nullableString(question.Result))
+
+	if err := writeJSON(w, http.StatusOK, questionJSON(question)); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// SetQuestionWindow schedules when voting opens and closes for a question
+// Times are RFC 3339 and stored as UTC
+// Route: PUT /admin/api/questions/{questionID}/window
+func (h *AdminAPI) SetQuestionWindow(w http.ResponseWriter, r *http.Request) {
+	questionID := chi.URLParam(r, "questionID")
+
+	var in windowInput
+	if err := readJSON(w, r, &in); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+
+	params := database.SetQuestionWindowParams{QuestionID: questionID}
+	if in.OpensAt != nil {
+		params.OpensAt = sql.NullTime{Time: in.OpensAt.UTC(), Valid: true}
+	}
+	if in.ClosesAt != nil {
+		params.ClosesAt = sql.NullTime{Time: in.ClosesAt.UTC(), Valid: true}
+	}
+	if params.OpensAt.Valid && params.ClosesAt.Valid && params.ClosesAt.Time.Before(params.OpensAt.Time) {
+		h.writeValidationErrors(w, map[string]string{"closes_at": "must not be before opens_at"})
+		return
+	}
+
+	question, err := h.Queries.SetQuestionWindow(r.Context(), params)
+	if err != nil {
+		h.writeDBError(w, err, "Question")
+		return
+	}
+
+	h.EventCache.InvalidateEvent(question.EventID)
+	h.Log.Printf("Admin set voting window for question %s", questionID)
+
+	if err := writeJSON(w, http.StatusOK, questionJSON(question)); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// LockQuestion closes voting for a question immediately
+// Route: POST /admin/api/questions/{questionID}/lock
+func (h *AdminAPI) LockQuestion(w http.ResponseWriter, r *http.Request) {
+	questionID := chi.URLParam(r, "questionID")
+
+	question, err := h.Queries.LockQuestion(r.Context(), questionID)
+	if err != nil {
+		h.writeDBError(w, err, "Question")
+		return
+	}
+
+	h.EventCache.InvalidateEvent(question.EventID)
+	h.Log.Printf("Admin locked question %s", questionID)
+
+	if err := writeJSON(w, http.StatusOK, questionJSON(question)); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// Helper: writeDBError maps database errors to HTTP status codes
+func (h *AdminAPI) writeDBError(w http.ResponseWriter, err error, entity string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeError(w, http.StatusNotFound, entity+" not found")
+	case database.IsUniqueViolation(err):
+		writeError(w, http.StatusConflict, entity+" already exists")
+	case database.IsForeignKeyViolation(err), database.IsCheckViolation(err):
+		writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Invalid %s", strings.ToLower(entity)))
+	default:
+		h.Log.Printf("Admin API database error (%s): %v", entity, err)
+		writeError(w, http.StatusInternalServerError, "Internal Server Error")
+	}
+}
+
+// Helper: eventJSON renders an event with nullable columns as plain JSON values
+func eventJSON(e database.Event) map[string]interface{} {
+	return map[string]interface{}{
+		"event_id":           e.EventID,
+		"description":        e.Description,
+		"created_at":         e.CreatedAt,
+		"on_air_question_id": nullableString(e.OnAirQuestionID),
+	}
+}
+
+func eventsJSON(events []database.Event) []map[string]interface{} {
+	out := make([]map[string]interface{}, len(events))
+	for i, e := range events {
+		out[i] = eventJSON(e)
+	}
+	return out
+}
+
+// Helper: questionJSON renders a question with nullable columns as plain JSON values
+func questionJSON(q database.Question) map[string]interface{} {
+	return map[string]interface{}{
+		"question_id":    q.QuestionID,
+		"event_id":       q.EventID,
+		"big_text":       q.BigText,
+		"small_text":     q.SmallText,
+		"image_filename": q.ImageFilename,
+		"choice_a":       q.ChoiceA,
+		"choice_b":       q.ChoiceB,
+		"result":         nullableString(q.Result),
+		"result_set_at":  nullableTime(q.ResultSetAt),
+		"opens_at":       nullableTime(q.OpensAt),
+		"closes_at":      nullableTime(q.ClosesAt),
+		"is_open":        q.IsOpen(time.Now()),
+	}
+}
+
+func questionsJSON(questions []database.Question) []map[string]interface{} {
+	out := make([]map[string]interface{}, len(questions))
+	for i, q := range questions {
+		out[i] = questionJSON(q)
+	}
+	return out
+}
+
+// Helper: writeValidationErrors writes field errors in a consistent JSON envelope
+func (h *AdminAPI) writeValidationErrors(w http.ResponseWriter, errs map[string]string) {
+	response := map[string]interface{}{
+		"error":  "validation failed",
+		"fields": errs,
+	}
+	if err := writeJSON(w, http.StatusUnprocessableEntity, response); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
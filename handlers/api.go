@@ -2,179 +2,194 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/mrbennbenn/pick6/broadcast"
 	"github.com/mrbennbenn/pick6/database"
+	cache "github.com/patrickmn/go-cache"
 )
 
 type API struct {
-	Queries *database.Queries
-	Log     *log.Logger
-	BaseURL string
+	Queries  *database.Queries
+	Log      *log.Logger
+	BaseURL  string
+	Hub      *broadcast.Hub // Fan-out for question tallies, keyed by question ID
+	OnAirHub *broadcast.Hub // Fan-out for the on-air question, keyed by event ID
+
+	SocketOrigins []string // Host patterns browser pages may open the WebSocket from, empty allows any
+
+	// Rendered /api/events responses, served as-is for ResponseTTL and then
+	// revalidated against the event's version (nil = always revalidate)
+	Responses   *cache.Cache
+	ResponseTTL time.Duration
 }
 
 // GetEvent returns full event state with engagement summary
 // Route: GET /api/events/{eventIDOrSlug}
 func (h *API) GetEvent(w http.ResponseWriter, r *http.Request) {
-	eventIDOrSlug := chi.URLParam(r, "eventID")
-	ctx := r.Context()
-
-	// Resolve event ID
-	eventID, err := h.resolveEventID(ctx, eventIDOrSlug)
-	if err != nil {
-		h.Log.Printf("Error resolving event '%s': %v", eventIDOrSlug, err)
-		writeError(w, http.StatusNotFound, "Event not found")
-		return
-	}
-
-	// Get event metadata
-	event, err := h.Queries.GetEventByID(ctx, eventID)
-	if err != nil {
-		h.Log.Printf("Error getting event: %v", err)
-		writeError(w, http.StatusNotFound, "Event not found")
-		return
-	}
+	key := "event:" + chi.URLParam(r, "eventID")
+	h.serveVersioned(w, r, key, func(ctx context.Context, eventID string) (interface{}, error) {
+		// Get event metadata
+		event, err := h.Queries.GetEventByID(ctx, eventID)
+		if err != nil {
+			h.Log.Printf("Error getting event: %v", err)
+			return nil, &apiError{http.StatusNotFound, "Event not found"}
+		}
 
-	// Get all questions for this event
-	questions, err := h.Queries.ListQuestionsByEventID(ctx, eventID)
-	if err != nil {
-		h.Log.Printf("Error getting questions: %v", err)
-		writeError(w, http.StatusInternalServerError, "Internal Server Error")
-		return
-	}
+		// Get all questions for this event
+		questions, err := h.Queries.ListQuestionsByEventID(ctx, eventID)
+		if err != nil {
+			h.Log.Printf("Error getting questions: %v", err)
+			return nil, err
+		}
 
-	// Get total engagement
-	totalEngagement, err := h.Queries.GetEventEngagementTotal(ctx, eventID)
-	if err != nil {
-		h.Log.Printf("Error getting event engagement: %v", err)
-		writeError(w, http.StatusInternalServerError, "Internal Server Error")
-		return
-	}
+		// Get total engagement
+		totalEngagement, err := h.Queries.GetEventEngagementTotal(ctx, eventID)
+		if err != nil {
+			h.Log.Printf("Error getting event engagement: %v", err)
+			return nil, err
+		}
 
-	// Get engagement by slug
-	bySlugData, err := h.Queries.GetEventEngagementBySlug(ctx, eventID)
-	if err != nil {
-		h.Log.Printf("Error getting engagement by slug: %v", err)
-		writeError(w, http.StatusInternalServerError, "Internal Server Error")
-		return
-	}
+		// Get engagement by slug
+		bySlugData, err := h.Queries.GetEventEngagementBySlug(ctx, eventID)
+		if err != nil {
+			h.Log.Printf("Error getting engagement by slug: %v", err)
+			return nil, err
+		}
 
-	// Build questions summary (with vote counts per question)
-	questionsSummary := []map[string]interface{}{}
-	for i, q := range questions {
-		// Get total votes for this question
-		qEngagement, err := h.Queries.GetQuestionEngagementTotal(ctx, q.QuestionID)
+		// Get vote counts for every question at once
+		questionTotals, err := loadEventQuestionTotals(ctx, h.Queries, eventID, excludeFlagged(r))
 		if err != nil {
 			h.Log.Printf("Error getting question engagement: %v", err)
-			continue
+			return nil, err
 		}
 
-		questionsSummary = append(questionsSummary, map[string]interface{}{
-			"question_id": q.QuestionID,
-			"index":       i + 1,
-			"big_text":    q.BigText,
-			"sessions":    qEngagement.Sessions,
-			"total_votes": qEngagement.TotalVotes,
-		})
-	}
+		// Build questions summary (with vote counts per question)
+		questionsSummary := []map[string]interface{}{}
+		for i, q := range questions {
+			qTotal := questionTotals[q.QuestionID]
+			questionsSummary = append(questionsSummary, map[string]interface{}{
+				"question_id": q.QuestionID,
+				"index":       i + 1,
+				"big_text":    q.BigText,
+				"sessions":    qTotal.Sessions,
+				"total_votes": qTotal.TotalVotes,
+			})
+		}
 
-	// Build response
-	response := map[string]interface{}{
-		"event_id":        event.EventID,
-		"description":     event.Description,
-		"created_at":      event.CreatedAt,
-		"total_questions": len(questions),
-		"engagement": map[string]interface{}{
-			"total": map[string]interface{}{
-				"sessions":    totalEngagement.Sessions,
-				"total_votes": totalEngagement.TotalVotes,
+		// Build response
+		return map[string]interface{}{
+			"event_id":        event.EventID,
+			"description":     event.Description,
+			"created_at":      event.CreatedAt,
+			"total_questions": len(questions),
+			"engagement": map[string]interface{}{
+				"total": map[string]interface{}{
+					"sessions":    totalEngagement.Sessions,
+					"total_votes": totalEngagement.TotalVotes,
+				},
+				"by_slug": transformBySlugToMap(bySlugData),
 			},
-			"by_slug": transformBySlugToMap(bySlugData),
-		},
-		"questions": questionsSummary,
-	}
-
-	if err := writeJSON(w, http.StatusOK, response); err != nil {
-		h.Log.Printf("Error writing JSON response: %v", err)
-	}
+			"questions": questionsSummary,
+		}, nil
+	})
 }
 
 // GetQuestions returns all questions with full engagement for an event
 // Route: GET /api/events/{eventIDOrSlug}/questions
 func (h *API) GetQuestions(w http.ResponseWriter, r *http.Request) {
-	eventIDOrSlug := chi.URLParam(r, "eventID")
-	ctx := r.Context()
-
-	// Resolve event ID
-	eventID, err := h.resolveEventID(ctx, eventIDOrSlug)
-	if err != nil {
-		h.Log.Printf("Error resolving event '%s': %v", eventIDOrSlug, err)
-		writeError(w, http.StatusNotFound, "Event not found")
-		return
-	}
-
-	// Get all questions
-	questions, err := h.Queries.ListQuestionsByEventID(ctx, eventID)
-	if err != nil {
-		h.Log.Printf("Error getting questions: %v", err)
-		writeError(w, http.StatusInternalServerError, "Internal Server Error")
-		return
-	}
+	key := "questions:" + chi.URLParam(r, "eventID")
+	h.serveVersioned(w, r, key, func(ctx context.Context, eventID string) (interface{}, error) {
+		// Get all questions
+		questions, err := h.Queries.ListQuestionsByEventID(ctx, eventID)
+		if err != nil {
+			h.Log.Printf("Error getting questions: %v", err)
+			return nil, err
+		}
 
-	// Build response with full engagement for each question
-	questionsData := []map[string]interface{}{}
-	for i, q := range questions {
-		questionData := h.buildQuestionResponse(ctx, q, i+1)
-		if questionData != nil {
+		// Build response with full engagement for each question
+		questionsData := []map[string]interface{}{}
+		for i, q := range questions {
+			questionData := h.buildQuestionResponse(ctx, q, i+1, excludeFlagged(r))
+			if questionData == nil {
+				// Fail rather than cache a list with questions missing
+				return nil, &apiError{http.StatusInternalServerError, "Error building response"}
+			}
 			questionsData = append(questionsData, questionData)
 		}
-	}
-
-	response := map[string]interface{}{
-		"event_id":  eventID,
-		"questions": questionsData,
-	}
 
-	if err := writeJSON(w, http.StatusOK, response); err != nil {
-		h.Log.Printf("Error writing JSON response: %v", err)
-	}
+		return map[string]interface{}{
+			"event_id":  eventID,
+			"questions": questionsData,
+		}, nil
+	})
 }
 
 // GetQuestion returns a single question with full metadata and engagement
 // Route: GET /api/events/{eventIDOrSlug}/questions/{questionIDOrIndex}
-// This is the primary endpoint for broadcast graphics (poll every 1-2 seconds)
+// Broadcast graphics should prefer the /stream endpoint over polling this
 func (h *API) GetQuestion(w http.ResponseWriter, r *http.Request) {
-	eventIDOrSlug := chi.URLParam(r, "eventID")
 	questionIDOrIndex := chi.URLParam(r, "questionID")
-	ctx := r.Context()
+	key := "question:" + chi.URLParam(r, "eventID") + ":" + questionIDOrIndex
+	h.serveVersioned(w, r, key, func(ctx context.Context, eventID string) (interface{}, error) {
+		// Resolve question
+		question, index, err := h.resolveQuestion(ctx, eventID, questionIDOrIndex)
+		if err != nil {
+			if errors.Is(err, errInvalidQuestionIdentifier) {
+				return nil, &apiError{http.StatusBadRequest, "Invalid question identifier"}
+			}
+			return nil, &apiError{http.StatusNotFound, "Question not found"}
+		}
 
-	// Resolve event ID
-	eventID, err := h.resolveEventID(ctx, eventIDOrSlug)
+		// Build full response
+		response := h.buildQuestionResponse(ctx, question, index, excludeFlagged(r))
+		if response == nil {
+			return nil, &apiError{http.StatusInternalServerError, "Error building response"}
+		}
+		return response, nil
+	})
+}
+
+// Helper: resolveEventID resolves event ID from slug or returns the ID as-is
+func (h *API) resolveEventID(ctx context.Context, eventIDOrSlug string) (string, error) {
+	// Check if it's already an event ID
+	if strings.HasPrefix(eventIDOrSlug, "event_") {
+		return eventIDOrSlug, nil
+	}
+
+	// It's a slug - resolve it
+	event, err := h.Queries.GetEventBySlug(ctx, eventIDOrSlug)
 	if err != nil {
-		h.Log.Printf("Error resolving event '%s': %v", eventIDOrSlug, err)
-		writeError(w, http.StatusNotFound, "Event not found")
-		return
+		return "", err
 	}
 
-	// Resolve question
-	var question database.Question
-	var index int
+	return event.EventID, nil
+}
+
+// errInvalidQuestionIdentifier is returned by resolveQuestion for values that
+// are neither a question ID nor a numeric index
+var errInvalidQuestionIdentifier = errors.New("invalid question identifier")
 
+// Helper: resolveQuestion resolves a question ID or 1-based index within an event
+// Returns the question and its 1-based index
+func (h *API) resolveQuestion(ctx context.Context, eventID, questionIDOrIndex string) (database.Question, int, error) {
 	if strings.HasPrefix(questionIDOrIndex, "question_") {
 		// It's a question ID
-		question, err = h.Queries.GetQuestionByID(ctx, questionIDOrIndex)
+		question, err := h.Queries.GetQuestionByID(ctx, questionIDOrIndex)
 		if err != nil {
 			h.Log.Printf("Error getting question by ID: %v", err)
-			writeError(w, http.StatusNotFound, "Question not found")
-			return
+			return database.Question{}, 0, err
 		}
 
 		// Get the index by counting questions before this one
+		index := 0
 		questions, _ := h.Queries.ListQuestionsByEventID(ctx, eventID)
 		for i, q := range questions {
 			if q.QuestionID == questionIDOrIndex {
@@ -182,86 +197,39 @@ func (h *API) GetQuestion(w http.ResponseWriter, r *http.Request) {
 				break
 			}
 		}
-	} else {
-		// It's a numeric index
-		idx, err := strconv.Atoi(questionIDOrIndex)
-		if err != nil {
-			h.Log.Printf("Invalid question identifier '%s': %v", questionIDOrIndex, err)
-			writeError(w, http.StatusBadRequest, "Invalid question identifier")
-			return
-		}
-
-		question, err = h.Queries.GetQuestionByEventAndIndex(ctx, database.GetQuestionByEventAndIndexParams{
-			EventID:       eventID,
-			QuestionIndex: int64(idx),
-		})
-		if err != nil {
-			h.Log.Printf("Error getting question by index %d: %v", idx, err)
-			writeError(w, http.StatusNotFound, "Question not found")
-			return
-		}
-		index = idx
+		return question, index, nil
 	}
 
-	// Build full response
-	response := h.buildQuestionResponse(ctx, question, index)
-	if response == nil {
-		writeError(w, http.StatusInternalServerError, "Error building response")
-		return
-	}
-
-	if err := writeJSON(w, http.StatusOK, response); err != nil {
-		h.Log.Printf("Error writing JSON response: %v", err)
-	}
-}
-
-// Helper: resolveEventID resolves event ID from slug or returns the ID as-is
-func (h *API) resolveEventID(ctx context.Context, eventIDOrSlug string) (string, error) {
-	// Check if it's already an event ID
-	if strings.HasPrefix(eventIDOrSlug, "event_") {
-		return eventIDOrSlug, nil
+	// It's a numeric index
+	idx, err := strconv.Atoi(questionIDOrIndex)
+	if err != nil {
+		h.Log.Printf("Invalid question identifier '%s': %v", questionIDOrIndex, err)
+		return database.Question{}, 0, errInvalidQuestionIdentifier
 	}
 
-	// It's a slug - resolve it
-	event, err := h.Queries.GetEventBySlug(ctx, eventIDOrSlug)
+	question, err := h.Queries.GetQuestionByEventAndIndex(ctx, database.GetQuestionByEventAndIndexParams{
+		EventID:       eventID,
+		QuestionIndex: int64(idx),
+	})
 	if err != nil {
-		return "", err
+		h.Log.Printf("Error getting question by index %d: %v", idx, err)
+		return database.Question{}, 0, err
 	}
-
-	return event.EventID, nil
+	return question, idx, nil
 }
 
 // Helper: buildQuestionResponse builds a complete question response with engagement
-func (h *API) buildQuestionResponse(ctx context.Context, question database.Question, index int) map[string]interface{} {
-	// Get engagement totals
-	totalEngagement, err := h.Queries.GetQuestionEngagementTotal(ctx, question.QuestionID)
-	if err != nil {
-		h.Log.Printf("Error getting question engagement total: %v", err)
-		return nil
-	}
-
-	// Get engagement by slug
-	bySlugData, err := h.Queries.GetQuestionEngagementBySlug(ctx, question.QuestionID)
+func (h *API) buildQuestionResponse(ctx context.Context, question database.Question, index int, excludeFlagged bool) map[string]interface{} {
+	engagement, err := loadQuestionEngagement(ctx, h.Queries, question.QuestionID, excludeFlagged)
 	if err != nil {
-		h.Log.Printf("Error getting question engagement by slug: %v", err)
+		h.Log.Printf("Error getting question engagement: %v", err)
 		return nil
 	}
 
-	// Calculate percentages
-	percentageA, percentageB := calculatePercentages(totalEngagement.VotesA, totalEngagement.VotesB)
-
 	// Build by_slug with percentages
 	bySlug := make(map[string]interface{})
-	for _, row := range bySlugData {
-		pctA, pctB := calculatePercentages(row.VotesA, row.VotesB)
-		bySlug[row.Slug] = map[string]interface{}{
-			"sessions":     row.Sessions,
-			"total_votes":  row.TotalVotes,
-			"votes_a":      row.VotesA,
-			"votes_b":      row.VotesB,
-			"percentage_a": pctA,
-			"percentage_b": pctB,
-		}
+	for _, row := range engagement.BySlug {
+		bySlug[row.Slug] = row.toMap()
 	}
 
 	return map[string]interface{}{
@@ -273,20 +241,25 @@ func (h *API) buildQuestionResponse(ctx context.Context, question database.Quest
 		"image_url":   h.imageURL(question.ImageFilename),
 		"choice_a":    question.ChoiceA,
 		"choice_b":    question.ChoiceB,
+		"result":      nullableString(question.Result),
+		"opens_at":    nullableTime(question.OpensAt),
+		"closes_at":   nullableTime(question.ClosesAt),
+		"is_open":     question.IsOpen(time.Now()),
 		"engagement": map[string]interface{}{
-			"total": map[string]interface{}{
-				"sessions":     totalEngagement.Sessions,
-				"total_votes":  totalEngagement.TotalVotes,
-				"votes_a":      totalEngagement.VotesA,
-				"votes_b":      totalEngagement.VotesB,
-				"percentage_a": percentageA,
-				"percentage_b": percentageB,
-			},
-			"by_slug": bySlug,
+			"total":            engagement.Total.toMap(),
+			"by_slug":          bySlug,
+			"excludes_flagged": excludeFlagged,
 		},
 	}
 }
 
+// Helper: excludeFlagged reports whether ?exclude_flagged=true was passed
+// Question counts then leave out sessions flagged by fraud scoring
+func excludeFlagged(r *http.Request) bool {
+	exclude, _ := strconv.ParseBool(r.URL.Query().Get("exclude_flagged"))
+	return exclude
+}
+
 // Helper: imageURL constructs full image URL
 func (h *API) imageURL(filename string) string {
 	return fmt.Sprintf("%s/static/images/%s", h.BaseURL, filename)
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mrbennbenn/pick6/database"
+)
+
+// apiResponse is a rendered public API response, cached by its URL parameters
+type apiResponse struct {
+	etag       string
+	body       []byte
+	freshUntil time.Time // Served without touching the database until then
+}
+
+// apiError is returned by response builders to pick the status and message sent
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+// Helper: serveVersioned serves a GET under /api/events/{eventID} with a strong ETag
+// derived from the event's version (GetEventVersion), answering a matching
+// If-None-Match with 304 before anything is built
+// Responses are cached under key for ResponseTTL, then revalidated against the
+// version and only rebuilt when it has moved
+func (h *API) serveVersioned(w http.ResponseWriter, r *http.Request, key string, build func(ctx context.Context, eventID string) (interface{}, error)) {
+	eventIDOrSlug := chi.URLParam(r, "eventID")
+	ctx := r.Context()
+
+	// Fraud scoring flags sessions without touching vote_tallies, so the version
+	// can't tell when these counts change
+	if excludeFlagged(r) {
+		eventID, err := h.resolveEventID(ctx, eventIDOrSlug)
+		if err != nil {
+			h.Log.Printf("Error resolving event '%s': %v", eventIDOrSlug, err)
+			writeError(w, http.StatusNotFound, "Event not found")
+			return
+		}
+		data, err := build(ctx, eventID)
+		if err != nil {
+			h.writeBuildError(w, err)
+			return
+		}
+		if err := writeJSON(w, http.StatusOK, data); err != nil {
+			h.Log.Printf("Error writing JSON response: %v", err)
+		}
+		return
+	}
+
+	var cached *apiResponse
+	if h.Responses != nil {
+		if v, found := h.Responses.Get(key); found {
+			cached = v.(*apiResponse)
+		}
+	}
+	if cached != nil && time.Now().Before(cached.freshUntil) {
+		h.writeVersioned(w, r, cached)
+		return
+	}
+
+	// Resolved every time, a slug can be moved to another event
+	eventID, err := h.resolveEventID(ctx, eventIDOrSlug)
+	if err != nil {
+		h.Log.Printf("Error resolving event '%s': %v", eventIDOrSlug, err)
+		writeError(w, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	version, err := h.Queries.GetEventVersion(ctx, eventID)
+	if err != nil {
+		h.Log.Printf("Error getting event version: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	etag := versionETag(key, version)
+
+	if cached != nil && cached.etag == etag {
+		// Nothing changed, keep serving the same body
+		h.storeVersioned(key, cached.etag, cached.body)
+		h.writeVersioned(w, r, cached)
+		return
+	}
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	data, err := build(ctx, eventID)
+	if err != nil {
+		h.writeBuildError(w, err)
+		return
+	}
+	body, err := json.Marshal(data)
+	if err != nil {
+		h.Log.Printf("Error encoding JSON response: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	h.writeVersioned(w, r, h.storeVersioned(key, etag, append(body, '\n')))
+}
+
+// Helper: storeVersioned caches a response body as fresh for ResponseTTL
+func (h *API) storeVersioned(key, etag string, body []byte) *apiResponse {
+	resp := &apiResponse{
+		etag:       etag,
+		body:       body,
+		freshUntil: time.Now().Add(h.ResponseTTL),
+	}
+	if h.Responses != nil {
+		h.Responses.SetDefault(key, resp)
+	}
+	return resp
+}
+
+// Helper: writeVersioned writes a response, or 304 if the client already has it
+// no-cache makes browsers and proxies revalidate with If-None-Match every time
+func (h *API) writeVersioned(w http.ResponseWriter, r *http.Request, resp *apiResponse) {
+	w.Header().Set("ETag", resp.etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if etagMatches(r.Header.Get("If-None-Match"), resp.etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(resp.body); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// Helper: writeBuildError sends an apiError's status, or 500 for anything else
+func (h *API) writeBuildError(w http.ResponseWriter, err error) {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		writeError(w, apiErr.status, apiErr.message)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, "Internal Server Error")
+}
+
+// Helper: versionETag derives a strong ETag from the response key and event version
+// Deterministic, so every instance hands out the same tag for the same data
+func versionETag(key string, version database.GetEventVersionRow) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%d|%s", key, version.Generation, version.TallyVersion, version.OpenQuestions)))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// Helper: etagMatches reports whether an If-None-Match header lists etag (or is *)
+// Weak comparison, as RFC 9110 requires for If-None-Match
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
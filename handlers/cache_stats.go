@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/mrbennbenn/pick6/database"
+)
+
+// Route: GET /admin/api/cache/stats
+// Counters are per instance and reset on restart
+func (h *AdminAPI) GetCacheStats(w http.ResponseWriter, r *http.Request) {
+	stats := map[string]database.CacheStats{
+		"event": h.EventCache.Stats(),
+	}
+	if h.SessionCache != nil {
+		stats["session"] = h.SessionCacheStats.Snapshot(h.SessionCache.ItemCount())
+	}
+
+	if err := writeJSON(w, http.StatusOK, map[string]interface{}{"caches": stats}); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
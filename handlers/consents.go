@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mrbennbenn/pick6/database"
+	"github.com/segmentio/ksuid"
+)
+
+// Consent purposes, each recorded as its own row per submission
+const (
+	consentPrizeDrawTerms = "prize_draw_terms" // Required to enter the draw
+	consentMarketing      = "marketing"        // Optional opt-in to future contact
+)
+
+// Helper: recordConsents appends the terms and marketing decisions from an info form submission
+// Both are stored, so a later untick is recorded as a withdrawal rather than left at the old opt-in
+func (h *UI) recordConsents(ctx context.Context, eventID, sessionID, ip string, terms, marketing bool) error {
+	decisions := []struct {
+		purpose string
+		granted bool
+	}{
+		{consentPrizeDrawTerms, terms},
+		{consentMarketing, marketing},
+	}
+
+	for _, d := range decisions {
+		err := h.Queries.CreateConsent(ctx, database.CreateConsentParams{
+			ConsentID:     fmt.Sprintf("consent_%s", ksuid.New().String()),
+			SessionID:     sessionID,
+			EventID:       eventID,
+			Purpose:       d.purpose,
+			Granted:       d.granted,
+			PolicyVersion: h.PolicyVersion,
+			IpAddress:     ip,
+		})
+		if err != nil {
+			return fmt.Errorf("record %s consent: %w", d.purpose, err)
+		}
+	}
+	return nil
+}
+
+// ListMarketingContacts exports the sessions whose latest marketing decision for an event is an opt-in
+// Route: GET /admin/api/events/{eventID}/marketing-contacts
+func (h *AdminAPI) ListMarketingContacts(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+	ctx := r.Context()
+
+	if _, err := h.Queries.GetEventByID(ctx, eventID); err != nil {
+		h.writeDBError(w, err, "Event")
+		return
+	}
+
+	contacts, err := h.Queries.ListMarketingContacts(ctx, eventID)
+	if err != nil {
+		h.writeDBError(w, err, "Contact")
+		return
+	}
+
+	out := make([]map[string]interface{}, len(contacts))
+	for i, c := range contacts {
+		out[i] = map[string]interface{}{
+			"session_id":     c.SessionID,
+			"name":           c.Name.String,
+			"email":          c.Email.String,
+			"mobile":         c.Mobile.String,
+			"marketing":      true,
+			"policy_version": c.PolicyVersion,
+			"opted_in_at":    c.OptedInAt,
+		}
+	}
+
+	h.Log.Printf("Admin exported %d marketing contacts for event %s", len(out), eventID)
+
+	response := map[string]interface{}{
+		"event_id": eventID,
+		"contacts": out,
+	}
+	if err := writeJSON(w, http.StatusOK, response); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
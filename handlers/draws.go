@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mrbennbenn/pick6/database"
+	"github.com/mrbennbenn/pick6/draw"
+	"github.com/segmentio/ksuid"
+)
+
+const (
+	defaultDrawAlternates = 3
+	maxDrawAlternates     = 20
+)
+
+// drawInput has no seed: it always comes from crypto/rand, so whoever runs the
+// draw can't try seeds until one picks the winner they want
+type drawInput struct {
+	Alternates   *int    `json:"alternates"`   // Defaults to defaultDrawAlternates
+	PerfectOnly  *bool   `json:"perfect_only"` // Defaults to false, a redraw inherits it
+	RedrawOf     *string `json:"redraw_of"`
+	RedrawReason string  `json:"redraw_reason"`
+}
+
+// CreateDraw picks a prize draw winner and alternates from eligible sessions
+// A redraw excludes every winner earlier in its chain and must give a reason,
+// it keeps the perfect_only rule of the draw it replaces, a different one is rejected
+// Route: POST /admin/api/events/{eventID}/draws
+func (h *AdminAPI) CreateDraw(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+	ctx := r.Context()
+
+	var in drawInput
+	if err := readJSON(w, r, &in); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+
+	alternates := defaultDrawAlternates
+	if in.Alternates != nil {
+		alternates = *in.Alternates
+	}
+
+	errs := make(map[string]string)
+	if alternates < 0 || alternates > maxDrawAlternates {
+		errs["alternates"] = fmt.Sprintf("must be between 0 and %d", maxDrawAlternates)
+	}
+	in.RedrawReason = strings.TrimSpace(in.RedrawReason)
+	if in.RedrawOf != nil && in.RedrawReason == "" {
+		errs["redraw_reason"] = "is required for a redraw"
+	}
+	if in.RedrawOf == nil && in.RedrawReason != "" {
+		errs["redraw_of"] = "is required with a redraw reason"
+	}
+	if len(errs) > 0 {
+		h.writeValidationErrors(w, errs)
+		return
+	}
+
+	if _, err := h.Queries.GetEventByID(ctx, eventID); err != nil {
+		h.writeDBError(w, err, "Event")
+		return
+	}
+
+	params := database.CreateDrawParams{
+		DrawID:      fmt.Sprintf("draw_%s", ksuid.New().String()),
+		EventID:     eventID,
+		Algorithm:   draw.Algorithm,
+		PerfectOnly: in.PerfectOnly != nil && *in.PerfectOnly,
+		Excluded:    []string{},
+	}
+
+	if in.RedrawOf != nil {
+		previous, err := h.Queries.GetDraw(ctx, *in.RedrawOf)
+		if err != nil {
+			h.writeDBError(w, err, "Draw")
+			return
+		}
+		if previous.EventID != eventID {
+			h.writeValidationErrors(w, map[string]string{"redraw_of": "belongs to a different event"})
+			return
+		}
+		if in.PerfectOnly != nil && *in.PerfectOnly != previous.PerfectOnly {
+			// A redraw replaces a winner under the same rules, it can't change who was eligible
+			h.writeValidationErrors(w, map[string]string{"perfect_only": fmt.Sprintf("must match the draw being replaced (%t), or be omitted", previous.PerfectOnly)})
+			return
+		}
+
+		// Excluded accumulates along the chain, so one hop covers every earlier winner
+		params.Excluded = draw.Exclusions(previous.Excluded, previous.WinnerSessionID.String)
+		params.PerfectOnly = previous.PerfectOnly
+		params.RedrawOf = sql.NullString{String: previous.DrawID, Valid: true}
+		params.RedrawReason = sql.NullString{String: in.RedrawReason, Valid: true}
+	}
+
+	if params.PerfectOnly {
+		summary, err := h.Queries.GetEventResultSummary(ctx, eventID)
+		if err != nil {
+			h.writeDBError(w, err, "Event")
+			return
+		}
+		if summary.QuestionsScorable == 0 {
+			h.writeValidationErrors(w, map[string]string{"perfect_only": "needs at least one fight with a winner recorded"})
+			return
+		}
+	}
+
+	seed, err := draw.NewSeed()
+	if err != nil {
+		h.Log.Printf("Error generating draw seed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	params.Seed = seed
+
+	entrants, err := h.Queries.ListDrawEntrants(ctx, database.ListDrawEntrantsParams{
+		EventID:     eventID,
+		Excluded:    params.Excluded,
+		PerfectOnly: params.PerfectOnly,
+	})
+	if err != nil {
+		h.writeDBError(w, err, "Draw")
+		return
+	}
+
+	result := draw.Select(entrants, params.Seed, alternates)
+	params.EligibleCount = int32(len(entrants))
+	params.EligibleDigest = draw.Digest(entrants)
+	params.WinnerSessionID = sql.NullString{String: result.Winner, Valid: result.Winner != ""}
+	params.Alternates = result.Alternates
+
+	created, err := h.Queries.CreateDraw(ctx, params)
+	if err != nil {
+		h.writeDBError(w, err, "Draw")
+		return
+	}
+
+	h.Log.Printf("Admin created draw %s for event %s: seed=%d eligible=%d winner=%s",
+		created.DrawID, eventID, created.Seed, created.EligibleCount, created.WinnerSessionID.String)
+
+	response, err := h.drawWithContacts(ctx, created)
+	if err != nil {
+		h.writeDBError(w, err, "Session")
+		return
+	}
+
+	if err := writeJSON(w, http.StatusCreated, response); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// ListDraws returns every draw for an event, oldest first
+// Route: GET /admin/api/events/{eventID}/draws
+func (h *AdminAPI) ListDraws(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+	ctx := r.Context()
+
+	if _, err := h.Queries.GetEventByID(ctx, eventID); err != nil {
+		h.writeDBError(w, err, "Event")
+		return
+	}
+
+	draws, err := h.Queries.ListDrawsByEvent(ctx, eventID)
+	if err != nil {
+		h.writeDBError(w, err, "Draw")
+		return
+	}
+
+	out := make([]map[string]interface{}, len(draws))
+	for i, d := range draws {
+		out[i] = drawJSON(d)
+	}
+
+	if err := writeJSON(w, http.StatusOK, map[string]interface{}{"draws": out}); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// GetDraw returns a single draw with the winner's and alternates' contact details
+// Route: GET /admin/api/draws/{drawID}
+func (h *AdminAPI) GetDraw(w http.ResponseWriter, r *http.Request) {
+	drawID := chi.URLParam(r, "drawID")
+	ctx := r.Context()
+
+	d, err := h.Queries.GetDraw(ctx, drawID)
+	if err != nil {
+		h.writeDBError(w, err, "Draw")
+		return
+	}
+
+	response, err := h.drawWithContacts(ctx, d)
+	if err != nil {
+		h.writeDBError(w, err, "Session")
+		return
+	}
+
+	if err := writeJSON(w, http.StatusOK, response); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// VerifyDraw re-runs a stored draw and reports whether it reproduces
+// The digest check shows whether the eligible pool is still the same,
+// e.g. it changes if a result is corrected after the draw
+// Route: GET /admin/api/draws/{drawID}/verify
+func (h *AdminAPI) VerifyDraw(w http.ResponseWriter, r *http.Request) {
+	drawID := chi.URLParam(r, "drawID")
+	ctx := r.Context()
+
+	d, err := h.Queries.GetDraw(ctx, drawID)
+	if err != nil {
+		h.writeDBError(w, err, "Draw")
+		return
+	}
+
+	if d.Algorithm != draw.Algorithm {
+		writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Draw used algorithm %s, this build only runs %s", d.Algorithm, draw.Algorithm))
+		return
+	}
+
+	entrants, err := h.Queries.ListDrawEntrants(ctx, database.ListDrawEntrantsParams{
+		EventID:     d.EventID,
+		Excluded:    d.Excluded,
+		PerfectOnly: d.PerfectOnly,
+	})
+	if err != nil {
+		h.writeDBError(w, err, "Draw")
+		return
+	}
+
+	result := draw.Select(entrants, d.Seed, len(d.Alternates))
+	digestMatches := draw.Digest(entrants) == d.EligibleDigest
+	winnerMatches := result.Winner == d.WinnerSessionID.String
+	alternatesMatch := slices.Equal(result.Alternates, d.Alternates)
+
+	response := map[string]interface{}{
+		"draw_id":               d.DrawID,
+		"algorithm":             d.Algorithm,
+		"seed":                  d.Seed,
+		"eligible_count":        d.EligibleCount,
+		"eligible_count_now":    len(entrants),
+		"digest_matches":        digestMatches,
+		"winner_matches":        winnerMatches,
+		"alternates_match":      alternatesMatch,
+		"verified":              digestMatches && winnerMatches && alternatesMatch,
+		"recomputed_winner":     result.Winner,
+		"recomputed_alternates": result.Alternates,
+	}
+
+	if err := writeJSON(w, http.StatusOK, response); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// Helper: drawWithContacts adds name, email and mobile for the winner and alternates
+// so the organiser can get in touch without a second lookup
+func (h *AdminAPI) drawWithContacts(ctx context.Context, d database.Draw) (map[string]interface{}, error) {
+	response := drawJSON(d)
+
+	var winner interface{}
+	if d.WinnerSessionID.Valid {
+		contact, err := h.drawContact(ctx, d.WinnerSessionID.String)
+		if err != nil {
+			return nil, err
+		}
+		winner = contact
+	}
+	response["winner"] = winner
+
+	alternates := make([]map[string]interface{}, len(d.Alternates))
+	for i, sessionID := range d.Alternates {
+		contact, err := h.drawContact(ctx, sessionID)
+		if err != nil {
+			return nil, err
+		}
+		alternates[i] = contact
+	}
+	response["alternate_contacts"] = alternates
+
+	return response, nil
+}
+
+// Helper: drawContact looks up one picked session's contact details
+// Draws are an audit record and keep session IDs after a GDPR erasure deletes the
+// session, so a missing row renders as erased rather than failing the request
+func (h *AdminAPI) drawContact(ctx context.Context, sessionID string) (map[string]interface{}, error) {
+	session, err := h.Queries.GetSession(ctx, sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return map[string]interface{}{
+			"session_id": sessionID,
+			"name":       nil,
+			"email":      nil,
+			"mobile":     nil,
+			"erased":     true,
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	contact := sessionContactJSON(session)
+	contact["erased"] = false
+	return contact, nil
+}
+
+// Helper: drawJSON renders a draw with nullable columns as plain JSON values
+func drawJSON(d database.Draw) map[string]interface{} {
+	return map[string]interface{}{
+		"draw_id":           d.DrawID,
+		"event_id":          d.EventID,
+		"algorithm":         d.Algorithm,
+		"seed":              d.Seed,
+		"perfect_only":      d.PerfectOnly,
+		"excluded":          d.Excluded,
+		"eligible_count":    d.EligibleCount,
+		"eligible_digest":   d.EligibleDigest,
+		"winner_session_id": nullableString(d.WinnerSessionID),
+		"alternates":        d.Alternates,
+		"redraw_of":         nullableString(d.RedrawOf),
+		"redraw_reason":     nullableString(d.RedrawReason),
+		"created_at":        d.CreatedAt,
+	}
+}
+
+func sessionContactJSON(s database.Session) map[string]interface{} {
+	return map[string]interface{}{
+		"session_id": s.SessionID,
+		"name":       s.Name, // pii.String renders as a string or null
+		"email":      s.Email,
+		"mobile":     s.Mobile,
+	}
+}
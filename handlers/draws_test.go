@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Every case here is rejected before the database is touched
+func TestCreateDrawRejects(t *testing.T) {
+	h := &AdminAPI{Log: log.New(io.Discard, "", 0)}
+	router := chi.NewRouter()
+	router.Post("/admin/api/events/{eventID}/draws", h.CreateDraw)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		field  string // Expected key in the 422 fields
+	}{
+		{"caller-chosen seed", `{"seed":42}`, http.StatusBadRequest, ""},
+		{"malformed", `{"alternates":`, http.StatusBadRequest, ""},
+		{"too many alternates", `{"alternates":21}`, http.StatusUnprocessableEntity, "alternates"},
+		{"negative alternates", `{"alternates":-1}`, http.StatusUnprocessableEntity, "alternates"},
+		{"redraw without reason", `{"redraw_of":"draw_x"}`, http.StatusUnprocessableEntity, "redraw_reason"},
+		{"blank reason", `{"redraw_of":"draw_x","redraw_reason":"  "}`, http.StatusUnprocessableEntity, "redraw_reason"},
+		{"reason without redraw", `{"redraw_reason":"Winner unreachable"}`, http.StatusUnprocessableEntity, "redraw_of"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/admin/api/events/event_x/draws", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.field == "" {
+				return
+			}
+			var body struct {
+				Fields map[string]string `json:"fields"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if _, ok := body.Fields[tt.field]; !ok {
+				t.Fatalf("fields %v, want %q", body.Fields, tt.field)
+			}
+		})
+	}
+}
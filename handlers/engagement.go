@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mrbennbenn/pick6/database"
+)
+
+// voteCounts holds vote counts and percentages for a question, either in
+// total or for a single slug
+type voteCounts struct {
+	Slug        string
+	Sessions    int64
+	TotalVotes  int64
+	VotesA      int64
+	VotesB      int64
+	PercentageA float64
+	PercentageB float64
+}
+
+// questionEngagement is the engagement data behind API.buildQuestionResponse
+// Shared with the admin console so both show identical numbers
+type questionEngagement struct {
+	Total  voteCounts
+	BySlug []voteCounts // Ordered by slug
+}
+
+// loadQuestionEngagement fetches total and per-slug vote counts for a question
+// Both come from vote_tallies, so the cost is independent of the number of votes
+// With excludeFlagged, votes from sessions flagged by fraud scoring are subtracted
+func loadQuestionEngagement(ctx context.Context, queries *database.Queries, questionID string, excludeFlagged bool) (*questionEngagement, error) {
+	// Get engagement totals
+	total, err := queries.GetQuestionEngagementTotal(ctx, questionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get question engagement total: %w", err)
+	}
+
+	// Get engagement by slug
+	bySlugRows, err := queries.GetQuestionEngagementBySlug(ctx, questionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get question engagement by slug: %w", err)
+	}
+
+	engagement := &questionEngagement{
+		Total:  newVoteCounts("", total.Sessions, total.TotalVotes, total.VotesA, total.VotesB),
+		BySlug: make([]voteCounts, 0, len(bySlugRows)),
+	}
+	for _, row := range bySlugRows {
+		engagement.BySlug = append(engagement.BySlug,
+			newVoteCounts(row.Slug, row.Sessions, row.TotalVotes, row.VotesA, row.VotesB))
+	}
+
+	if excludeFlagged {
+		if err := engagement.subtractFlagged(ctx, queries, questionID); err != nil {
+			return nil, err
+		}
+	}
+
+	return engagement, nil
+}
+
+// subtractFlagged removes flagged sessions' votes from the counts
+// Flagged sessions are few, so this stays cheap next to the tallies
+func (e *questionEngagement) subtractFlagged(ctx context.Context, queries *database.Queries, questionID string) error {
+	flaggedRows, err := queries.ListFlaggedVotesBySlug(ctx, questionID)
+	if err != nil {
+		return fmt.Errorf("failed to get flagged votes: %w", err)
+	}
+	if len(flaggedRows) == 0 {
+		return nil
+	}
+
+	flagged := make(map[string]database.ListFlaggedVotesBySlugRow, len(flaggedRows))
+	for _, row := range flaggedRows {
+		flagged[row.Slug] = row
+	}
+
+	var votesA, votesB int64
+	for i, row := range e.BySlug {
+		f := flagged[row.Slug]
+		a := max(row.VotesA-f.VotesA, 0)
+		b := max(row.VotesB-f.VotesB, 0)
+		// One response per session, so votes are sessions
+		e.BySlug[i] = newVoteCounts(row.Slug, a+b, a+b, a, b)
+		votesA += a
+		votesB += b
+	}
+	e.Total = newVoteCounts("", votesA+votesB, votesA+votesB, votesA, votesB)
+	return nil
+}
+
+// loadEventQuestionTotals returns each question's total counts, keyed by question ID
+// Two queries for the whole event (one without excludeFlagged), where calling
+// loadQuestionEngagement per question would cost two or three each
+// Questions with no votes are missing from the map, their zero value is correct
+func loadEventQuestionTotals(ctx context.Context, queries *database.Queries, eventID string, excludeFlagged bool) (map[string]voteCounts, error) {
+	tallies, err := queries.ListQuestionTalliesByEvent(ctx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list question tallies: %w", err)
+	}
+
+	// Flagged votes by question and slug, clamped per slug like subtractFlagged
+	flagged := make(map[[2]string]database.ListFlaggedVotesByEventRow)
+	if excludeFlagged {
+		flaggedRows, err := queries.ListFlaggedVotesByEvent(ctx, eventID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get flagged votes: %w", err)
+		}
+		for _, row := range flaggedRows {
+			flagged[[2]string{row.QuestionID, row.Slug}] = row
+		}
+	}
+
+	votes := make(map[string][2]int64)
+	for _, row := range tallies {
+		f := flagged[[2]string{row.QuestionID, row.Slug}]
+		v := votes[row.QuestionID]
+		v[0] += max(row.VotesA-f.VotesA, 0)
+		v[1] += max(row.VotesB-f.VotesB, 0)
+		votes[row.QuestionID] = v
+	}
+
+	totals := make(map[string]voteCounts, len(votes))
+	for questionID, v := range votes {
+		// One response per session, so votes are sessions
+		totals[questionID] = newVoteCounts("", v[0]+v[1], v[0]+v[1], v[0], v[1])
+	}
+	return totals, nil
+}
+
+// sumSlugs adds up the counts for the given slugs, ignoring slugs with no votes
+func (e *questionEngagement) sumSlugs(slugs []string) voteCounts {
+	include := make(map[string]bool, len(slugs))
+	for _, slug := range slugs {
+		include[slug] = true
+	}
+
+	var sessions, votesA, votesB int64
+	for _, row := range e.BySlug {
+		if include[row.Slug] {
+			sessions += row.Sessions
+			votesA += row.VotesA
+			votesB += row.VotesB
+		}
+	}
+	return newVoteCounts("", sessions, votesA+votesB, votesA, votesB)
+}
+
+func newVoteCounts(slug string, sessions, totalVotes, votesA, votesB int64) voteCounts {
+	pctA, pctB := calculatePercentages(votesA, votesB)
+	return voteCounts{
+		Slug:        slug,
+		Sessions:    sessions,
+		TotalVotes:  totalVotes,
+		VotesA:      votesA,
+		VotesB:      votesB,
+		PercentageA: pctA,
+		PercentageB: pctB,
+	}
+}
+
+// toMap renders counts in the public API's JSON shape
+func (c voteCounts) toMap() map[string]interface{} {
+	return map[string]interface{}{
+		"sessions":     c.Sessions,
+		"total_votes":  c.TotalVotes,
+		"votes_a":      c.VotesA,
+		"votes_b":      c.VotesB,
+		"percentage_a": c.PercentageA,
+		"percentage_b": c.PercentageB,
+	}
+}
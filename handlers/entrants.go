@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mrbennbenn/pick6/database"
+	"github.com/mrbennbenn/pick6/database/pii"
+	"github.com/segmentio/ksuid"
+)
+
+// entrantClaim is the outcome of linking a session to a prize entrant
+type entrantClaim int
+
+const (
+	entrantCreated   entrantClaim = iota // First entry with these details
+	entrantUnchanged                     // Session re-submitted (or corrected) its own entry
+	entrantMerged                        // Existing entrant on a new session, e.g. after clearing cookies
+)
+
+// errEntrantConflict means the details belong to someone already entered, and
+// merging would tie two different entries together. It also covers an entrant
+// matching only the email or only the mobile: merging on one field would let
+// anyone who knows a fan's email attach their own session to the fan's entry
+var errEntrantConflict = errors.New("contact details already belong to another entrant")
+
+// Helper: saveEntry links a session to the entrant for its email/mobile in an event
+// and saves the session's details, in one transaction so a failed save never
+// leaves a link to details that weren't stored
+// Entrants are unique per email and per mobile blind index, so the draw counts each person once
+func (h *UI) saveEntry(ctx context.Context, eventID string, session database.UpsertSessionParams) (entrantClaim, error) {
+	// Two sessions can race to create the same entrant, the loser retries and merges
+	for attempt := 1; ; attempt++ {
+		claim, err := h.trySaveEntry(ctx, eventID, session)
+		if database.IsUniqueViolation(err) && attempt < 2 {
+			continue
+		}
+		if database.IsUniqueViolation(err) {
+			return 0, errEntrantConflict
+		}
+		return claim, err
+	}
+}
+
+func (h *UI) trySaveEntry(ctx context.Context, eventID string, session database.UpsertSessionParams) (entrantClaim, error) {
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin entry: %w", err)
+	}
+	defer tx.Rollback()
+	queries := h.Queries.WithTx(tx)
+
+	claim, err := claimEntrant(ctx, queries, eventID, session.SessionID, session.Email.String, session.Mobile.String)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := queries.UpsertSession(ctx, session); err != nil {
+		return 0, fmt.Errorf("save session: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit entry: %w", err)
+	}
+	return claim, nil
+}
+
+func claimEntrant(ctx context.Context, queries *database.Queries, eventID, sessionID, email, mobile string) (entrantClaim, error) {
+	current, err := queries.GetEntrantBySession(ctx, database.GetEntrantBySessionParams{
+		EventID:   eventID,
+		SessionID: sessionID,
+	})
+	hasCurrent := err == nil
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("get entrant by session: %w", err)
+	}
+
+	emailIndex := sql.NullString{String: pii.EmailIndex(email), Valid: true}
+	mobileIndex := sql.NullString{String: pii.MobileIndex(mobile), Valid: true}
+
+	matches, err := queries.ListEntrantsByContact(ctx, database.ListEntrantsByContactParams{
+		EventID:     eventID,
+		EmailIndex:  emailIndex,
+		MobileIndex: mobileIndex,
+		Email:       email,
+		Mobile:      sql.NullString{String: mobile, Valid: true},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("list entrants by contact: %w", err)
+	}
+
+	var others []database.Entrant
+	for _, entrant := range matches {
+		if !hasCurrent || entrant.EntrantID != current.EntrantID {
+			others = append(others, entrant)
+		}
+	}
+
+	switch {
+	case len(others) > 1, len(others) == 1 && hasCurrent:
+		// Email and mobile belong to different people, or this session is already
+		// someone else's entry
+		return 0, errEntrantConflict
+
+	case len(others) == 1 && !entrantMatchesBoth(others[0], email, mobile):
+		// Only one of the details matches, left for the organiser to review
+		return 0, errEntrantConflict
+
+	case len(others) == 1:
+		if err := queries.LinkEntrantSession(ctx, database.LinkEntrantSessionParams{
+			EventID:   eventID,
+			SessionID: sessionID,
+			EntrantID: others[0].EntrantID,
+		}); err != nil {
+			return 0, fmt.Errorf("link entrant session: %w", err)
+		}
+		return entrantMerged, nil
+
+	case hasCurrent:
+		// Only this session's own entrant matches (or nothing does), so treat it as a correction
+		if _, err := queries.UpdateEntrantContact(ctx, database.UpdateEntrantContactParams{
+			EmailIndex:  emailIndex,
+			MobileIndex: mobileIndex,
+			EntrantID:   current.EntrantID,
+		}); err != nil {
+			return 0, fmt.Errorf("update entrant contact: %w", err)
+		}
+		return entrantUnchanged, nil
+	}
+
+	entrant, err := queries.CreateEntrant(ctx, database.CreateEntrantParams{
+		EntrantID:   fmt.Sprintf("entrant_%s", ksuid.New().String()),
+		EventID:     eventID,
+		EmailIndex:  emailIndex,
+		MobileIndex: mobileIndex,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("create entrant: %w", err)
+	}
+	if err := queries.LinkEntrantSession(ctx, database.LinkEntrantSessionParams{
+		EventID:   eventID,
+		SessionID: sessionID,
+		EntrantID: entrant.EntrantID,
+	}); err != nil {
+		return 0, fmt.Errorf("link entrant session: %w", err)
+	}
+	return entrantCreated, nil
+}
+
+// Helper: entrantMatchesBoth reports whether an entrant holds both the email and the
+// mobile, by blind index or, before the backfill, by plaintext
+func entrantMatchesBoth(e database.Entrant, email, mobile string) bool {
+	emailMatch := (e.EmailIndex.Valid && e.EmailIndex.String == pii.EmailIndex(email)) ||
+		(e.EmailNormalized.Valid && e.EmailNormalized.String == pii.NormalizeEmail(email))
+	mobileMatch := (e.MobileIndex.Valid && e.MobileIndex.String == pii.MobileIndex(mobile)) ||
+		(e.Mobile.Valid && e.Mobile.String == mobile)
+	return emailMatch && mobileMatch
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/mrbennbenn/pick6/database"
+	"github.com/mrbennbenn/pick6/database/pii"
+)
+
+func TestEntrantMatchesBoth(t *testing.T) {
+	value := func(s string) sql.NullString { return sql.NullString{String: s, Valid: true} }
+	indexed := database.Entrant{
+		EmailIndex:  value(pii.EmailIndex("fan@gmail.com")),
+		MobileIndex: value(pii.MobileIndex("+447700900123")),
+	}
+	// Entered before the blind index backfill
+	plaintext := database.Entrant{
+		EmailNormalized: value("fan@gmail.com"),
+		Mobile:          value("+447700900123"),
+	}
+
+	tests := []struct {
+		name   string
+		email  string
+		mobile string
+		want   bool
+	}{
+		{"same details", "fan@gmail.com", "+447700900123", true},
+		{"same person, new spelling", "F.an+tk4@gmail.com", "+447700900123", true},
+		{"email only", "fan@gmail.com", "+447700900999", false},
+		{"mobile only", "someone@example.com", "+447700900123", false},
+	}
+	for _, tt := range tests {
+		for kind, entrant := range map[string]database.Entrant{"indexed": indexed, "plaintext": plaintext} {
+			if got := entrantMatchesBoth(entrant, tt.email, tt.mobile); got != tt.want {
+				t.Errorf("%s (%s): entrantMatchesBoth = %v, want %v", tt.name, kind, got, tt.want)
+			}
+		}
+	}
+}
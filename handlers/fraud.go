@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mrbennbenn/pick6/fraud"
+)
+
+// ListFlaggedClusters reports groups of sessions sharing an IP hash and user
+// agent in which at least one session was flagged by fraud scoring
+// Route: GET /admin/api/events/{eventID}/fraud/clusters
+func (h *AdminAPI) ListFlaggedClusters(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+	ctx := r.Context()
+
+	if _, err := h.Queries.GetEventByID(ctx, eventID); err != nil {
+		h.writeDBError(w, err, "Event")
+		return
+	}
+
+	clusters, err := h.Queries.ListFlaggedClusters(ctx, eventID)
+	if err != nil {
+		h.writeDBError(w, err, "Cluster")
+		return
+	}
+
+	out := make([]map[string]interface{}, len(clusters))
+	for i, c := range clusters {
+		out[i] = map[string]interface{}{
+			"ip_hash":            c.IpHash,
+			"user_agent":         c.UserAgent,
+			"sessions":           c.Sessions,
+			"flagged_sessions":   c.FlaggedSessions,
+			"votes":              c.Votes,
+			"max_score":          c.MaxScore,
+			"first_seen":         c.FirstSeen,
+			"last_seen":          c.LastSeen,
+			"sample_session_ids": c.SampleSessionIds,
+		}
+	}
+
+	response := map[string]interface{}{
+		"event_id":       eventID,
+		"flag_threshold": fraud.FlagThreshold,
+		"clusters":       out,
+	}
+	if err := writeJSON(w, http.StatusOK, response); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
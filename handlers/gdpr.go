@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mrbennbenn/pick6/gdpr"
+)
+
+// Subject details travel in the POST body rather than the URL, so they stay out of request logs
+type subjectInput struct {
+	Email  string `json:"email"`
+	Mobile string `json:"mobile"`
+	Mode   string `json:"mode"` // Erase only: "erase" (default) or "anonymise"
+}
+
+// ExportSubject returns everything held about a data subject (access request)
+// Route: POST /admin/api/gdpr/export
+func (h *AdminAPI) ExportSubject(w http.ResponseWriter, r *http.Request) {
+	var in subjectInput
+	if err := readJSON(w, r, &in); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+
+	subject, err := gdpr.NewSubject(in.Email, in.Mobile)
+	if err != nil {
+		h.writeValidationErrors(w, map[string]string{"email": err.Error()})
+		return
+	}
+
+	export, err := gdpr.Find(r.Context(), h.Queries, subject)
+	if err != nil {
+		h.writeDBError(w, err, "Subject")
+		return
+	}
+
+	h.Log.Printf("Admin exported data subject: %d sessions", len(export.Sessions))
+
+	w.Header().Set("Content-Disposition", `attachment; filename="subject-export.json"`)
+	if err := writeJSON(w, http.StatusOK, export); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// EraseSubject deletes or anonymises a data subject's personal data (erasure request)
+// Route: POST /admin/api/gdpr/erase
+func (h *AdminAPI) EraseSubject(w http.ResponseWriter, r *http.Request) {
+	var in subjectInput
+	if err := readJSON(w, r, &in); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+
+	subject, err := gdpr.NewSubject(in.Email, in.Mobile)
+	if err != nil {
+		h.writeValidationErrors(w, map[string]string{"email": err.Error()})
+		return
+	}
+	mode := gdpr.ModeErase
+	if in.Mode != "" {
+		mode = gdpr.Mode(in.Mode)
+	}
+	if mode != gdpr.ModeErase && mode != gdpr.ModeAnonymise {
+		h.writeValidationErrors(w, map[string]string{"mode": `must be "erase" or "anonymise"`})
+		return
+	}
+
+	result, err := gdpr.Erase(r.Context(), h.DB, subject, mode)
+	if err != nil {
+		h.writeDBError(w, err, "Subject")
+		return
+	}
+
+	h.Log.Printf("Admin %s data subject: %d sessions, %d responses", mode, result.SessionsErased, result.ResponsesRemoved)
+
+	if err := writeJSON(w, http.StatusOK, result); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
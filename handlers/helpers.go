@@ -1,13 +1,17 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/mail"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/mrbennbenn/pick6/database"
+	"github.com/mrbennbenn/pick6/fraud"
 	"github.com/mrbennbenn/pick6/templates"
 	"github.com/nyaruka/phonenumbers"
 )
@@ -36,6 +40,7 @@ func buildExistingAnswersMap(responses []database.Response) map[string]string {
 
 // convertToTemplateQuestions converts database.Question to templates.Question
 func convertToTemplateQuestions(dbQuestions []database.Question) []templates.Question {
+	now := time.Now()
 	questions := make([]templates.Question, len(dbQuestions))
 	for i, q := range dbQuestions {
 		questions[i] = templates.Question{
@@ -45,11 +50,97 @@ func convertToTemplateQuestions(dbQuestions []database.Question) []templates.Que
 			ImageFilename: q.ImageFilename,
 			ChoiceA:       q.ChoiceA,
 			ChoiceB:       q.ChoiceB,
+			Locked:        !q.IsOpen(now),
 		}
 	}
 	return questions
 }
 
+// nextStepURL returns the page after question {order}: the next question,
+// or the info form after the last one
+func nextStepURL(slug string, order, total int) string {
+	if order >= total {
+		return fmt.Sprintf("/%s/submit-info", slug)
+	}
+	return fmt.Sprintf("/%s/question/%d", slug, order+1)
+}
+
+// buildResultsViewModel scores the user's picks against official results
+// Returns nil until at least one question has a result
+func buildResultsViewModel(questions []database.Question, answers map[string]string) *templates.ResultsViewModel {
+	hasResults := false
+	for _, q := range questions {
+		if q.HasResult() {
+			hasResults = true
+			break
+		}
+	}
+	if !hasResults {
+		return nil
+	}
+
+	vm := &templates.ResultsViewModel{
+		Picks: make([]templates.PickResult, len(questions)),
+	}
+	for i, q := range questions {
+		choice := answers[q.QuestionID]
+		pick := templates.PickResult{
+			Index:      i + 1,
+			BigText:    q.BigText,
+			Pick:       choiceName(q, choice),
+			ResultText: resultText(q),
+		}
+
+		switch {
+		case !q.HasResult():
+			pick.Outcome = templates.OutcomePending
+		case !q.IsDecisive():
+			pick.Outcome = templates.OutcomeVoid
+		case choice == "":
+			pick.Outcome = templates.OutcomeUnanswered
+		case q.IsCorrect(choice):
+			pick.Outcome = templates.OutcomeCorrect
+		default:
+			pick.Outcome = templates.OutcomeWrong
+		}
+
+		if q.IsDecisive() {
+			vm.Scorable++
+			if pick.Outcome == templates.OutcomeCorrect {
+				vm.Correct++
+			}
+		}
+		vm.Picks[i] = pick
+	}
+
+	return vm
+}
+
+// choiceName returns the fighter name for a choice ("a"/"b"), or empty if unanswered
+func choiceName(q database.Question, choice string) string {
+	switch choice {
+	case database.ResultA:
+		return q.ChoiceA
+	case database.ResultB:
+		return q.ChoiceB
+	}
+	return ""
+}
+
+// resultText describes a question's official result for display
+func resultText(q database.Question) string {
+	if !q.HasResult() {
+		return ""
+	}
+	switch q.Result.String {
+	case database.ResultDraw:
+		return "Draw"
+	case database.ResultNoContest:
+		return "No contest"
+	}
+	return choiceName(q, q.Result.String) + " won"
+}
+
 // writeJSON writes a JSON response
 func writeJSON(w http.ResponseWriter, status int, data interface{}) error {
 	w.Header().Set("Content-Type", "application/json")
@@ -57,11 +148,61 @@ func writeJSON(w http.ResponseWriter, status int, data interface{}) error {
 	return json.NewEncoder(w).Encode(data)
 }
 
+// readJSON decodes a JSON request body into v, rejecting unknown fields
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1MB is plenty for admin payloads
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// nullableString converts a sql.NullString to a JSON-friendly value (string or null)
+func nullableString(s sql.NullString) interface{} {
+	if !s.Valid {
+		return nil
+	}
+	return s.String
+}
+
+func nullableTime(t sql.NullTime) interface{} {
+	if !t.Valid {
+		return nil
+	}
+	return t.Time
+}
+
+func nullableInt32(n sql.NullInt32) interface{} {
+	if !n.Valid {
+		return nil
+	}
+	return n.Int32
+}
+
 // writeError writes a plain text error response
 func writeError(w http.ResponseWriter, status int, message string) {
 	http.Error(w, message, status)
 }
 
+// maskName reduces a full name to first name and last initial, e.g. "Joe Brooks" -> "Joe B."
+// Used wherever names are shown publicly since sessions.name is PII
+func maskName(name string) string {
+	parts := strings.Fields(name)
+	if len(parts) == 0 {
+		return "Anonymous"
+	}
+
+	first := []rune(parts[0])
+	if len(first) > 20 {
+		first = first[:20]
+	}
+	if len(parts) == 1 {
+		return string(first)
+	}
+
+	last := []rune(parts[len(parts)-1])
+	return fmt.Sprintf("%s %s.", string(first), strings.ToUpper(string(last[0])))
+}
+
 // isValidEmail validates email format using net/mail
 func isValidEmail(email string) bool {
 	_, err := mail.ParseAddress(email)
@@ -139,6 +280,27 @@ func transformQuestionBySlugToMap(rows []database.GetQuestionEngagementBySlugRow
 	return result
 }
 
+// questionForm and infoForm name the forms that form tokens are bound to
+func questionForm(questionID string) string {
+	return "question:" + questionID
+}
+
+func infoForm(slug string) string {
+	return "info:" + slug
+}
+
+// formTokenMessage explains a rejected form token to the fan
+func formTokenMessage(err error) string {
+	switch err {
+	case fraud.ErrTokenTooFast:
+		return "Whoa, that was quick! Take a second and try again."
+	case fraud.ErrTokenReplayed:
+		return "That was already submitted."
+	default:
+		return "This page has expired, please try again."
+	}
+}
+
 // buildErrorRedirectURL builds a redirect URL with error and pre-fill query parameters
 func buildErrorRedirectURL(baseURL string, errors map[string]string, values map[string]string) string {
 	queryParams := url.Values{}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/mrbennbenn/pick6/database"
+	"github.com/mrbennbenn/pick6/templates"
+)
+
+func TestMaskName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Joe Brooks", "Joe B."},
+		{"  joe   brooks  ", "joe B."},
+		{"Mary Jane Watson", "Mary W."},
+		{"Cher", "Cher"},
+		{"", "Anonymous"},
+		{"   ", "Anonymous"},
+		{"Zoë Ødegaard", "Zoë Ø."},
+		{"Bartholomewbartholomew Smith", "Bartholomewbartholom S."},
+	}
+	for _, tt := range tests {
+		if got := maskName(tt.name); got != tt.want {
+			t.Errorf("maskName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBuildResultsViewModel(t *testing.T) {
+	result := func(r string) sql.NullString { return sql.NullString{String: r, Valid: r != ""} }
+	question := func(id, r string) database.Question {
+		return database.Question{QuestionID: id, BigText: id, ChoiceA: "Joe", ChoiceB: "Bahaa", Result: result(r)}
+	}
+
+	if vm := buildResultsViewModel([]database.Question{question("q1", ""), question("q2", "")}, nil); vm != nil {
+		t.Fatalf("results before any fight finished: %+v", vm)
+	}
+
+	questions := []database.Question{
+		question("q1", database.ResultA),
+		question("q2", database.ResultB),
+		question("q3", database.ResultDraw),
+		question("q4", database.ResultNoContest),
+		question("q5", ""),
+		question("q6", database.ResultA),
+	}
+	answers := map[string]string{"q1": "a", "q2": "a", "q3": "a", "q4": "b", "q5": "b"}
+
+	vm := buildResultsViewModel(questions, answers)
+	if vm == nil {
+		t.Fatal("no results")
+	}
+	want := []struct {
+		outcome string
+		pick    string
+		result  string
+	}{
+		{templates.OutcomeCorrect, "Joe", "Joe won"},
+		{templates.OutcomeWrong, "Joe", "Bahaa won"},
+		{templates.OutcomeVoid, "Joe", "Draw"},
+		{templates.OutcomeVoid, "Bahaa", "No contest"},
+		{templates.OutcomePending, "Bahaa", ""},
+		{templates.OutcomeUnanswered, "", "Joe won"},
+	}
+	for i, w := range want {
+		p := vm.Picks[i]
+		if p.Index != i+1 || p.Outcome != w.outcome || p.Pick != w.pick || p.ResultText != w.result {
+			t.Errorf("pick %d = %+v, want %+v", i+1, p, w)
+		}
+	}
+	// Draws, no contests and unfinished fights aren't scorable, a skipped decided fight is
+	if vm.Scorable != 3 || vm.Correct != 1 {
+		t.Fatalf("scored %d/%d, want 1/3", vm.Correct, vm.Scorable)
+	}
+}
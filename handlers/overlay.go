@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mrbennbenn/pick6/database"
+	"github.com/mrbennbenn/pick6/templates"
+)
+
+var overlayThemes = map[string]bool{
+	templates.OverlayThemeDark:  true,
+	templates.OverlayThemeLight: true,
+	templates.OverlayThemeBrand: true,
+}
+
+// ShowQuestionOverlay renders a transparent broadcast overlay for one question
+// The page follows the question's SSE stream, so it shows the same data as GetQuestion
+// Query: theme=dark|light|brand, slugs=tk03,tk03-web (default all), counts=1 to show raw counts,
+// exclude_flagged=true to leave out sessions flagged by fraud scoring
+// Route: GET /overlay/{eventIDOrSlug}/question/{questionIDOrIndex}
+func (h *API) ShowQuestionOverlay(w http.ResponseWriter, r *http.Request) {
+	eventIDOrSlug := chi.URLParam(r, "eventID")
+	questionIDOrIndex := chi.URLParam(r, "questionID")
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	theme := templates.OverlayThemeDark
+	if t := query.Get("theme"); t != "" {
+		if !overlayThemes[t] {
+			http.Error(w, "theme must be one of dark, light, brand", http.StatusBadRequest)
+			return
+		}
+		theme = t
+	}
+
+	var slugs []string
+	for _, slug := range strings.Split(query.Get("slugs"), ",") {
+		slug = strings.TrimSpace(slug)
+		if slug == "" {
+			continue
+		}
+		if !database.ValidSlug(slug) {
+			http.Error(w, "slugs must be a comma-separated list of slugs", http.StatusBadRequest)
+			return
+		}
+		slugs = append(slugs, slug)
+	}
+
+	showCounts := false
+	if c := query.Get("counts"); c != "" {
+		parsed, err := strconv.ParseBool(c)
+		if err != nil {
+			http.Error(w, "counts must be true or false", http.StatusBadRequest)
+			return
+		}
+		showCounts = parsed
+	}
+
+	// Resolve event ID
+	eventID, err := h.resolveEventID(ctx, eventIDOrSlug)
+	if err != nil {
+		h.Log.Printf("Error resolving event '%s': %v", eventIDOrSlug, err)
+		http.NotFound(w, r)
+		return
+	}
+
+	// Resolve question
+	question, _, err := h.resolveQuestion(ctx, eventID, questionIDOrIndex)
+	if err != nil {
+		if errors.Is(err, errInvalidQuestionIdentifier) {
+			http.Error(w, "Invalid question identifier", http.StatusBadRequest)
+			return
+		}
+		http.NotFound(w, r)
+		return
+	}
+
+	// Initial render, the stream takes over once connected
+	exclude := excludeFlagged(r)
+	engagement, err := loadQuestionEngagement(ctx, h.Queries, question.QuestionID, exclude)
+	if err != nil {
+		h.Log.Printf("Error getting question engagement: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	counts := engagement.Total
+	if len(slugs) > 0 {
+		counts = engagement.sumSlugs(slugs)
+	}
+
+	streamURL := fmt.Sprintf("/api/events/%s/questions/%s/stream", eventID, question.QuestionID)
+	if exclude {
+		streamURL += "?exclude_flagged=true"
+	}
+
+	vm := templates.OverlayViewModel{
+		BigText:     question.BigText,
+		ChoiceA:     question.ChoiceA,
+		ChoiceB:     question.ChoiceB,
+		VotesA:      counts.VotesA,
+		VotesB:      counts.VotesB,
+		PercentageA: counts.PercentageA,
+		PercentageB: counts.PercentageB,
+		StreamURL:   streamURL,
+		Theme:       theme,
+		Slugs:       strings.Join(slugs, ","),
+		ShowCounts:  showCounts,
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := templates.OverlayPage(vm).Render(ctx, w); err != nil {
+		h.Log.Printf("Error rendering template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mrbennbenn/pick6/database"
+	"github.com/mrbennbenn/pick6/retention"
+)
+
+const (
+	defaultRetentionRuns = 20
+	maxRetentionRuns     = 200
+)
+
+// retentionInput sets an event's PII retention, null falls back to the default
+type retentionInput struct {
+	RetentionDays *int32 `json:"retention_days"`
+}
+
+// retentionRunInput starts a purge run from the admin API
+type retentionRunInput struct {
+	DryRun bool `json:"dry_run"`
+}
+
+// GetRetention returns an event's PII retention policy and when its PII is purged
+// Route: GET /admin/api/events/{eventID}/retention
+func (h *AdminAPI) GetRetention(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+
+	row, err := h.Queries.GetEventRetention(r.Context(), eventID)
+	if err != nil {
+		h.writeDBError(w, err, "Event")
+		return
+	}
+
+	if err := writeJSON(w, http.StatusOK, h.retentionJSON(eventID, row)); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// SetRetention sets how many days after an event closes its PII is kept
+// Route: PUT /admin/api/events/{eventID}/retention
+func (h *AdminAPI) SetRetention(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+	ctx := r.Context()
+
+	var in retentionInput
+	if err := readJSON(w, r, &in); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+	if in.RetentionDays != nil && *in.RetentionDays < 1 {
+		h.writeValidationErrors(w, map[string]string{"retention_days": "must be at least 1, or null for the default"})
+		return
+	}
+
+	if _, err := h.Queries.GetEventByID(ctx, eventID); err != nil {
+		h.writeDBError(w, err, "Event")
+		return
+	}
+
+	params := database.UpsertEventRetentionParams{EventID: eventID}
+	if in.RetentionDays != nil {
+		params.RetentionDays.Int32, params.RetentionDays.Valid = *in.RetentionDays, true
+	}
+	if _, err := h.Queries.UpsertEventRetention(ctx, params); err != nil {
+		h.writeDBError(w, err, "Retention")
+		return
+	}
+
+	row, err := h.Queries.GetEventRetention(ctx, eventID)
+	if err != nil {
+		h.writeDBError(w, err, "Event")
+		return
+	}
+
+	h.Log.Printf("Admin set PII retention for event %s: %v", eventID, nullableInt32(row.RetentionDays))
+
+	if err := writeJSON(w, http.StatusOK, h.retentionJSON(eventID, row)); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// RunRetention purges every event past its retention now, or previews it with dry_run
+// Route: POST /admin/api/retention/run
+func (h *AdminAPI) RunRetention(w http.ResponseWriter, r *http.Request) {
+	var in retentionRunInput
+	if err := readJSON(w, r, &in); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+
+	result, err := retention.Run(r.Context(), h.DB, retention.Options{
+		DefaultDays: h.RetentionDays,
+		DryRun:      in.DryRun,
+		Trigger:     retention.TriggerAdmin,
+	})
+	if err != nil {
+		h.Log.Printf("Error running retention: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	if result.Skipped {
+		writeError(w, http.StatusConflict, "A retention run is already in progress")
+		return
+	}
+
+	h.Log.Printf("Admin ran retention %s (dry_run=%t): %d sessions across %d events",
+		result.RunID, result.DryRun, result.SessionsPurged, len(result.Events))
+
+	if err := writeJSON(w, http.StatusOK, result); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// ListRetentionRuns returns the audit log of purge runs, newest first
+// Route: GET /admin/api/retention/runs?limit=N
+func (h *AdminAPI) ListRetentionRuns(w http.ResponseWriter, r *http.Request) {
+	limit := defaultRetentionRuns
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > maxRetentionRuns {
+			writeError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxRetentionRuns))
+			return
+		}
+		limit = parsed
+	}
+
+	runs, err := h.Queries.ListRetentionRuns(r.Context(), int32(limit))
+	if err != nil {
+		h.writeDBError(w, err, "Retention run")
+		return
+	}
+
+	out := make([]map[string]interface{}, len(runs))
+	for i, run := range runs {
+		out[i] = map[string]interface{}{
+			"run_id":           run.RunID,
+			"trigger":          run.Trigger,
+			"dry_run":          run.DryRun,
+			"default_days":     run.DefaultDays,
+			"event_ids":        run.EventIds,
+			"sessions_purged":  run.SessionsPurged,
+			"entrants_deleted": run.EntrantsDeleted,
+			"consents_deleted": run.ConsentsDeleted,
+			"signals_deleted":  run.SignalsDeleted,
+			"error":            nullableString(run.Error),
+			"started_at":       run.StartedAt,
+			"finished_at":      run.FinishedAt,
+		}
+	}
+
+	if err := writeJSON(w, http.StatusOK, map[string]interface{}{"runs": out}); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// Helper: retentionJSON renders an event's policy with the effective days and purge time
+func (h *AdminAPI) retentionJSON(eventID string, row database.GetEventRetentionRow) map[string]interface{} {
+	days := h.RetentionDays
+	if row.RetentionDays.Valid {
+		days = int(row.RetentionDays.Int32)
+	}
+
+	// Unknown until the event has votes or a question window, never with no policy at all
+	var purgeAfter interface{}
+	if row.ClosedAt.Valid && days > 0 {
+		purgeAfter = row.ClosedAt.Time.AddDate(0, 0, days)
+	}
+
+	return map[string]interface{}{
+		"event_id":       eventID,
+		"retention_days": nullableInt32(row.RetentionDays),
+		"default_days":   h.RetentionDays,
+		"effective_days": days,
+		"closed_at":      nullableTime(row.ClosedAt),
+		"purge_after":    purgeAfter,
+		"purged_at":      nullableTime(row.PurgedAt),
+	}
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mrbennbenn/pick6/database"
+)
+
+const (
+	defaultLeaderboardSize = 10
+	maxLeaderboardSize     = 100
+)
+
+// GetScores returns how the crowd's predictions scored against official results
+// Per-session scores are admin-only, the public view is a score distribution
+// Route: GET /api/events/{eventIDOrSlug}/scores
+func (h *API) GetScores(w http.ResponseWriter, r *http.Request) {
+	eventIDOrSlug := chi.URLParam(r, "eventID")
+	ctx := r.Context()
+
+	// Resolve event ID
+	eventID, err := h.resolveEventID(ctx, eventIDOrSlug)
+	if err != nil {
+		h.Log.Printf("Error resolving event '%s': %v", eventIDOrSlug, err)
+		writeError(w, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	response, err := buildScoresResponse(ctx, h.Queries, eventID)
+	if err != nil {
+		h.Log.Printf("Error building scores: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	distribution, err := h.Queries.GetEventScoreDistribution(ctx, eventID)
+	if err != nil {
+		h.Log.Printf("Error getting score distribution: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	var sessions int64
+	for _, row := range distribution {
+		sessions += row.Sessions
+	}
+	response["sessions"] = sessions
+	response["distribution"] = distribution
+
+	if err := writeJSON(w, http.StatusOK, response); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// GetLeaderboard returns the top-N predictors with masked names
+// Names are PII, so only first name and last initial are exposed (e.g. "Joe B.")
+// Route: GET /api/events/{eventIDOrSlug}/leaderboard?limit=N
+func (h *API) GetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	eventIDOrSlug := chi.URLParam(r, "eventID")
+	ctx := r.Context()
+
+	limit := defaultLeaderboardSize
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > maxLeaderboardSize {
+			writeError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxLeaderboardSize))
+			return
+		}
+		limit = parsed
+	}
+
+	// Resolve event ID
+	eventID, err := h.resolveEventID(ctx, eventIDOrSlug)
+	if err != nil {
+		h.Log.Printf("Error resolving event '%s': %v", eventIDOrSlug, err)
+		writeError(w, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	summary, err := h.Queries.GetEventResultSummary(ctx, eventID)
+	if err != nil {
+		h.Log.Printf("Error getting result summary: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	// Until a decisive result exists everyone is on zero, so there is nothing to rank
+	entries := []map[string]interface{}{}
+	if summary.QuestionsScorable > 0 {
+		rows, err := h.Queries.ListLeaderboardByEvent(ctx, database.ListLeaderboardByEventParams{
+			EventID:    eventID,
+			MaxEntries: int32(limit),
+		})
+		if err != nil {
+			h.Log.Printf("Error getting leaderboard: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		// Standard competition ranking: equal scores share a rank (1, 1, 3)
+		rank := 0
+		for i, row := range rows {
+			if i == 0 || row.Correct != rows[i-1].Correct {
+				rank = i + 1
+			}
+			entries = append(entries, map[string]interface{}{
+				"rank":     rank,
+				"name":     maskName(row.Name.String),
+				"correct":  row.Correct,
+				"answered": row.Answered,
+			})
+		}
+	}
+
+	response := map[string]interface{}{
+		"event_id":           eventID,
+		"total_questions":    summary.TotalQuestions,
+		"questions_scorable": summary.QuestionsScorable,
+		"entries":            entries,
+	}
+
+	if err := writeJSON(w, http.StatusOK, response); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// ListScores returns every session's correct-pick count for an event
+// Route: GET /admin/api/events/{eventID}/scores
+func (h *AdminAPI) ListScores(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventID")
+	ctx := r.Context()
+
+	if _, err := h.Queries.GetEventByID(ctx, eventID); err != nil {
+		h.writeDBError(w, err, "Event")
+		return
+	}
+
+	response, err := buildScoresResponse(ctx, h.Queries, eventID)
+	if err != nil {
+		h.Log.Printf("Error building scores: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	scores, err := h.Queries.ListSessionScoresByEvent(ctx, eventID)
+	if err != nil {
+		h.writeDBError(w, err, "Score")
+		return
+	}
+	response["sessions"] = scores
+
+	if err := writeJSON(w, http.StatusOK, response); err != nil {
+		h.Log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// Helper: buildScoresResponse builds the result summary shared by the public and admin score views
+func buildScoresResponse(ctx context.Context, queries *database.Queries, eventID string) (map[string]interface{}, error) {
+	summary, err := queries.GetEventResultSummary(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	questions, err := queries.ListQuestionsByEventID(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]map[string]interface{}, len(questions))
+	for i, q := range questions {
+		results[i] = map[string]interface{}{
+			"question_id": q.QuestionID,
+			"index":       i + 1,
+			"big_text":    q.BigText,
+			"result":      nullableString(q.Result),
+		}
+	}
+
+	return map[string]interface{}{
+		"event_id":           eventID,
+		"total_questions":    summary.TotalQuestions,
+		"questions_resulted": summary.QuestionsResulted,
+		"questions_scorable": summary.QuestionsScorable,
+		"results":            results,
+	}, nil
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/go-chi/chi/v5"
+	"github.com/mrbennbenn/pick6/broadcast"
+)
+
+const (
+	socketPingInterval = 20 * time.Second
+	socketWriteTimeout = 5 * time.Second
+)
+
+// EventSocket is a WebSocket feed for broadcast graphics following an event's on-air question
+// Clients connect once per event and receive:
+//   - {"type":"on_air","event_id":...,"question_id":...} when an admin switches the on-air question (null when cleared)
+//   - {"type":"tally","event_id":...,"question":{...}} with the GetQuestion payload whenever the on-air question's counts change
+//
+// Tallies come from the same Hub as the SSE stream, so sockets and streams share one poller per question
+// Route: GET /api/events/{eventIDOrSlug}/ws
+func (h *API) EventSocket(w http.ResponseWriter, r *http.Request) {
+	eventIDOrSlug := chi.URLParam(r, "eventID")
+
+	// Resolve event ID
+	eventID, err := h.resolveEventID(r.Context(), eventIDOrSlug)
+	if err != nil {
+		h.Log.Printf("Error resolving event '%s': %v", eventIDOrSlug, err)
+		writeError(w, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	// Browser pages may only connect from the configured origins (requests without an Origin
+	// header, like native clients, are always accepted)
+	opts := &websocket.AcceptOptions{OriginPatterns: h.SocketOrigins}
+	if len(h.SocketOrigins) == 0 {
+		// The feed is public and read-only, so accept any origin (vMix/CasparCG browser sources)
+		opts.InsecureSkipVerify = true
+	}
+	conn, err := websocket.Accept(w, r, opts)
+	if err != nil {
+		h.Log.Printf("Error accepting WebSocket: %v", err)
+		return
+	}
+	defer conn.CloseNow()
+
+	// Clients don't send anything, CloseRead handles control frames and cancels ctx on disconnect
+	ctx := conn.CloseRead(r.Context())
+
+	onAir := h.OnAirHub.Subscribe(eventID)
+	defer onAir.Close()
+
+	var tally *broadcast.Subscription
+	var tallyC <-chan []byte
+	defer func() {
+		if tally != nil {
+			tally.Close()
+		}
+	}()
+
+	ping := time.NewTicker(socketPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			conn.Close(websocket.StatusNormalClosure, "")
+			return
+
+		case payload := <-onAir.C:
+			var state onAirState
+			if err := json.Unmarshal(payload, &state); err != nil {
+				h.Log.Printf("Error decoding on-air state: %v", err)
+				continue
+			}
+
+			// Switch the tally subscription to the new on-air question
+			if tally != nil {
+				tally.Close()
+				tally, tallyC = nil, nil
+			}
+			if state.QuestionID != nil {
+				tally = h.Hub.Subscribe(*state.QuestionID)
+				tallyC = tally.C
+			}
+
+			message := map[string]interface{}{
+				"type":        "on_air",
+				"event_id":    eventID,
+				"question_id": state.QuestionID,
+			}
+			if err := h.writeSocket(ctx, conn, message); err != nil {
+				return
+			}
+
+		case payload := <-tallyC:
+			message := map[string]interface{}{
+				"type":     "tally",
+				"event_id": eventID,
+				"question": json.RawMessage(payload),
+			}
+			if err := h.writeSocket(ctx, conn, message); err != nil {
+				return
+			}
+
+		case <-ping.C:
+			pingCtx, cancel := context.WithTimeout(ctx, socketWriteTimeout)
+			err := conn.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// onAirState is the OnAirHub payload for an event
+type onAirState struct {
+	QuestionID *string `json:"question_id"`
+}
+
+// OnAirPayload is the Hub fetch function for on-air state, keyed by event ID
+func (h *API) OnAirPayload(ctx context.Context, eventID string) ([]byte, error) {
+	event, err := h.Queries.GetEventByID(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	state := onAirState{}
+	if event.OnAirQuestionID.Valid {
+		state.QuestionID = &event.OnAirQuestionID.String
+	}
+	return json.Marshal(state)
+}
+
+// Helper: writeSocket writes a JSON message with a timeout so a stalled client can't block forever
+func (h *API) writeSocket(ctx context.Context, conn *websocket.Conn, message interface{}) error {
+	writeCtx, cancel := context.WithTimeout(ctx, socketWriteTimeout)
+	defer cancel()
+	return wsjson.Write(writeCtx, conn, message)
+}
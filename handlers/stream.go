@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// streamHeartbeat keeps idle connections alive through proxies (Fly closes after 60s)
+const streamHeartbeat = 15 * time.Second
+
+// StreamQuestion pushes the GetQuestion payload as Server-Sent Events whenever it changes
+// All subscribers to a question share one poller in the Hub, so counts are
+// computed once per interval however many graphics clients are connected
+// Route: GET /api/events/{eventIDOrSlug}/questions/{questionIDOrIndex}/stream
+func (h *API) StreamQuestion(w http.ResponseWriter, r *http.Request) {
+	eventIDOrSlug := chi.URLParam(r, "eventID")
+	questionIDOrIndex := chi.URLParam(r, "questionID")
+	ctx := r.Context()
+
+	// Resolve event ID
+	eventID, err := h.resolveEventID(ctx, eventIDOrSlug)
+	if err != nil {
+		h.Log.Printf("Error resolving event '%s': %v", eventIDOrSlug, err)
+		writeError(w, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	// Resolve question
+	question, _, err := h.resolveQuestion(ctx, eventID, questionIDOrIndex)
+	if err != nil {
+		if errors.Is(err, errInvalidQuestionIdentifier) {
+			writeError(w, http.StatusBadRequest, "Invalid question identifier")
+			return
+		}
+		writeError(w, http.StatusNotFound, "Question not found")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "Streaming unsupported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable proxy buffering
+	w.WriteHeader(http.StatusOK)
+
+	key := question.QuestionID
+	if excludeFlagged(r) {
+		key += excludeFlaggedKeySuffix
+	}
+	sub := h.Hub.Subscribe(key)
+	defer sub.Close()
+
+	// Tell EventSource to reconnect quickly if the connection drops
+	fmt.Fprint(w, "retry: 2000\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case payload := <-sub.C:
+			if _, err := fmt.Fprintf(w, "event: question\ndata: %s\n\n", payload); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// excludeFlaggedKeySuffix marks Hub keys for streams that leave out flagged sessions,
+// so filtered and unfiltered subscribers each share their own poller
+const excludeFlaggedKeySuffix = "|exclude_flagged"
+
+// QuestionPayload is the Hub fetch function for question streams
+// It builds the same JSON as GetQuestion for a question ID
+func (h *API) QuestionPayload(ctx context.Context, key string) ([]byte, error) {
+	questionID, exclude := strings.CutSuffix(key, excludeFlaggedKeySuffix)
+	question, err := h.Queries.GetQuestionByID(ctx, questionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Index can change if earlier questions are deleted, so look it up each time
+	questions, err := h.Queries.ListQuestionsByEventID(ctx, question.EventID)
+	if err != nil {
+		return nil, err
+	}
+	index := 0
+	for i, q := range questions {
+		if q.QuestionID == questionID {
+			index = i + 1
+			break
+		}
+	}
+
+	response := h.buildQuestionResponse(ctx, question, index, exclude)
+	if response == nil {
+		return nil, errors.New("error building question response")
+	}
+	return json.Marshal(response)
+}
@@ -7,19 +7,34 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/mrbennbenn/pick6/database"
+	"github.com/mrbennbenn/pick6/database/pii"
+	"github.com/mrbennbenn/pick6/fraud"
 	"github.com/mrbennbenn/pick6/middleware"
 	"github.com/mrbennbenn/pick6/templates"
 )
 
 type UI struct {
+	DB         *sql.DB // For saving an entry and its entrant link together
 	Queries    *database.Queries
 	Log        *log.Logger
 	EventCache *database.EventCache // Cache for event and questions data
+	Signals    *fraud.Recorder      // Fraud signals for each vote (nil disables)
+	FormTokens *fraud.FormTokens    // Signed single-use tokens for CSRF and bot checks (nil disables)
+
+	PolicyVersion string // Terms/privacy version recorded with each consent
+	TermsURL      string // Prize draw terms linked from the consent checkbox (optional)
 }
 
+// Minimum time between rendering a form and submitting it
+const (
+	minQuestionFormAge = 700 * time.Millisecond // Read the matchup and tap a fighter
+	minInfoFormAge     = 2 * time.Second        // Autofill is quick, typing takes far longer
+)
+
 // RedirectToFirst redirects to the first question
 // Route: GET /{slug}/
 func (h *UI) RedirectToFirst(w http.ResponseWriter, r *http.Request) {
@@ -28,7 +43,7 @@ func (h *UI) RedirectToFirst(w http.ResponseWriter, r *http.Request) {
 	// Validate slug exists using cache
 	_, err := h.EventCache.GetEventWithQuestionsBySlug(r.Context(), slug)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if database.IsNotFound(err) {
 			http.NotFound(w, r)
 			return
 		}
@@ -71,7 +86,7 @@ func (h *UI) ShowQuestion(w http.ResponseWriter, r *http.Request) {
 		return queryErr
 	})
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if database.IsNotFound(err) {
 			http.NotFound(w, r)
 			return
 		}
@@ -118,8 +133,10 @@ func (h *UI) ShowQuestion(w http.ResponseWriter, r *http.Request) {
 		Slug:            slug,
 		Questions:       convertToTemplateQuestions(questions),
 		CurrentIndex:    currentIndex,
+		NextURL:         nextStepURL(slug, order, len(questions)),
 		ExistingAnswers: existingAnswers, // Now only contains current question's answer if exists
 		Errors:          parseErrors(r),
+		FormToken:       h.FormTokens.Issue(sessionID, questionForm(currentQuestion.QuestionID)),
 	}
 
 	// Prevent browser caching to ensure fresh data on back button
@@ -163,7 +180,7 @@ func (h *UI) SubmitAnswer(w http.ResponseWriter, r *http.Request) {
 		return queryErr
 	})
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if database.IsNotFound(err) {
 			http.NotFound(w, r)
 			return
 		}
@@ -189,6 +206,27 @@ func (h *UI) SubmitAnswer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Bots that fill the honeypot are sent on as if the vote counted, so they learn nothing
+	if fraud.HoneypotFilled(r) {
+		h.Log.Printf("Honeypot vote dropped: session=%s question=%s remote=%s", sessionID, currentQuestion.QuestionID, r.RemoteAddr)
+		http.Redirect(w, r, nextStepURL(slug, order, len(questions)), http.StatusSeeOther)
+		return
+	}
+
+	// The token proves the page was rendered for this session (CSRF), long
+	// enough ago for a person to have read it, and not already submitted
+	shownAt, err := h.FormTokens.Check(r.Context(), sessionID, questionForm(currentQuestion.QuestionID), r.PostFormValue(fraud.TokenField), minQuestionFormAge)
+	if err != nil {
+		h.Log.Printf("Vote rejected: %v - session=%s question=%s remote=%s", err, sessionID, currentQuestion.QuestionID, r.RemoteAddr)
+		redirectURL := buildErrorRedirectURL(
+			fmt.Sprintf("/%s/question/%d", slug, order),
+			map[string]string{"choice": formTokenMessage(err)},
+			nil,
+		)
+		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+		return
+	}
+
 	choice := r.FormValue("choice")
 
 	// Validate choice
@@ -202,6 +240,18 @@ func (h *UI) SubmitAnswer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Reject picks once the fight's voting window has closed
+	// UpsertResponse enforces this again against the database, in case the cache is stale
+	lockedRedirect := buildErrorRedirectURL(
+		fmt.Sprintf("/%s/question/%d", slug, order),
+		map[string]string{"choice": "Voting is closed for this fight"},
+		nil,
+	)
+	if !currentQuestion.IsOpen(time.Now()) {
+		http.Redirect(w, r, lockedRedirect, http.StatusSeeOther)
+		return
+	}
+
 	// Save response (with retry logic)
 	err = database.WithRetry(r.Context(), database.DefaultRetryConfig(), func() error {
 		_, queryErr := h.Queries.UpsertResponse(r.Context(), database.UpsertResponseParams{
@@ -212,21 +262,30 @@ func (h *UI) SubmitAnswer(w http.ResponseWriter, r *http.Request) {
 		})
 		return queryErr
 	})
+	if err == sql.ErrNoRows {
+		// Question was locked after the cache was loaded
+		h.EventCache.InvalidateEvent(eventData.Event.EventID)
+		http.Redirect(w, r, lockedRedirect, http.StatusSeeOther)
+		return
+	}
 	if err != nil {
 		h.Log.Printf("Error saving response: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
+	// Time on page comes from the signed render time in the form token
+	h.Signals.Record(fraud.Vote{
+		QuestionID: currentQuestion.QuestionID,
+		SessionID:  sessionID,
+		IP:         middleware.KeyByIP(r),
+		UserAgent:  r.UserAgent(),
+		TimeOnPage: time.Since(shownAt),
+		PageViewed: !shownAt.IsZero(),
+	})
+
 	// Determine next step (database is authoritative for question count)
-	if order == len(questions) {
-		// Last question - redirect to info form
-		http.Redirect(w, r, fmt.Sprintf("/%s/submit-info", slug), http.StatusSeeOther)
-	} else {
-		// More questions - redirect to next
-		nextOrder := order + 1
-		http.Redirect(w, r, fmt.Sprintf("/%s/question/%d", slug, nextOrder), http.StatusSeeOther)
-	}
+	http.Redirect(w, r, nextStepURL(slug, order, len(questions)), http.StatusSeeOther)
 }
 
 // ShowInfoForm displays the user information collection form
@@ -234,10 +293,18 @@ func (h *UI) SubmitAnswer(w http.ResponseWriter, r *http.Request) {
 func (h *UI) ShowInfoForm(w http.ResponseWriter, r *http.Request) {
 	slug := chi.URLParam(r, "slug")
 
+	// Get session ID
+	sessionID, err := middleware.SessionFromCtx(r.Context())
+	if err != nil {
+		h.Log.Printf("Error getting session from context: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	// Validate slug exists using cache
-	_, err := h.EventCache.GetEventWithQuestionsBySlug(r.Context(), slug)
+	_, err = h.EventCache.GetEventWithQuestionsBySlug(r.Context(), slug)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if database.IsNotFound(err) {
 			http.NotFound(w, r)
 			return
 		}
@@ -248,11 +315,15 @@ func (h *UI) ShowInfoForm(w http.ResponseWriter, r *http.Request) {
 
 	// Build view model (pre-fill from query params if validation failed)
 	vm := templates.InfoFormViewModel{
-		Slug:   slug,
-		Name:   r.URL.Query().Get("name"),
-		Email:  r.URL.Query().Get("email"),
-		Phone:  r.URL.Query().Get("phone"),
-		Errors: parseErrors(r),
+		Slug:      slug,
+		Name:      r.URL.Query().Get("name"),
+		Email:     r.URL.Query().Get("email"),
+		Phone:     r.URL.Query().Get("phone"),
+		Terms:     r.URL.Query().Get("terms") == "on",
+		Marketing: r.URL.Query().Get("marketing") == "on",
+		TermsURL:  h.TermsURL,
+		Errors:    parseErrors(r),
+		FormToken: h.FormTokens.Issue(sessionID, infoForm(slug)),
 	}
 
 	// Render template
@@ -281,9 +352,29 @@ func (h *UI) SubmitInfoForm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if fraud.HoneypotFilled(r) {
+		h.Log.Printf("Honeypot entry dropped: session=%s remote=%s", sessionID, r.RemoteAddr)
+		http.Redirect(w, r, fmt.Sprintf("/%s/end", slug), http.StatusSeeOther)
+		return
+	}
+
+	if _, err := h.FormTokens.Check(r.Context(), sessionID, infoForm(slug), r.PostFormValue(fraud.TokenField), minInfoFormAge); err != nil {
+		h.Log.Printf("Entry rejected: %v - session=%s remote=%s", err, sessionID, r.RemoteAddr)
+		redirectURL := buildErrorRedirectURL(
+			fmt.Sprintf("/%s/submit-info", slug),
+			map[string]string{"entry": formTokenMessage(err)},
+			map[string]string{"name": r.FormValue("name"), "email": r.FormValue("email"), "phone": r.FormValue("phone"),
+				"terms": r.FormValue("terms"), "marketing": r.FormValue("marketing")},
+		)
+		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+		return
+	}
+
 	name := strings.TrimSpace(r.FormValue("name"))
 	email := strings.TrimSpace(r.FormValue("email"))
 	phone := strings.TrimSpace(r.FormValue("phone"))
+	terms := r.FormValue("terms") == "on"
+	marketing := r.FormValue("marketing") == "on"
 
 	// Validate
 	errors := make(map[string]string)
@@ -310,36 +401,80 @@ func (h *UI) SubmitInfoForm(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if !terms {
+		errors["terms"] = "Please accept the prize draw terms to enter"
+	}
+
 	// If validation fails, redirect back with errors
 	if len(errors) > 0 {
 		redirectURL := buildErrorRedirectURL(
 			fmt.Sprintf("/%s/submit-info", slug),
 			errors,
 			map[string]string{
-				"name":  name,
-				"email": email,
-				"phone": r.FormValue("phone"), // Use original, not normalized
+				"name":      name,
+				"email":     email,
+				"phone":     r.FormValue("phone"), // Use original, not normalized
+				"terms":     r.FormValue("terms"),
+				"marketing": r.FormValue("marketing"),
 			},
 		)
 		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
 		return
 	}
 
-	// Save session
-	_, err = h.Queries.UpsertSession(r.Context(), database.UpsertSessionParams{
-		SessionID: sessionID,
-		Name:      sql.NullString{String: name, Valid: true},
-		Email:     sql.NullString{String: email, Valid: true},
-		Mobile:    sql.NullString{String: phone, Valid: true}, // E.164 format
+	eventData, err := h.EventCache.GetEventWithQuestionsBySlug(r.Context(), slug)
+	if err != nil {
+		if database.IsNotFound(err) {
+			http.NotFound(w, r)
+			return
+		}
+		h.Log.Printf("Error getting event: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// One entry per person per event, claimed with the details in one transaction
+	// so a rejected duplicate never becomes eligible for the draw
+	claim, err := h.saveEntry(r.Context(), eventData.Event.EventID, database.UpsertSessionParams{
+		SessionID:   sessionID,
+		Name:        pii.NewString(name),
+		Email:       pii.NewString(email),
+		Mobile:      pii.NewString(phone), // E.164 format
+		EmailIndex:  sql.NullString{String: pii.EmailIndex(email), Valid: true},
+		MobileIndex: sql.NullString{String: pii.MobileIndex(phone), Valid: true},
 	})
+	if err == errEntrantConflict {
+		h.Log.Printf("Duplicate entry rejected: session=%s event=%s", sessionID, eventData.Event.EventID)
+		redirectURL := buildErrorRedirectURL(
+			fmt.Sprintf("/%s/submit-info", slug),
+			map[string]string{"entry": "These details are already entered in the prize draw. Each person can enter once."},
+			map[string]string{"name": name, "email": email, "phone": r.FormValue("phone"),
+				"terms": r.FormValue("terms"), "marketing": r.FormValue("marketing")},
+		)
+		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		h.Log.Printf("Error saving entry: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Consent evidence: when, under which policy version and from where
+	err = h.recordConsents(r.Context(), eventData.Event.EventID, sessionID, middleware.KeyByIP(r), terms, marketing)
 	if err != nil {
-		h.Log.Printf("Error saving session: %v", err)
+		h.Log.Printf("Error recording consent: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
 	// Redirect to end page
-	http.Redirect(w, r, fmt.Sprintf("/%s/end", slug), http.StatusSeeOther)
+	endURL := fmt.Sprintf("/%s/end", slug)
+	if claim == entrantMerged {
+		h.Log.Printf("Entry merged into existing entrant: session=%s event=%s", sessionID, eventData.Event.EventID)
+		endURL += "?entered=already"
+	}
+	http.Redirect(w, r, endURL, http.StatusSeeOther)
 }
 
 // ShowEnd displays the thank you page
@@ -358,7 +493,7 @@ func (h *UI) ShowEnd(w http.ResponseWriter, r *http.Request) {
 	// Get event from cache
 	eventData, err := h.EventCache.GetEventWithQuestionsBySlug(r.Context(), slug)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if database.IsNotFound(err) {
 			http.NotFound(w, r)
 			return
 		}
@@ -381,8 +516,10 @@ func (h *UI) ShowEnd(w http.ResponseWriter, r *http.Request) {
 
 	// Build view model
 	vm := templates.EndViewModel{
-		Slug:         slug,
-		TotalAnswers: len(responses),
+		Slug:           slug,
+		TotalAnswers:   len(responses),
+		AlreadyEntered: r.URL.Query().Get("entered") == "already",
+		Results:        buildResultsViewModel(eventData.Questions, buildExistingAnswersMap(responses)),
 	}
 
 	// Render template
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -13,17 +14,73 @@ import (
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/kelseyhightower/envconfig"
 	_ "github.com/lib/pq"
+	"github.com/mrbennbenn/pick6/broadcast"
 	"github.com/mrbennbenn/pick6/database"
+	"github.com/mrbennbenn/pick6/database/pii"
+	"github.com/mrbennbenn/pick6/fraud"
 	"github.com/mrbennbenn/pick6/handlers"
+	"github.com/mrbennbenn/pick6/metrics"
 	"github.com/mrbennbenn/pick6/middleware"
+	"github.com/mrbennbenn/pick6/retention"
 	cache "github.com/patrickmn/go-cache"
 )
 
 type Config struct {
-	DatabaseURL  string `envconfig:"DATABASE_URL" required:"true"`
-	Port         string `envconfig:"PORT" default:"8080"`
-	SecureCookie bool   `envconfig:"SECURE_COOKIE" default:"true"`
-	BaseURL      string `envconfig:"BASE_URL" default:"http://localhost:8080"`
+	DatabaseURL   string   `envconfig:"DATABASE_URL" required:"true"`
+	Port          string   `envconfig:"PORT" default:"8080"`
+	SecureCookie  bool     `envconfig:"SECURE_COOKIE" default:"true"`
+	BaseURL       string   `envconfig:"BASE_URL" default:"http://localhost:8080"`
+	APIKeys       []string `envconfig:"API_KEYS"`       // Comma-separated keys for the admin API
+	AdminPassword string   `envconfig:"ADMIN_PASSWORD"` // Admin console login (API keys also work)
+
+	WSOriginPatterns []string `envconfig:"WS_ORIGIN_PATTERNS"` // Comma-separated origin hosts allowed on the WebSocket, empty allows any
+
+	// Comma-separated cookie signing secrets, the first signs and all verify (rotation)
+	SessionSecrets []string `envconfig:"SESSION_SECRET"`
+	// Accept pre-signing unsigned cookies and re-issue them signed, turn off once they have expired (24h)
+	SessionAllowUnsigned bool `envconfig:"SESSION_ALLOW_UNSIGNED" default:"true"`
+
+	// Token-bucket limits on the vote and signup POSTs and on new sessions, 0 disables a limit
+	// The per-IP limits are generous because a whole venue can share one NAT address
+	RateLimitIPPerMinute          int `envconfig:"RATE_LIMIT_IP_PER_MINUTE" default:"300"`
+	RateLimitIPBurst              int `envconfig:"RATE_LIMIT_IP_BURST" default:"100"`
+	RateLimitSessionPerMinute     int `envconfig:"RATE_LIMIT_SESSION_PER_MINUTE" default:"30"`
+	RateLimitSessionBurst         int `envconfig:"RATE_LIMIT_SESSION_BURST" default:"10"`
+	RateLimitNewSessionsPerMinute int `envconfig:"RATE_LIMIT_NEW_SESSIONS_PER_MINUTE" default:"300"`
+	RateLimitNewSessionsBurst     int `envconfig:"RATE_LIMIT_NEW_SESSIONS_BURST" default:"100"`
+	// Admin console password attempts per client IP
+	RateLimitAdminLoginPerMinute int `envconfig:"RATE_LIMIT_ADMIN_LOGIN_PER_MINUTE" default:"5"`
+
+	// Keys the IP hashes stored with fraud signals, random per process if unset
+	IPHashSecret string `envconfig:"IP_HASH_SECRET"`
+	// Workers storing fraud signals (each holds at most one connection, 0 disables
+	// signals) and how many votes can wait for them before signals are dropped
+	FraudSignalWorkers int `envconfig:"FRAUD_SIGNAL_WORKERS" default:"4"`
+	FraudSignalQueue   int `envconfig:"FRAUD_SIGNAL_QUEUE" default:"1000"`
+
+	// Recorded with every consent, bump it whenever the terms or privacy notice change
+	PolicyVersion string `envconfig:"POLICY_VERSION" default:"1"`
+	TermsURL      string `envconfig:"TERMS_URL"` // Prize draw terms linked from the info form
+
+	// Comma-separated id:base64 AES-256 keys sealing names, emails and mobiles, the first
+	// seals and all open (rotate by prepending, then run `pick6 pii reencrypt`)
+	PIIKeys []string `envconfig:"PII_KEYS"`
+	// Base64 HMAC key for email/mobile blind indexes, must never change once set
+	PIIIndexKey string `envconfig:"PII_INDEX_KEY"`
+
+	// Days after an event closes that fans' details are kept, for events without
+	// their own policy (0 keeps them), and how often the purge worker runs (0 disables it)
+	PIIRetentionDays     int           `envconfig:"PII_RETENTION_DAYS" default:"90"`
+	PIIRetentionInterval time.Duration `envconfig:"PII_RETENTION_INTERVAL" default:"6h"`
+
+	// LISTEN needs a session-level connection, set this to a direct (unpooled) URL
+	// when DATABASE_URL goes through a transaction pooler
+	CacheListenURL    string        `envconfig:"CACHE_LISTEN_URL"`
+	CachePollInterval time.Duration `envconfig:"CACHE_POLL_INTERVAL" default:"30s"`
+
+	// How long /api/events responses are served without checking the database, 0
+	// revalidates every request (ETags and 304s work either way)
+	APICacheTTL time.Duration `envconfig:"API_CACHE_TTL" default:"2s"`
 }
 
 func main() {
@@ -32,6 +89,16 @@ func main() {
 		log.Fatal(err.Error())
 	}
 
+	// Installed before any query runs, so every read and write of contact details goes through it
+	piiKeys, err := pii.NewKeys(cfg.PIIKeys, cfg.PIIIndexKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pii.Use(piiKeys)
+	if piiKeys == nil {
+		log.Println("warning: PII_KEYS not set, names, emails and mobiles will be stored in plaintext")
+	}
+
 	// Add prefer_simple_protocol to fix Neon Postgres prepared statement cache issues
 	// This forces the driver to use simple query protocol instead of prepared statements
 	// Prevents "bind message supplies X parameters, but prepared statement requires Y" errors
@@ -65,8 +132,48 @@ func main() {
 	log.Println("successfully connected to database")
 
 	queries := database.New(db)
+
+	// Subcommands (e.g. `pick6 gdpr export`) run against the database and exit
+	if len(os.Args) > 1 {
+		if err := runCommand(context.Background(), cfg, db, queries, os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	logger := log.New(os.Stdout, "", log.LstdFlags)
 
+	// Purges fans' details once events pass their retention, every instance runs it
+	// and the database lock lets one purge at a time
+	retention.NewWorker(db, cfg.PIIRetentionDays, cfg.PIIRetentionInterval, logger).Start(context.Background())
+
+	// Initialize event cache with 1 hour TTL (events/questions are static)
+	// Shared between the voting UI and the admin API so admin writes can invalidate it
+	eventCache := database.NewEventCache(queries, 1*time.Hour, 2*time.Hour)
+
+	// Initialize session cache with 5 minute default expiration and 10 minute cleanup interval
+	sessionCache := cache.New(5*time.Minute, 10*time.Minute)
+	sessionCacheStats := &database.CacheCounters{}
+	sessionCacheStats.Watch(sessionCache)
+
+	// Both caches are per process, so writes from other instances (or psql) arrive
+	// through Postgres NOTIFY, with a poll of cache_generation while it is down
+	listenURL := cfg.CacheListenURL
+	if listenURL == "" {
+		listenURL = dbURL
+	}
+	database.NewCacheListener(listenURL, queries, eventCache, sessionCache, cfg.CachePollInterval, logger).Start(context.Background())
+
+	// Keyed off the cookie secrets, so tokens survive restarts and work on every instance,
+	// with used nonces in Postgres so each token is single-use across all of them
+	formTokens, err := fraud.NewFormTokens(cfg.SessionSecrets, 2*time.Hour, &fraud.DBNonces{Queries: queries, Log: logger})
+	if err != nil {
+		log.Fatalf("SESSION_SECRET is required, it signs vote_session cookies and form tokens: %v", err)
+	}
+
+	// Fraud signals for each vote, stored by a fixed pool of workers off the request path
+	signals := fraud.NewRecorder(queries, cfg.IPHashSecret, cfg.FraudSignalWorkers, cfg.FraudSignalQueue, logger)
+
 	// Create chi router
 	r := chi.NewRouter()
 
@@ -75,53 +182,227 @@ func main() {
 	r.Use(chimiddleware.RealIP)
 	r.Use(chimiddleware.Logger)
 	r.Use(chimiddleware.Recoverer)
-	r.Use(chimiddleware.Compress(5))               // Gzip compression (level 5 balances speed/size)
-	r.Use(chimiddleware.Timeout(10 * time.Second)) // Reduced from 20s for faster failure detection
+	r.Use(chimiddleware.Compress(5)) // Gzip compression (level 5 balances speed/size)
+
+	// Request timeout, applied per route group so long-lived streams aren't cut off
+	timeout := chimiddleware.Timeout(10 * time.Second) // Reduced from 20s for faster failure detection
 
 	// Serve static files with caching headers
 	fileServer := http.FileServer(http.Dir("./static"))
-	r.Handle("/static/*", middleware.CacheControl(http.StripPrefix("/static/", fileServer)))
+	r.With(timeout).Handle("/static/*", middleware.CacheControl(http.StripPrefix("/static/", fileServer)))
+
+	// Public API handler, shared by the JSON API and broadcast overlays
+	apiHandler := &handlers.API{
+		Queries:     queries,
+		Log:         logger,
+		BaseURL:     cfg.BaseURL,
+		ResponseTTL: cfg.APICacheTTL,
+
+		SocketOrigins: cfg.WSOriginPatterns,
+	}
+	if cfg.APICacheTTL > 0 {
+		// Entries outlive their TTL so a revalidation that finds nothing changed skips the rebuild
+		apiHandler.Responses = cache.New(max(time.Minute, 2*cfg.APICacheTTL), 2*time.Minute)
+	}
+
+	// One poller per watched question (or event for on-air state), shared by all
+	// stream and socket subscribers
+	apiHandler.Hub = broadcast.NewHub(apiHandler.QuestionPayload, 1*time.Second, logger)
+	apiHandler.OnAirHub = broadcast.NewHub(apiHandler.OnAirPayload, 1*time.Second, logger)
 
 	// API routes (public - no authentication required)
 	r.Route("/api", func(r chi.Router) {
-		apiHandler := &handlers.API{
-			Queries: queries,
+		// RESTful API for broadcast graphics
+		r.Group(func(r chi.Router) {
+			r.Use(timeout)
+
+			r.Get("/events/{eventID}", apiHandler.GetEvent)
+			r.Get("/events/{eventID}/questions", apiHandler.GetQuestions)
+			r.Get("/events/{eventID}/questions/{questionID}", apiHandler.GetQuestion)
+			r.Get("/events/{eventID}/scores", apiHandler.GetScores)
+			r.Get("/events/{eventID}/leaderboard", apiHandler.GetLeaderboard)
+		})
+
+		// Server-Sent Events and WebSocket for live graphics (no timeout, the connection stays open)
+		r.Get("/events/{eventID}/questions/{questionID}/stream", apiHandler.StreamQuestion)
+		r.Get("/events/{eventID}/ws", apiHandler.EventSocket)
+	})
+
+	// Broadcast overlays (public, drop into OBS/vMix as a browser source)
+	r.Route("/overlay", func(r chi.Router) {
+		r.Use(timeout)
+
+		r.Get("/{eventID}/question/{questionID}", apiHandler.ShowQuestionOverlay)
+	})
+
+	if cfg.IPHashSecret == "" {
+		log.Println("warning: IP_HASH_SECRET not set, fraud signal IP hashes will change on restart")
+	}
+
+	// Admin routes (authenticated)
+	if len(cfg.APIKeys) == 0 {
+		log.Println("warning: API_KEYS not set, admin API will reject all requests")
+	}
+	if cfg.AdminPassword == "" && len(cfg.APIKeys) == 0 {
+		log.Println("warning: ADMIN_PASSWORD and API_KEYS not set, admin console login is disabled")
+	}
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(timeout)
+
+		apiKeyMiddleware := &middleware.APIKey{
+			APIKeys: cfg.APIKeys,
 			Log:     logger,
-			BaseURL: cfg.BaseURL,
 		}
 
-		// RESTful API for broadcast graphics
-		r.Get("/events/{eventID}", apiHandler.GetEvent)
-		r.Get("/events/{eventID}/questions", apiHandler.GetQuestions)
-		r.Get("/events/{eventID}/questions/{questionID}", apiHandler.GetQuestion)
+		adminAPIHandler := &handlers.AdminAPI{
+			DB:         db,
+			Queries:    queries,
+			Log:        logger,
+			EventCache: eventCache,
+
+			SessionCache:      sessionCache,
+			SessionCacheStats: sessionCacheStats,
+
+			RetentionDays: cfg.PIIRetentionDays,
+		}
+
+		adminAuth := &middleware.AdminAuth{
+			Password:     cfg.AdminPassword,
+			APIKeys:      cfg.APIKeys,
+			SecureCookie: cfg.SecureCookie,
+			Log:          logger,
+		}
+
+		adminHandler := &handlers.Admin{
+			Queries:    queries,
+			Log:        logger,
+			EventCache: eventCache,
+			Auth:       adminAuth,
+			ImageDir:   "./static/images",
+		}
+
+		// Server-rendered console for producers (cookie login)
+		r.Get("/login", adminHandler.ShowLogin)
+		// Password guesses are limited per IP
+		loginLimiter := middleware.NewRateLimiter("admin-login-per-ip", cfg.RateLimitAdminLoginPerMinute, cfg.RateLimitAdminLoginPerMinute, middleware.KeyByIP, logger)
+		r.With(loginLimiter.ServeHTTP).Post("/login", adminHandler.Login)
+		r.Post("/logout", adminHandler.Logout)
+
+		r.Group(func(r chi.Router) {
+			r.Use(adminAuth.ServeHTTP)
+
+			r.Get("/", adminHandler.ShowDashboard)
+			r.Post("/events", adminHandler.CreateEvent)
+			r.Get("/events/{eventID}", adminHandler.ShowEvent)
+			r.Get("/events/{eventID}/counts", adminHandler.ShowCounts)
+			r.Post("/events/{eventID}/slugs", adminHandler.CreateSlug)
+			r.Post("/events/{eventID}/on-air", adminHandler.SetOnAir)
+			r.Post("/events/{eventID}/questions", adminHandler.CreateQuestion)
+			r.Post("/events/{eventID}/images", adminHandler.UploadImages)
+			r.Post("/questions/{questionID}", adminHandler.UpdateQuestion)
+			r.Post("/questions/{questionID}/result", adminHandler.SetQuestionResult)
+			r.Post("/questions/{questionID}/lock", adminHandler.LockQuestion)
+			r.Post("/questions/{questionID}/reopen", adminHandler.ReopenQuestion)
+		})
+
+		// JSON API for managing events, slugs and questions (X-API-Key required)
+		r.Route("/api", func(r chi.Router) {
+			r.Use(apiKeyMiddleware.ServeHTTP)
+
+			r.Get("/events", adminAPIHandler.ListEvents)
+			r.Post("/events", adminAPIHandler.CreateEvent)
+			r.Get("/events/{eventID}", adminAPIHandler.GetEvent)
+			r.Put("/events/{eventID}", adminAPIHandler.UpdateEvent)
+			r.Delete("/events/{eventID}", adminAPIHandler.DeleteEvent)
+			r.Put("/events/{eventID}/on-air", adminAPIHandler.SetOnAir)
+
+			r.Get("/events/{eventID}/slugs", adminAPIHandler.ListSlugs)
+			r.Post("/events/{eventID}/slugs", adminAPIHandler.CreateSlug)
+			r.Delete("/slugs/{slug}", adminAPIHandler.DeleteSlug)
+
+			r.Get("/events/{eventID}/questions", adminAPIHandler.ListQuestions)
+			r.Post("/events/{eventID}/questions", adminAPIHandler.CreateQuestion)
+			r.Get("/questions/{questionID}", adminAPIHandler.GetQuestion)
+			r.Put("/questions/{questionID}", adminAPIHandler.UpdateQuestion)
+			r.Delete("/questions/{questionID}", adminAPIHandler.DeleteQuestion)
+			r.Put("/questions/{questionID}/result", adminAPIHandler.SetQuestionResult)
+			r.Put("/questions/{questionID}/window", adminAPIHandler.SetQuestionWindow)
+			r.Post("/questions/{questionID}/lock", adminAPIHandler.LockQuestion)
+
+			r.Get("/events/{eventID}/scores", adminAPIHandler.ListScores)
+			r.Get("/events/{eventID}/draws", adminAPIHandler.ListDraws)
+			r.Post("/events/{eventID}/draws", adminAPIHandler.CreateDraw)
+			r.Get("/draws/{drawID}", adminAPIHandler.GetDraw)
+			r.Get("/draws/{drawID}/verify", adminAPIHandler.VerifyDraw)
+
+			r.Get("/events/{eventID}/fraud/clusters", adminAPIHandler.ListFlaggedClusters)
+			r.Get("/events/{eventID}/marketing-contacts", adminAPIHandler.ListMarketingContacts)
+
+			r.Get("/events/{eventID}/retention", adminAPIHandler.GetRetention)
+			r.Put("/events/{eventID}/retention", adminAPIHandler.SetRetention)
+			r.Post("/retention/run", adminAPIHandler.RunRetention)
+			r.Get("/retention/runs", adminAPIHandler.ListRetentionRuns)
+
+			r.Post("/gdpr/export", adminAPIHandler.ExportSubject)
+			r.Post("/gdpr/erase", adminAPIHandler.EraseSubject)
+
+			// Per-instance cache counters, as JSON and for Prometheus (scrape with the X-API-Key header)
+			r.Get("/cache/stats", adminAPIHandler.GetCacheStats)
+			r.Method(http.MethodGet, "/metrics", metrics.Handler(
+				metrics.Caches(
+					metrics.Cache{Name: "event", Stats: eventCache.Stats},
+					metrics.Cache{Name: "session", Stats: func() database.CacheStats {
+						return sessionCacheStats.Snapshot(sessionCache.ItemCount())
+					}},
+				),
+				metrics.Counter("pick6_fraud_signals_dropped_total",
+					"Vote signals dropped because the fraud signal queue was full.", signals.Dropped),
+			))
+		})
 	})
 
 	r.Route("/{slug}", func(r chi.Router) {
-		// Initialize event cache with 1 hour TTL (events/questions are static)
-		eventCache := database.NewEventCache(queries, 1*time.Hour, 2*time.Hour)
-
 		uiHandler := &handlers.UI{
+			DB:         db,
 			Queries:    queries,
 			Log:        logger,
 			EventCache: eventCache,
-		}
+			Signals:    signals,
+			FormTokens: formTokens,
 
-		// Initialize session cache with 5 minute default expiration and 10 minute cleanup interval
-		sessionCache := cache.New(5*time.Minute, 10*time.Minute)
+			PolicyVersion: cfg.PolicyVersion,
+			TermsURL:      cfg.TermsURL,
+		}
 
 		sessionMiddleware := &middleware.Session{
-			SecureCookie: cfg.SecureCookie,
-			Log:          logger,
-			Queries:      queries,
-			Cache:        sessionCache,
+			SecureCookie:  cfg.SecureCookie,
+			Log:           logger,
+			Queries:       queries,
+			Cache:         sessionCache,
+			CacheStats:    sessionCacheStats,
+			Secrets:       cfg.SessionSecrets,
+			AllowUnsigned: cfg.SessionAllowUnsigned,
+			CreateLimiter: middleware.NewRateLimiter("new-sessions-per-ip", cfg.RateLimitNewSessionsPerMinute, cfg.RateLimitNewSessionsBurst, middleware.KeyByIP, logger),
 		}
+		// Unknown slugs 404 before the session middleware, so they never create sessions
+		slugMiddleware := &middleware.Slug{
+			Events: eventCache,
+			Log:    logger,
+		}
+		r.Use(timeout)
+		r.Use(slugMiddleware.ServeHTTP)
 		r.Use(sessionMiddleware.ServeHTTP)
 
+		// Vote and signup POSTs are limited per IP and per session (runs after the session middleware)
+		ipLimiter := middleware.NewRateLimiter("posts-per-ip", cfg.RateLimitIPPerMinute, cfg.RateLimitIPBurst, middleware.KeyByIP, logger)
+		sessionLimiter := middleware.NewRateLimiter("posts-per-session", cfg.RateLimitSessionPerMinute, cfg.RateLimitSessionBurst, middleware.KeyBySession, logger)
+		limited := r.With(ipLimiter.ServeHTTP, sessionLimiter.ServeHTTP)
+
 		r.Get("/", uiHandler.RedirectToFirst)
 		r.Get("/question/{order}", uiHandler.ShowQuestion)
-		r.Post("/question/{order}", uiHandler.SubmitAnswer)
+		limited.Post("/question/{order}", uiHandler.SubmitAnswer)
 		r.Get("/submit-info", uiHandler.ShowInfoForm)
-		r.Post("/submit-info", uiHandler.SubmitInfoForm)
+		limited.Post("/submit-info", uiHandler.SubmitInfoForm)
 		r.Get("/end", uiHandler.ShowEnd)
 	})
 
@@ -0,0 +1,90 @@
+// Package metrics exposes runtime statistics in the Prometheus text format
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/mrbennbenn/pick6/database"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Cache is a named cache whose counters are read at scrape time
+type Cache struct {
+	Name  string
+	Stats func() database.CacheStats
+}
+
+// Handler serves /metrics for the given collectors plus the Go runtime and process collectors
+func Handler(cs ...prometheus.Collector) http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	registry.MustRegister(cs...)
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Caches reports the given caches' counters under pick6_cache_*{cache="<name>"}
+func Caches(caches ...Cache) prometheus.Collector {
+	return &cacheCollector{caches: caches}
+}
+
+// Counter reports a monotonic count read at scrape time
+func Counter(name, help string, value func() uint64) prometheus.Collector {
+	return prometheus.NewCounterFunc(prometheus.CounterOpts{Name: name, Help: help}, func() float64 {
+		return float64(value())
+	})
+}
+
+var (
+	cacheHits = prometheus.NewDesc("pick6_cache_hits_total",
+		"Lookups served from the cache.", []string{"cache"}, nil)
+	cacheMisses = prometheus.NewDesc("pick6_cache_misses_total",
+		"Lookups that went to the database.", []string{"cache"}, nil)
+	cacheNotFound = prometheus.NewDesc("pick6_cache_not_found_total",
+		"Lookups answered from the not-found cache.", []string{"cache"}, nil)
+	cacheLoadErrors = prometheus.NewDesc("pick6_cache_load_errors_total",
+		"Database loads after a miss that failed.", []string{"cache"}, nil)
+	cacheLoadDuration = prometheus.NewDesc("pick6_cache_load_duration_seconds",
+		"Time spent loading from the database on a miss or refresh.", []string{"cache"}, nil)
+	cacheRefreshes = prometheus.NewDesc("pick6_cache_refreshes_total",
+		"Background reloads of entries close to expiry.", []string{"cache"}, nil)
+	cacheEvictions = prometheus.NewDesc("pick6_cache_evictions_total",
+		"Entries expired, invalidated or flushed from the cache.", []string{"cache"}, nil)
+	cacheItems = prometheus.NewDesc("pick6_cache_items",
+		"Entries currently in the cache.", []string{"cache"}, nil)
+)
+
+// cacheCollector turns database.CacheStats into metrics on every scrape
+// The counters live in the caches, so there's nothing to keep in sync here
+type cacheCollector struct {
+	caches []Cache
+}
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheHits
+	ch <- cacheMisses
+	ch <- cacheNotFound
+	ch <- cacheLoadErrors
+	ch <- cacheLoadDuration
+	ch <- cacheRefreshes
+	ch <- cacheEvictions
+	ch <- cacheItems
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, cache := range c.caches {
+		stats := cache.Stats()
+		ch <- prometheus.MustNewConstMetric(cacheHits, prometheus.CounterValue, float64(stats.Hits), cache.Name)
+		ch <- prometheus.MustNewConstMetric(cacheMisses, prometheus.CounterValue, float64(stats.Misses), cache.Name)
+		ch <- prometheus.MustNewConstMetric(cacheNotFound, prometheus.CounterValue, float64(stats.NotFound), cache.Name)
+		ch <- prometheus.MustNewConstMetric(cacheLoadErrors, prometheus.CounterValue, float64(stats.LoadErrors), cache.Name)
+		ch <- prometheus.MustNewConstSummary(cacheLoadDuration, stats.Loads, stats.LoadSecondsTotal, nil, cache.Name)
+		ch <- prometheus.MustNewConstMetric(cacheRefreshes, prometheus.CounterValue, float64(stats.Refreshes), cache.Name)
+		ch <- prometheus.MustNewConstMetric(cacheEvictions, prometheus.CounterValue, float64(stats.Evictions), cache.Name)
+		ch <- prometheus.MustNewConstMetric(cacheItems, prometheus.GaugeValue, float64(stats.Items), cache.Name)
+	}
+}
@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	adminCookieName  = "admin_session"
+	adminSessionTTL  = 12 * time.Hour // Long enough to cover an event night
+	maxAdminFormSize = 20 << 20       // Largest console post, a batch of matchup images
+)
+
+// CSRFField is the hidden input console forms post their CSRF token in
+const CSRFField = "csrf_token"
+
+// AdminAuth protects the admin console with a password or API key login
+// The login cookie is stateless (expiry + HMAC), so it works across instances
+// and is invalidated automatically when the password or keys change
+type AdminAuth struct {
+	Password     string
+	APIKeys      []string
+	SecureCookie bool
+	Log          *log.Logger
+}
+
+// Enabled reports whether any credential is configured
+func (a *AdminAuth) Enabled() bool {
+	return a.Password != "" || len(a.APIKeys) > 0
+}
+
+// ServeHTTP redirects to the login page unless a valid admin cookie is present
+// Anything but GET and HEAD must also post the login's CSRF token: SameSite=Strict
+// keeps other sites' posts from carrying the cookie, the token covers older
+// browsers and pages on sibling subdomains, which count as same-site
+func (a *AdminAuth) ServeHTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(adminCookieName)
+		if err != nil || !a.validCookie(cookie.Value) {
+			http.Redirect(w, r, "/admin/login", http.StatusSeeOther)
+			return
+		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			// Reading the token parses the body, so cap it first
+			r.Body = http.MaxBytesReader(w, r.Body, maxAdminFormSize)
+			if err := parseAdminForm(r); err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					http.Error(w, "Form too large", http.StatusRequestEntityTooLarge)
+					return
+				}
+				http.Error(w, "Bad request", http.StatusBadRequest)
+				return
+			}
+			token := r.PostFormValue(CSRFField)
+			if token == "" || !hmac.Equal([]byte(token), []byte(a.csrfToken(cookie.Value))) {
+				if a.Log != nil {
+					a.Log.Printf("Admin CSRF check failed - path=%s remote=%s", r.URL.Path, r.RemoteAddr)
+				}
+				http.Error(w, "Form expired, reload the page and try again", http.StatusForbidden)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// CSRFToken returns the token console forms post back, empty without a valid login
+// It is derived from the login cookie, so every login gets a new one
+func (a *AdminAuth) CSRFToken(r *http.Request) string {
+	cookie, err := r.Cookie(adminCookieName)
+	if err != nil || !a.validCookie(cookie.Value) {
+		return ""
+	}
+	return a.csrfToken(cookie.Value)
+}
+
+// Login checks a password or API key and sets the admin cookie on success
+func (a *AdminAuth) Login(w http.ResponseWriter, r *http.Request, credential string) bool {
+	if !a.Enabled() || !a.validCredential(credential) {
+		if a.Log != nil {
+			a.Log.Printf("Admin login failed - remote=%s", r.RemoteAddr)
+		}
+		return false
+	}
+
+	expires := time.Now().Add(adminSessionTTL)
+	http.SetCookie(w, &http.Cookie{
+		Name:     adminCookieName,
+		Value:    a.sign(expires),
+		Path:     "/admin",
+		HttpOnly: true,
+		Secure:   a.SecureCookie,
+		SameSite: http.SameSiteStrictMode, // Blocks cross-site form posts to the console
+		Expires:  expires,
+	})
+
+	if a.Log != nil {
+		a.Log.Printf("Admin login success - remote=%s", r.RemoteAddr)
+	}
+	return true
+}
+
+// Logout clears the admin cookie
+func (a *AdminAuth) Logout(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     adminCookieName,
+		Value:    "",
+		Path:     "/admin",
+		HttpOnly: true,
+		Secure:   a.SecureCookie,
+		SameSite: http.SameSiteStrictMode,
+		MaxAge:   -1,
+	})
+}
+
+func (a *AdminAuth) validCredential(credential string) bool {
+	if credential == "" {
+		return false
+	}
+	if a.Password != "" && subtle.ConstantTimeCompare([]byte(credential), []byte(a.Password)) == 1 {
+		return true
+	}
+	for _, key := range a.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(credential), []byte(key)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// sign builds a cookie value of the form "<unix expiry>.<hex hmac>"
+func (a *AdminAuth) sign(expires time.Time) string {
+	payload := strconv.FormatInt(expires.Unix(), 10)
+	return fmt.Sprintf("%s.%s", payload, hex.EncodeToString(a.mac(payload)))
+}
+
+func (a *AdminAuth) validCookie(value string) bool {
+	if !a.Enabled() {
+		return false
+	}
+
+	payload, signature, found := strings.Cut(value, ".")
+	if !found {
+		return false
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, a.mac(payload)) {
+		return false
+	}
+
+	expiresUnix, err := strconv.ParseInt(payload, 10, 64)
+	if err != nil {
+		return false
+	}
+	return time.Now().Before(time.Unix(expiresUnix, 0))
+}
+
+// csrfToken is the HMAC of the cookie's expiry under the cookie key, in its own namespace
+func (a *AdminAuth) csrfToken(cookieValue string) string {
+	payload, _, _ := strings.Cut(cookieValue, ".")
+	return hex.EncodeToString(a.mac("csrf|" + payload))
+}
+
+// parseAdminForm parses a url-encoded or multipart body, keeping uploads in memory up to the cap
+func parseAdminForm(r *http.Request) error {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		return r.ParseMultipartForm(maxAdminFormSize)
+	}
+	return r.ParseForm()
+}
+
+// mac derives the signing key from the configured credentials
+func (a *AdminAuth) mac(payload string) []byte {
+	key := sha256.Sum256([]byte("pick6-admin|" + a.Password + "|" + strings.Join(a.APIKeys, ",")))
+	m := hmac.New(sha256.New, key[:])
+	m.Write([]byte(payload))
+	return m.Sum(nil)
+}
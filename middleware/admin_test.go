@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// login returns the admin cookie a successful login sets
+func login(t *testing.T, a *AdminAuth, credential string) *http.Cookie {
+	t.Helper()
+	w := httptest.NewRecorder()
+	if !a.Login(w, httptest.NewRequest(http.MethodPost, "/admin/login", nil), credential) {
+		t.Fatalf("Login(%q) failed", credential)
+	}
+	for _, c := range w.Result().Cookies() {
+		if c.Name == adminCookieName {
+			return c
+		}
+	}
+	t.Fatal("no admin cookie set")
+	return nil
+}
+
+func TestAdminLogin(t *testing.T) {
+	a := &AdminAuth{Password: "hunter2", APIKeys: []string{"key-one"}}
+
+	tests := []struct {
+		credential string
+		want       bool
+	}{
+		{"hunter2", true},
+		{"key-one", true},
+		{"", false},
+		{"hunter", false},
+		{"key-one ", false},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		if got := a.Login(w, httptest.NewRequest(http.MethodPost, "/admin/login", nil), tt.credential); got != tt.want {
+			t.Errorf("Login(%q) = %v, want %v", tt.credential, got, tt.want)
+		}
+	}
+
+	disabled := &AdminAuth{}
+	if disabled.Login(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/admin/login", nil), "") {
+		t.Error("login succeeded with no credentials configured")
+	}
+}
+
+func TestAdminCSRF(t *testing.T) {
+	a := &AdminAuth{Password: "hunter2"}
+	cookie := login(t, a, "hunter2")
+
+	page := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	page.AddCookie(cookie)
+	token := a.CSRFToken(page)
+	if token == "" {
+		t.Fatal("no CSRF token for a logged in request")
+	}
+	if a.CSRFToken(httptest.NewRequest(http.MethodGet, "/admin", nil)) != "" {
+		t.Fatal("CSRF token without a login")
+	}
+
+	// A token from another login (another password here) must not work
+	other := &AdminAuth{Password: "other"}
+	otherPage := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	otherPage.AddCookie(login(t, other, "other"))
+	otherToken := other.CSRFToken(otherPage)
+
+	tampered := []byte(token)
+	tampered[0] ^= 1 // One character off
+	handler := a.ServeHTTP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	form := func(values url.Values) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/admin/events", strings.NewReader(values.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return r
+	}
+	upload := func(token string) *http.Request {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		mw.WriteField(CSRFField, token)
+		part, _ := mw.CreateFormFile("images", "matchup.png")
+		part.Write([]byte("png"))
+		mw.Close()
+		r := httptest.NewRequest(http.MethodPost, "/admin/events/e/images", &body)
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		return r
+	}
+
+	tests := []struct {
+		name   string
+		req    *http.Request
+		cookie bool
+		status int
+	}{
+		{"page without login", httptest.NewRequest(http.MethodGet, "/admin", nil), false, http.StatusSeeOther},
+		{"page", httptest.NewRequest(http.MethodGet, "/admin", nil), true, http.StatusNoContent},
+		{"post with token", form(url.Values{"description": {"TK4"}, CSRFField: {token}}), true, http.StatusNoContent},
+		{"post without token", form(url.Values{"description": {"TK4"}}), true, http.StatusForbidden},
+		{"post with wrong token", form(url.Values{CSRFField: {string(tampered)}}), true, http.StatusForbidden},
+		{"post with another login's token", form(url.Values{CSRFField: {otherToken}}), true, http.StatusForbidden},
+		{"post without login", form(url.Values{CSRFField: {token}}), false, http.StatusSeeOther},
+		{"upload with token", upload(token), true, http.StatusNoContent},
+		{"upload without token", upload(""), true, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.cookie {
+				tt.req.AddCookie(cookie)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, tt.req)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"log"
 	"net/http"
 )
@@ -24,12 +25,12 @@ func (a *APIKey) ServeHTTP(next http.Handler) http.Handler {
 			return
 		}
 
-		// Check against known list of keys
+		// Check against known list of keys, in constant time so a key can't be
+		// guessed byte by byte from response timings
 		validKey := false
 		for _, key := range a.APIKeys {
-			if key == apiKey {
+			if subtle.ConstantTimeCompare([]byte(apiKey), []byte(key)) == 1 {
 				validKey = true
-				break
 			}
 		}
 
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIKey(t *testing.T) {
+	auth := &APIKey{APIKeys: []string{"key-one", "key-two"}}
+	handler := auth.ServeHTTP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name   string
+		key    string
+		status int
+	}{
+		{"missing", "", http.StatusUnauthorized},
+		{"unknown", "key-three", http.StatusUnauthorized},
+		{"prefix of a key", "key-on", http.StatusUnauthorized},
+		{"key with a suffix", "key-one2", http.StatusUnauthorized},
+		{"first key", "key-one", http.StatusNoContent},
+		{"second key", "key-two", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/admin/api/events", nil)
+			if tt.key != "" {
+				r.Header.Set("X-API-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
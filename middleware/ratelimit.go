@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mrbennbenn/pick6/templates"
+	cache "github.com/patrickmn/go-cache"
+	"golang.org/x/time/rate"
+)
+
+// KeyFunc picks the bucket a request is charged to
+type KeyFunc func(r *http.Request) string
+
+// RateLimiter is a keyed token-bucket limiter
+// Buckets live in a go-cache so idle keys are evicted and memory stays bounded
+// A nil *RateLimiter allows everything, so a limit can be disabled with a zero rate
+type RateLimiter struct {
+	Name    string // Used in log lines, e.g. "votes-per-session"
+	Limit   rate.Limit
+	Burst   int
+	Key     KeyFunc
+	Log     *log.Logger
+	buckets *cache.Cache
+}
+
+// NewRateLimiter allows perMinute requests per key with bursts of up to burst
+// Returns nil (no limit) if perMinute is zero or negative
+func NewRateLimiter(name string, perMinute, burst int, key KeyFunc, logger *log.Logger) *RateLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+	return &RateLimiter{
+		Name:    name,
+		Limit:   rate.Limit(float64(perMinute) / 60),
+		Burst:   burst,
+		Key:     key,
+		Log:     logger,
+		buckets: cache.New(10*time.Minute, 10*time.Minute), // A bucket idle for 10 minutes is full again anyway
+	}
+}
+
+// ServeHTTP rejects requests over the limit with the 429 page
+func (l *RateLimiter) ServeHTTP(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.Allow(r) {
+			rateLimited(w, r, l.RetryAfter(), l.Log)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Allow takes a token from the request's bucket
+func (l *RateLimiter) Allow(r *http.Request) bool {
+	if l == nil {
+		return true
+	}
+
+	key := l.Key(r)
+	if l.bucket(key).Allow() {
+		return true
+	}
+
+	if l.Log != nil {
+		l.Log.Printf("Rate limited (%s): key=%s path=%s remote=%s", l.Name, key, r.URL.Path, r.RemoteAddr)
+	}
+	return false
+}
+
+// RetryAfter is how long until a drained bucket has a token again
+func (l *RateLimiter) RetryAfter() time.Duration {
+	if l == nil || l.Limit <= 0 {
+		return time.Second
+	}
+	return time.Duration(math.Ceil(float64(time.Second) / float64(l.Limit)))
+}
+
+func (l *RateLimiter) bucket(key string) *rate.Limiter {
+	if existing, found := l.buckets.Get(key); found {
+		return existing.(*rate.Limiter)
+	}
+
+	limiter := rate.NewLimiter(l.Limit, l.Burst)
+	if err := l.buckets.Add(key, limiter, cache.DefaultExpiration); err != nil {
+		// Another request created it first - use theirs so both share the bucket
+		if existing, found := l.buckets.Get(key); found {
+			return existing.(*rate.Limiter)
+		}
+	}
+	return limiter
+}
+
+// KeyByIP buckets by client IP
+// Relies on chimiddleware.RealIP having rewritten RemoteAddr from X-Forwarded-For / X-Real-IP
+func KeyByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr // RealIP sets a bare IP without a port
+	}
+	return host
+}
+
+// KeyBySession buckets by vote session, falling back to IP before a session exists
+func KeyBySession(r *http.Request) string {
+	if sessionID, err := SessionFromCtx(r.Context()); err == nil {
+		return sessionID
+	}
+	return "ip:" + KeyByIP(r)
+}
+
+// rateLimited renders the 429 page with a Retry-After header
+func rateLimited(w http.ResponseWriter, r *http.Request, retryAfter time.Duration, logger *log.Logger) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusTooManyRequests)
+	vm := templates.RateLimitedViewModel{RetryURL: retryURL(r), RetryAfter: seconds}
+	if err := templates.RateLimitedPage(vm).Render(r.Context(), w); err != nil && logger != nil {
+		logger.Printf("Failed to render rate limited page: %v", err)
+	}
+}
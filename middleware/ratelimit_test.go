@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func requestFrom(remoteAddr string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/event/final/questions/1", nil)
+	r.RemoteAddr = remoteAddr
+	return r
+}
+
+func TestRateLimiterBurst(t *testing.T) {
+	tests := []struct {
+		name      string
+		perMinute int
+		burst     int
+		allowed   int
+	}{
+		{"burst of three", 1, 3, 3},
+		{"burst defaults to one", 1, 0, 1},
+		{"negative burst", 1, -5, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewRateLimiter("test", tt.perMinute, tt.burst, KeyByIP, nil)
+			for i := 0; i < tt.allowed; i++ {
+				if !l.Allow(requestFrom("203.0.113.1:1234")) {
+					t.Fatalf("request %d rejected within the burst", i+1)
+				}
+			}
+			if l.Allow(requestFrom("203.0.113.1:5678")) {
+				t.Fatal("request over the burst allowed")
+			}
+			// Buckets are per key, another client still has a full one
+			if !l.Allow(requestFrom("203.0.113.2:1234")) {
+				t.Fatal("other key rejected")
+			}
+		})
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	for _, perMinute := range []int{0, -1} {
+		l := NewRateLimiter("test", perMinute, 1, KeyByIP, nil)
+		if l != nil {
+			t.Fatalf("NewRateLimiter(%d) = %v, want nil", perMinute, l)
+		}
+		for i := 0; i < 100; i++ {
+			if !l.Allow(requestFrom("203.0.113.1:1234")) {
+				t.Fatal("nil limiter rejected a request")
+			}
+		}
+	}
+}
+
+func TestRateLimiterRetryAfter(t *testing.T) {
+	tests := []struct {
+		perMinute int
+		want      time.Duration
+		header    string
+	}{
+		{60, time.Second, "1"},
+		{30, 2 * time.Second, "2"},
+		{6, 10 * time.Second, "10"},
+		{120, 500 * time.Millisecond, "1"}, // Retry-After is whole seconds, at least one
+		{7, 8571428572 * time.Nanosecond, "9"},
+	}
+	for _, tt := range tests {
+		l := NewRateLimiter("test", tt.perMinute, 1, KeyByIP, nil)
+		if got := l.RetryAfter(); got != tt.want {
+			t.Errorf("RetryAfter(%d/min) = %v, want %v", tt.perMinute, got, tt.want)
+		}
+
+		handler := l.ServeHTTP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		for _, wantStatus := range []int{http.StatusOK, http.StatusTooManyRequests} {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, requestFrom("203.0.113.1:1234"))
+			if w.Code != wantStatus {
+				t.Fatalf("%d/min: status %d, want %d", tt.perMinute, w.Code, wantStatus)
+			}
+			if wantStatus == http.StatusTooManyRequests && w.Header().Get("Retry-After") != tt.header {
+				t.Errorf("%d/min: Retry-After %q, want %q", tt.perMinute, w.Header().Get("Retry-After"), tt.header)
+			}
+		}
+	}
+
+	var disabled *RateLimiter
+	if got := disabled.RetryAfter(); got != time.Second {
+		t.Errorf("nil RetryAfter = %v, want 1s", got)
+	}
+}
+
+func TestKeyByIP(t *testing.T) {
+	tests := []struct {
+		remoteAddr string
+		want       string
+	}{
+		{"203.0.113.1:1234", "203.0.113.1"},
+		{"[2001:db8::1]:443", "2001:db8::1"},
+		{"203.0.113.1", "203.0.113.1"}, // RealIP leaves no port
+	}
+	for _, tt := range tests {
+		if got := KeyByIP(requestFrom(tt.remoteAddr)); got != tt.want {
+			t.Errorf("KeyByIP(%q) = %q, want %q", tt.remoteAddr, got, tt.want)
+		}
+	}
+}
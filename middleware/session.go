@@ -2,36 +2,76 @@ package middleware
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/mrbennbenn/pick6/database"
+	"github.com/mrbennbenn/pick6/database/pii"
+	"github.com/mrbennbenn/pick6/templates"
 	cache "github.com/patrickmn/go-cache"
 	"github.com/segmentio/ksuid"
 )
 
+const (
+	sessionCookieName = "vote_session"
+	sessionIDPrefix   = "voter_"
+)
+
 type Session struct {
 	SecureCookie bool
 	Log          *log.Logger
 	Queries      *database.Queries
-	Cache        *cache.Cache // In-memory cache for session validation
+	Cache        *cache.Cache            // In-memory cache for session validation
+	CacheStats   *database.CacheCounters // Hit/miss/load counters for Cache (nil = not counted)
+
+	// Secrets sign the cookie as "<sessionID>.<hmac>", the first signs and all verify,
+	// so a new secret can be prepended and the old one dropped a day later
+	// With no secrets, cookies are unsigned (legacy behaviour)
+	Secrets []string
+
+	// AllowUnsigned accepts bare "voter_<ksuid>" cookies issued before signing was
+	// enabled, checks them against the database and re-issues them signed
+	AllowUnsigned bool
+
+	// CreateLimiter caps how fast one client can mint new sessions rows
+	// Keyed by IP, so cookie-less bots can't flood the sessions table (nil = unlimited)
+	CreateLimiter *RateLimiter
 }
 
+// cookieStatus is the outcome of checking a vote_session cookie's signature
+type cookieStatus int
+
+const (
+	cookieInvalid  cookieStatus = iota // Forged, malformed or signed with an unknown key
+	cookieValid                        // Signed with the current key
+	cookieReissue                      // Genuine but needs re-signing (old key or legacy unsigned)
+	cookieUnsigned                     // Legacy unsigned, must be checked against the database
+)
+
 func (s *Session) ServeHTTP(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		cookie, err := r.Cookie("vote_session")
 		if err != nil {
 			// No cookie found - create new session
+			if !s.CreateLimiter.Allow(r) {
+				rateLimited(w, r, s.CreateLimiter.RetryAfter(), s.Log)
+				return
+			}
 			sessionID, err := s.createSession(w, r)
 			if err != nil {
 				if s.Log != nil {
 					s.Log.Printf("Session creation failed: %v - path=%s remote=%s", err, r.URL.Path, r.RemoteAddr)
 				}
-				http.Error(w, "could not generate user session", http.StatusInternalServerError)
+				s.unavailable(w, r)
 				return
 			}
 
@@ -43,40 +83,80 @@ func (s *Session) ServeHTTP(next http.Handler) http.Handler {
 			return
 		}
 
-		// Cookie exists - validate it's a valid session
-		if err := cookie.Valid(); err != nil {
+		// Cookie exists - check its signature before touching the cache or database
+		sessionID, status := s.verifyCookie(cookie.Value)
+		if err := cookie.Valid(); err != nil || status == cookieInvalid {
 			if s.Log != nil {
 				s.Log.Printf("Session auth failed: invalid cookie - path=%s remote=%s", r.URL.Path, r.RemoteAddr)
 			}
+			// Clear it so the next request starts a fresh session
+			s.clearCookie(w)
 			http.Error(w, "invalid vote_session cookie", http.StatusUnauthorized)
 			return
 		}
+		// Re-sign after the session is confirmed, never before, so a guessed
+		// unsigned ID can't be laundered into a signed cookie
+		reissue := status == cookieReissue || (status == cookieUnsigned && len(s.Secrets) > 0)
 
 		// Validate session exists - check cache first to reduce DB load
-		sessionID := cookie.Value
 
 		// Check cache first
 		if s.Cache != nil {
 			if _, found := s.Cache.Get(sessionID); found {
 				// Session found in cache, skip DB query
+				s.CacheStats.Hit()
 				if s.Log != nil {
 					s.Log.Printf("Session auth success (cached): %s - path=%s remote=%s", sessionID, r.URL.Path, r.RemoteAddr)
 				}
+				if reissue {
+					s.setCookie(w, sessionID)
+				}
 				next.ServeHTTP(w, r.WithContext(ctxWithSession(r.Context(), sessionID)))
 				return
 			}
 		}
 
 		// Not in cache, validate against database (with retry for transient failures)
+		s.CacheStats.Miss()
+		start := time.Now()
 		err = database.WithRetry(r.Context(), database.DefaultRetryConfig(), func() error {
 			_, queryErr := s.Queries.GetSession(r.Context(), sessionID)
 			return queryErr
 		})
+		if errors.Is(err, sql.ErrNoRows) {
+			s.CacheStats.Load(time.Since(start), nil) // An answer, just not a session
+		} else {
+			s.CacheStats.Load(time.Since(start), err)
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			// The cookie is genuine but its row is gone (e.g. after a database reset)
+			// Start a fresh session rather than locking the fan out
+			if !s.CreateLimiter.Allow(r) {
+				rateLimited(w, r, s.CreateLimiter.RetryAfter(), s.Log)
+				return
+			}
+			newSessionID, createErr := s.createSession(w, r)
+			if createErr != nil {
+				if s.Log != nil {
+					s.Log.Printf("Session re-issue failed: %v - staleSessionID=%s path=%s remote=%s", createErr, sessionID, r.URL.Path, r.RemoteAddr)
+				}
+				s.unavailable(w, r)
+				return
+			}
+
+			if s.Log != nil {
+				s.Log.Printf("Session re-issued: %s replaces unknown %s - path=%s remote=%s", newSessionID, sessionID, r.URL.Path, r.RemoteAddr)
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctxWithSession(r.Context(), newSessionID)))
+			return
+		}
 		if err != nil {
+			// Transient failure - keep the cookie so the retry picks up the same session
 			if s.Log != nil {
-				s.Log.Printf("Session auth failed: session not found in database - sessionID=%s path=%s remote=%s error=%v", sessionID, r.URL.Path, r.RemoteAddr, err)
+				s.Log.Printf("Session auth failed: database unavailable - sessionID=%s path=%s remote=%s error=%v", sessionID, r.URL.Path, r.RemoteAddr, err)
 			}
-			http.Error(w, "invalid session", http.StatusUnauthorized)
+			s.unavailable(w, r)
 			return
 		}
 
@@ -88,21 +168,24 @@ func (s *Session) ServeHTTP(next http.Handler) http.Handler {
 		if s.Log != nil {
 			s.Log.Printf("Session auth success: %s - path=%s remote=%s", sessionID, r.URL.Path, r.RemoteAddr)
 		}
+		if reissue {
+			s.setCookie(w, sessionID)
+		}
 
 		next.ServeHTTP(w, r.WithContext(ctxWithSession(r.Context(), sessionID)))
 	})
 }
 
 func (s *Session) createSession(w http.ResponseWriter, r *http.Request) (string, error) {
-	sessionID := fmt.Sprintf("voter_%s", ksuid.New().String())
+	sessionID := sessionIDPrefix + ksuid.New().String()
 
 	// Insert session into database with NULL fields (will be updated later) with retry
 	err := database.WithRetry(r.Context(), database.DefaultRetryConfig(), func() error {
 		_, queryErr := s.Queries.UpsertSession(r.Context(), database.UpsertSessionParams{
 			SessionID: sessionID,
-			Name:      sql.NullString{Valid: false},
-			Email:     sql.NullString{Valid: false},
-			Mobile:    sql.NullString{Valid: false},
+			Name:      pii.String{Valid: false},
+			Email:     pii.String{Valid: false},
+			Mobile:    pii.String{Valid: false},
 		})
 		return queryErr
 	})
@@ -110,23 +193,114 @@ func (s *Session) createSession(w http.ResponseWriter, r *http.Request) (string,
 		return "", fmt.Errorf("failed to insert session into database: %w", err)
 	}
 
-	// Set simple cookie with just the session ID
+	s.setCookie(w, sessionID)
+
+	// Cache the new session immediately
+	if s.Cache != nil {
+		s.Cache.Set(sessionID, true, 5*time.Minute)
+	}
+
+	return sessionID, nil
+}
+
+// unavailable renders the retryable 503 page used when the database can't be reached
+func (s *Session) unavailable(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Retry-After", "5")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	if err := templates.UnavailablePage(templates.UnavailableViewModel{RetryURL: retryURL(r)}).Render(r.Context(), w); err != nil && s.Log != nil {
+		s.Log.Printf("Failed to render unavailable page: %v", err)
+	}
+}
+
+// retryURL is where a "Try again" link on an error page should point
+func retryURL(r *http.Request) string {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return r.URL.RequestURI()
+	}
+	if ref, err := url.Parse(r.Referer()); err == nil && ref.Path != "" {
+		// Form posts can't be retried with a link, send them back to the page they came from
+		return ref.Path
+	}
+	return "/"
+}
+
+// setCookie sets the vote_session cookie, signed with the current secret if configured
+func (s *Session) setCookie(w http.ResponseWriter, sessionID string) {
+	value := sessionID
+	if len(s.Secrets) > 0 {
+		value = sessionID + "." + signSessionID(s.Secrets[0], sessionID)
+	}
+
 	http.SetCookie(w, &http.Cookie{
-		Name:     "vote_session",
-		Value:    sessionID,
+		Name:     sessionCookieName,
+		Value:    value,
 		Path:     "/",
 		HttpOnly: true,
 		Secure:   s.SecureCookie,
 		SameSite: http.SameSiteLaxMode,
 		MaxAge:   86400, // 24 hours in seconds
 	})
+}
 
-	// Cache the new session immediately
-	if s.Cache != nil {
-		s.Cache.Set(sessionID, true, 5*time.Minute)
+func (s *Session) clearCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   s.SecureCookie,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1,
+	})
+}
+
+// verifyCookie checks a cookie value without any I/O and returns the session ID it carries
+func (s *Session) verifyCookie(value string) (string, cookieStatus) {
+	sessionID, signature, signed := strings.Cut(value, ".")
+	if !validSessionID(sessionID) {
+		return "", cookieInvalid
 	}
 
-	return sessionID, nil
+	if !signed {
+		if len(s.Secrets) == 0 || s.AllowUnsigned {
+			return sessionID, cookieUnsigned
+		}
+		return "", cookieInvalid
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return "", cookieInvalid
+	}
+	for i, secret := range s.Secrets {
+		expected, _ := base64.RawURLEncoding.DecodeString(signSessionID(secret, sessionID))
+		if hmac.Equal(mac, expected) {
+			if i == 0 {
+				return sessionID, cookieValid
+			}
+			return sessionID, cookieReissue // Rotated key, re-sign with the current one
+		}
+	}
+	return "", cookieInvalid
+}
+
+// signSessionID returns the base64url HMAC-SHA256 of a session ID
+func signSessionID(secret, sessionID string) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte(sessionID))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
+
+// validSessionID reports whether id looks like "voter_<ksuid>"
+func validSessionID(id string) bool {
+	raw, ok := strings.CutPrefix(id, sessionIDPrefix)
+	if !ok {
+		return false
+	}
+	_, err := ksuid.Parse(raw)
+	return err == nil
 }
 
 type sessionCtxKeyType string
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mrbennbenn/pick6/database"
+)
+
+// Slug 404s requests for slugs with no event before the session middleware runs,
+// so scanners probing /wp-admin/ and friends never create sessions rows
+// Lookups go through EventCache, which remembers unknown slugs for a short while
+type Slug struct {
+	Events *database.EventCache
+	Log    *log.Logger
+}
+
+func (s *Slug) ServeHTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slug := chi.URLParam(r, "slug")
+		if !database.ValidSlug(slug) {
+			http.NotFound(w, r)
+			return
+		}
+
+		_, err := s.Events.GetEventWithQuestionsBySlug(r.Context(), slug)
+		if database.IsNotFound(err) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil && s.Log != nil {
+			// Let the request through, the handler retries and shows the unavailable page
+			s.Log.Printf("Slug check failed: %v - slug=%s path=%s remote=%s", err, slug, r.URL.Path, r.RemoteAddr)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,169 @@
+// Package retention purges fans' contact details once an event's retention period is over
+//
+// An event closes at its last question window or its last vote, whichever is
+// later. Once its retention period (its own policy, or the default) has passed
+// since then, its sessions lose their name, email, mobile and blind indexes,
+// and its entrants, consents and fraud signals are deleted. Votes are kept, so
+// analytics, scores and tallies are unaffected.
+//
+// Every run is recorded in retention_runs. Shared by the background worker,
+// the admin API and the `pick6 retention` command
+package retention
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/mrbennbenn/pick6/database"
+	"github.com/segmentio/ksuid"
+)
+
+// Trigger records what started a run
+type Trigger string
+
+const (
+	TriggerWorker Trigger = "worker"
+	TriggerCLI    Trigger = "cli"
+	TriggerAdmin  Trigger = "admin"
+)
+
+// Options controls a run
+type Options struct {
+	DefaultDays int  // Retention for events without their own policy, 0 leaves them alone
+	DryRun      bool // Purge inside a transaction that is rolled back, to see what would go
+	Trigger     Trigger
+}
+
+// Result summarises a run
+type Result struct {
+	RunID           string        `json:"run_id"`
+	DryRun          bool          `json:"dry_run"`
+	Skipped         bool          `json:"skipped"` // Another instance was already purging
+	Events          []EventResult `json:"events"`
+	SessionsPurged  int64         `json:"sessions_purged"`
+	EntrantsDeleted int64         `json:"entrants_deleted"`
+	ConsentsDeleted int64         `json:"consents_deleted"`
+	SignalsDeleted  int64         `json:"signals_deleted"`
+}
+
+// EventResult is what was purged for one event
+type EventResult struct {
+	EventID         string    `json:"event_id"`
+	ClosedAt        time.Time `json:"closed_at"`
+	RetentionDays   int32     `json:"retention_days"`
+	SessionsPurged  int64     `json:"sessions_purged"`
+	EntrantsDeleted int64     `json:"entrants_deleted"`
+	ConsentsDeleted int64     `json:"consents_deleted"`
+	SignalsDeleted  int64     `json:"signals_deleted"`
+}
+
+// Run purges every expired event in one transaction and records the run
+// A failed run is rolled back and recorded with its error
+func Run(ctx context.Context, db *sql.DB, opts Options) (*Result, error) {
+	if opts.DefaultDays < 0 {
+		return nil, fmt.Errorf("default retention must be 0 or more days, got %d", opts.DefaultDays)
+	}
+
+	startedAt := time.Now().UTC()
+	result := &Result{
+		RunID:  fmt.Sprintf("retention_%s", ksuid.New().String()),
+		DryRun: opts.DryRun,
+		Events: []EventResult{},
+	}
+
+	runErr := purge(ctx, db, opts, result)
+	if result.Skipped {
+		return result, nil
+	}
+
+	eventIDs := make([]string, len(result.Events))
+	for i, e := range result.Events {
+		eventIDs[i] = e.EventID
+	}
+	audit := database.CreateRetentionRunParams{
+		RunID:       result.RunID,
+		Trigger:     string(opts.Trigger),
+		DryRun:      opts.DryRun,
+		DefaultDays: int32(opts.DefaultDays),
+		EventIds:    eventIDs,
+		StartedAt:   startedAt,
+	}
+	if runErr != nil {
+		// Nothing was kept, only the events it tried are worth recording
+		audit.Error = sql.NullString{String: runErr.Error(), Valid: true}
+	} else {
+		audit.SessionsPurged = result.SessionsPurged
+		audit.EntrantsDeleted = result.EntrantsDeleted
+		audit.ConsentsDeleted = result.ConsentsDeleted
+		audit.SignalsDeleted = result.SignalsDeleted
+	}
+	if err := database.New(db).CreateRetentionRun(ctx, audit); err != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("%w (and recording the run failed: %v)", runErr, err)
+		}
+		return nil, fmt.Errorf("record run: %w", err)
+	}
+
+	if runErr != nil {
+		return nil, runErr
+	}
+	return result, nil
+}
+
+func purge(ctx context.Context, db *sql.DB, opts Options, result *Result) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	queries := database.New(db).WithTx(tx)
+
+	locked, err := queries.TryRetentionLock(ctx)
+	if err != nil {
+		return fmt.Errorf("retention lock: %w", err)
+	}
+	if !locked {
+		result.Skipped = true
+		return nil
+	}
+
+	expired, err := queries.ListExpiredEvents(ctx, int32(opts.DefaultDays))
+	if err != nil {
+		return fmt.Errorf("list expired events: %w", err)
+	}
+
+	// In close order, so a session shared with a later event is purged with that one
+	for _, e := range expired {
+		ev := EventResult{EventID: e.EventID, ClosedAt: e.ClosedAt, RetentionDays: e.RetentionDays}
+		result.Events = append(result.Events, ev)
+
+		if ev.SessionsPurged, err = queries.PurgeEventSessions(ctx, e.EventID); err != nil {
+			return fmt.Errorf("purge sessions for %s: %w", e.EventID, err)
+		}
+		if ev.EntrantsDeleted, err = queries.DeleteEntrantsByEvent(ctx, e.EventID); err != nil {
+			return fmt.Errorf("delete entrants for %s: %w", e.EventID, err)
+		}
+		if ev.ConsentsDeleted, err = queries.DeleteConsentsByEvent(ctx, e.EventID); err != nil {
+			return fmt.Errorf("delete consents for %s: %w", e.EventID, err)
+		}
+		if ev.SignalsDeleted, err = queries.DeleteResponseSignalsByEvent(ctx, e.EventID); err != nil {
+			return fmt.Errorf("delete signals for %s: %w", e.EventID, err)
+		}
+		if err := queries.MarkEventPurged(ctx, e.EventID); err != nil {
+			return fmt.Errorf("mark %s purged: %w", e.EventID, err)
+		}
+
+		result.Events[len(result.Events)-1] = ev
+		result.SessionsPurged += ev.SessionsPurged
+		result.EntrantsDeleted += ev.EntrantsDeleted
+		result.ConsentsDeleted += ev.ConsentsDeleted
+		result.SignalsDeleted += ev.SignalsDeleted
+	}
+
+	if opts.DryRun {
+		return nil // Rolled back by the deferred Rollback
+	}
+	return tx.Commit()
+}
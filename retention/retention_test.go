@@ -0,0 +1,123 @@
+package retention_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/mrbennbenn/pick6/database/dbtest"
+	"github.com/mrbennbenn/pick6/retention"
+)
+
+// Seeded by the initial migration
+const (
+	seedEvent    = "event_39aJ1km3pr9v1yQYX5gS88e3CUM"
+	seedQuestion = "question_39aJ1eE9ihQ3hH9kmOfKdCSueFP"
+)
+
+// fixture stores a fan who voted in the seed event only and one who also
+// voted in a second event, with the seed event's votes long past
+func fixture(t *testing.T, db *sql.DB) {
+	t.Helper()
+	statements := []string{
+		`INSERT INTO events (event_id, description) VALUES ('event_later', 'Total Kombat 4')`,
+		`INSERT INTO slugs (slug, event_id) VALUES ('tk04', 'event_later')`,
+		`INSERT INTO questions (question_id, event_id, big_text, small_text, image_filename, choice_a, choice_b)
+			VALUES ('question_later', 'event_later', 'A vs B', '', 'matchup.png', 'A', 'B')`,
+		`INSERT INTO sessions (session_id, name, email, mobile) VALUES
+			('session_only', 'Fan One', 'one@example.com', '+447700900001'),
+			('session_shared', 'Fan Two', 'two@example.com', '+447700900002')`,
+		`INSERT INTO responses (question_id, session_id, slug, choice, created_at) VALUES
+			('` + seedQuestion + `', 'session_only', 'tk03', 'a', NOW() - INTERVAL '60 days'),
+			('` + seedQuestion + `', 'session_shared', 'tk03', 'b', NOW() - INTERVAL '60 days'),
+			('question_later', 'session_shared', 'tk04', 'a', NOW())`,
+		`INSERT INTO event_retention (event_id, retention_days) VALUES
+			('` + seedEvent + `', 30),
+			('event_later', 30)`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("fixture: %v", err)
+		}
+	}
+}
+
+// hasContact reports whether a session still holds any contact details
+func hasContact(t *testing.T, db *sql.DB, sessionID string) bool {
+	t.Helper()
+	var has bool
+	err := db.QueryRow(`SELECT name IS NOT NULL OR email IS NOT NULL OR mobile IS NOT NULL
+		FROM sessions WHERE session_id = $1`, sessionID).Scan(&has)
+	if err != nil {
+		t.Fatalf("session %s: %v", sessionID, err)
+	}
+	return has
+}
+
+func TestRunSkipsSharedSessions(t *testing.T) {
+	db := dbtest.Open(t)
+	fixture(t, db)
+	ctx := context.Background()
+
+	// A dry run reports the purge but keeps everything
+	dry, err := retention.Run(ctx, db, retention.Options{DryRun: true, Trigger: retention.TriggerCLI})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if len(dry.Events) != 1 || dry.SessionsPurged != 1 {
+		t.Fatalf("dry run %+v, want the seed event with one session", dry)
+	}
+	if !hasContact(t, db, "session_only") {
+		t.Fatal("dry run purged a session")
+	}
+
+	result, err := retention.Run(ctx, db, retention.Options{Trigger: retention.TriggerCLI})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(result.Events) != 1 || result.Events[0].EventID != seedEvent {
+		t.Fatalf("purged %+v, want only the seed event", result.Events)
+	}
+	if result.SessionsPurged != 1 {
+		t.Errorf("%d sessions purged, want 1", result.SessionsPurged)
+	}
+	if hasContact(t, db, "session_only") {
+		t.Error("session_only still holds contact details")
+	}
+	// Still needed for the later event, which hasn't expired
+	if !hasContact(t, db, "session_shared") {
+		t.Error("session_shared purged while its later event is unpurged")
+	}
+
+	// Votes are kept
+	var votes int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM responses`).Scan(&votes); err != nil {
+		t.Fatalf("count responses: %v", err)
+	}
+	if votes != 3 {
+		t.Errorf("%d votes left, want 3", votes)
+	}
+
+	// Once the later event expires too, the shared session goes with it
+	if _, err := db.Exec(`UPDATE responses SET created_at = NOW() - INTERVAL '60 days' WHERE question_id = 'question_later'`); err != nil {
+		t.Fatalf("age later event: %v", err)
+	}
+	result, err = retention.Run(ctx, db, retention.Options{Trigger: retention.TriggerCLI})
+	if err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	if len(result.Events) != 1 || result.Events[0].EventID != "event_later" || result.SessionsPurged != 1 {
+		t.Fatalf("second run %+v, want event_later with one session", result)
+	}
+	if hasContact(t, db, "session_shared") {
+		t.Error("session_shared still holds contact details")
+	}
+
+	var runs int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM retention_runs`).Scan(&runs); err != nil {
+		t.Fatalf("count runs: %v", err)
+	}
+	if runs != 3 {
+		t.Errorf("%d runs recorded, want 3", runs)
+	}
+}
@@ -0,0 +1,59 @@
+package retention
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// Worker runs retention on a schedule
+type Worker struct {
+	DB          *sql.DB
+	DefaultDays int
+	Interval    time.Duration
+	Log         *log.Logger
+}
+
+// NewWorker creates a worker, or returns nil (disabled) when interval is zero
+func NewWorker(db *sql.DB, defaultDays int, interval time.Duration, logger *log.Logger) *Worker {
+	if interval <= 0 {
+		return nil
+	}
+	return &Worker{DB: db, DefaultDays: defaultDays, Interval: interval, Log: logger}
+}
+
+// Start runs once straight away and then every interval until ctx is done
+// Safe to call on a nil Worker, which does nothing
+func (w *Worker) Start(ctx context.Context) {
+	if w == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(w.Interval)
+		defer ticker.Stop()
+		for {
+			w.runOnce(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (w *Worker) runOnce(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	result, err := Run(ctx, w.DB, Options{DefaultDays: w.DefaultDays, Trigger: TriggerWorker})
+	if err != nil {
+		w.Log.Printf("Retention run failed: %v", err)
+		return
+	}
+	if len(result.Events) > 0 {
+		w.Log.Printf("Retention run %s: purged %d sessions across %d events",
+			result.RunID, result.SessionsPurged, len(result.Events))
+	}
+}
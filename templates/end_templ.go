@@ -12,8 +12,10 @@ import "fmt"
 
 // EndViewModel contains all data needed for the thank you/end page
 type EndViewModel struct {
-	Slug         string
-	TotalAnswers int
+	Slug           string
+	TotalAnswers   int
+	AlreadyEntered bool              // Details matched an earlier entry, so this device was linked to it
+	Results        *ResultsViewModel // Nil until at least one official result is in
 }
 
 // EndPage is the main component for the thank you page
@@ -75,13 +77,29 @@ func EndContent(vm EndViewModel) templ.Component {
 		var templ_7745c5c3_Var3 string
 		templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("%d", vm.TotalAnswers))
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/end.templ`, Line: 24, Col: 79}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `templates/end.templ`, Line: 26, Col: 79}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, " predictions and entered the £1,000 VVIP prize draw.</p></div><div class=\"entry-details\"><h3>What happens next?</h3><div class=\"next-steps\"><div class=\"step\"><span class=\"step-icon\">🎲</span><div class=\"step-text\"><strong>The Draw</strong><p>Winner will be selected after TK03 concludes</p></div></div><div class=\"step\"><span class=\"step-icon\">📞</span><div class=\"step-text\"><strong>We'll Contact You</strong><p>If you win, we'll reach out using the details you provided</p></div></div><div class=\"step\"><span class=\"step-icon\">🏆</span><div class=\"step-text\"><strong>Claim Your Prize</strong><p>Winner gets the full £1,000 VVIP experience at TK04!</p></div></div></div></div><div class=\"prize-reminder\"><h3>Your Prize Package:</h3><ul class=\"prize-summary\"><li>5x VVIP passes to TK04</li><li>VIP bar tab</li><li>Meet & Greet with fighters</li><li>Photo with Total Kombat title belt</li><li>Limited Edition goodie bag</li></ul></div><div class=\"social-share\"><p>Good luck! 🤞</p></div></div></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, " predictions and entered the £1,000 VVIP prize draw.</p></div>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		if vm.AlreadyEntered {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "<div class=\"entry-notice\"><p>Welcome back! You were already in the draw, so we've linked this device to your existing entry. You'll only be counted once.</p></div>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		if vm.Results != nil {
+			templ_7745c5c3_Err = ResultsSection(*vm.Results).Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "<div class=\"entry-details\"><h3>What happens next?</h3><div class=\"next-steps\"><div class=\"step\"><span class=\"step-icon\">🎲</span><div class=\"step-text\"><strong>The Draw</strong><p>Winner will be selected after TK03 concludes</p></div></div><div class=\"step\"><span class=\"step-icon\">📞</span><div class=\"step-text\"><strong>We'll Contact You</strong><p>If you win, we'll reach out using the details you provided</p></div></div><div class=\"step\"><span class=\"step-icon\">🏆</span><div class=\"step-text\"><strong>Claim Your Prize</strong><p>Winner gets the full £1,000 VVIP experience at TK04!</p></div></div></div></div><div class=\"prize-reminder\"><h3>Your Prize Package:</h3><ul class=\"prize-summary\"><li>5x VVIP passes to TK04</li><li>VIP bar tab</li><li>Meet & Greet with fighters</li><li>Photo with Total Kombat title belt</li><li>Limited Edition goodie bag</li></ul></div><div class=\"social-share\"><p>Good luck! 🤞</p></div></div></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}